@@ -0,0 +1,184 @@
+// Package archive 在不同的 Checkpointer 之间导出和导入线程。
+// 归档格式为 JSONL，每行一个检查点，同一线程的检查点按保存顺序连续排列。
+package archive
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"time"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
+)
+
+const (
+	Version = 1
+
+	// maxRecordSize 单个检查点允许的最大长度
+	maxRecordSize = 64 << 20
+)
+
+// Record 归档中的一行，State 为 state.State.Serialize 的结果
+type Record struct {
+	Version   int             `json:"version"`
+	Namespace string          `json:"namespace"`
+	ID        string          `json:"id,omitempty"`
+	Step      int             `json:"step"`
+	Node      string          `json:"node,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	State     json.RawMessage `json:"state"`
+}
+
+// Stats 导出或导入的统计
+type Stats struct {
+	Threads     int
+	Checkpoints int
+	// Skipped 目标中 ID 已存在而跳过的检查点数量
+	Skipped int
+}
+
+// Export 将线程导出到 w，未指定 namespaces 时导出 source 中的所有线程，此时 source 需要实现 ThreadManager。
+// source 实现 CheckpointLister 时会导出检查点 ID 和创建时间，否则只导出状态。
+func Export(ctx context.Context, source flowcontract.Checkpointer, w io.Writer, namespaces ...string) (*Stats, error) {
+	if len(namespaces) == 0 {
+		manager, ok := source.(flowcontract.ThreadManager)
+		if !ok {
+			return nil, xerror.New("checkpointer does not support listing threads, namespaces are required")
+		}
+
+		threads, err := manager.ListThreads(ctx)
+		if err != nil {
+			return nil, xerror.Wrap(err)
+		}
+		for _, thread := range threads {
+			namespaces = append(namespaces, thread.ID)
+		}
+	}
+
+	writer := bufio.NewWriter(w)
+	encoder := json.NewEncoder(writer)
+	stats := &Stats{}
+
+	for _, namespace := range namespaces {
+		for checkpoint, err := range readThread(ctx, source, namespace) {
+			if err != nil {
+				return stats, xerror.Wrap(err)
+			}
+
+			data, err := checkpoint.State.Serialize()
+			if err != nil {
+				return stats, xerror.Wrap(fmt.Errorf("failed to serialize checkpoint %s: %w", checkpoint.ID, err))
+			}
+
+			if err := encoder.Encode(&Record{
+				Version:   Version,
+				Namespace: namespace,
+				ID:        checkpoint.ID,
+				Step:      checkpoint.Step,
+				Node:      checkpoint.State.GetNode(),
+				CreatedAt: checkpoint.CreatedAt,
+				State:     data,
+			}); err != nil {
+				return stats, xerror.Wrap(err)
+			}
+			stats.Checkpoints++
+		}
+		stats.Threads++
+	}
+
+	if err := writer.Flush(); err != nil {
+		return stats, xerror.Wrap(err)
+	}
+
+	return stats, nil
+}
+
+// Import 从 r 读取归档并按顺序写入 target。
+// target 实现 CheckpointImporter 时保留检查点 ID 和创建时间，已存在的 ID 会被跳过，因此可以重复导入；
+// 否则通过 Save 写入，ID 由 target 重新生成。
+func Import(ctx context.Context, target flowcontract.Checkpointer, r io.Reader) (*Stats, error) {
+	importer, canImport := target.(flowcontract.CheckpointImporter)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+
+	stats := &Stats{}
+	threads := make(map[string]bool)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return stats, xerror.Wrap(fmt.Errorf("invalid record at line %d: %w", line, err))
+		}
+		if record.Version != Version {
+			return stats, xerror.New(fmt.Sprintf("unsupported archive version %d at line %d", record.Version, line))
+		}
+
+		s := &state.State{}
+		if err := s.Deserialize(record.State); err != nil {
+			return stats, xerror.Wrap(fmt.Errorf("invalid state at line %d: %w", line, err))
+		}
+
+		if canImport && record.ID != "" {
+			imported, err := importer.Import(ctx, record.Namespace, &flowcontract.Checkpoint{
+				ID:        record.ID,
+				Namespace: record.Namespace,
+				Step:      record.Step,
+				Node:      record.Node,
+				CreatedAt: record.CreatedAt,
+				State:     s,
+			})
+			if err != nil {
+				return stats, xerror.Wrap(err)
+			}
+			if !imported {
+				stats.Skipped++
+				continue
+			}
+		} else if _, err := target.Save(ctx, record.Namespace, s); err != nil {
+			return stats, xerror.Wrap(err)
+		}
+
+		if !threads[record.Namespace] {
+			threads[record.Namespace] = true
+			stats.Threads++
+		}
+		stats.Checkpoints++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return stats, xerror.Wrap(err)
+	}
+
+	return stats, nil
+}
+
+// readThread 按保存顺序读取线程的所有检查点
+func readThread(ctx context.Context, source flowcontract.Checkpointer, namespace string) iter.Seq2[*flowcontract.Checkpoint, error] {
+	if lister, ok := source.(flowcontract.CheckpointLister); ok {
+		return lister.Iter(ctx, namespace)
+	}
+
+	return func(yield func(*flowcontract.Checkpoint, error) bool) {
+		states, err := source.GetAll(ctx, namespace)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for i, s := range states {
+			if !yield(&flowcontract.Checkpoint{Namespace: namespace, Step: i + 1, State: s}, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/futurxlab/golanggraph/checkpointer"
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/state"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	source := checkpointer.NewRedisCheckpointer(client)
+	for _, namespace := range []string{"a", "b"} {
+		for i := 0; i < 3; i++ {
+			s := &state.State{History: []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, fmt.Sprintf("%s %d", namespace, i))}}
+			s.SetNode(fmt.Sprintf("node%d", i))
+			if _, err := source.Save(ctx, namespace, s); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var buffer bytes.Buffer
+	stats, err := Export(ctx, source, &buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Threads != 2 || stats.Checkpoints != 6 {
+		t.Fatalf("unexpected export stats %+v", stats)
+	}
+
+	target := checkpointer.NewInMemoryCheckpointer()
+	archived := buffer.Bytes()
+	stats, err = Import(ctx, target, bytes.NewReader(archived))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Threads != 2 || stats.Checkpoints != 6 {
+		t.Fatalf("unexpected import stats %+v", stats)
+	}
+
+	// 重复导入会跳过已存在的检查点
+	stats, err = Import(ctx, target, bytes.NewReader(archived))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Skipped != 6 || stats.Checkpoints != 0 {
+		t.Fatalf("unexpected reimport stats %+v", stats)
+	}
+
+	for _, namespace := range []string{"a", "b"} {
+		var sourceCheckpoints, targetCheckpoints []*flowcontract.Checkpoint
+		for checkpoint, err := range source.Iter(ctx, namespace) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			sourceCheckpoints = append(sourceCheckpoints, checkpoint)
+		}
+		for checkpoint, err := range target.Iter(ctx, namespace) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			targetCheckpoints = append(targetCheckpoints, checkpoint)
+		}
+
+		if len(sourceCheckpoints) != len(targetCheckpoints) {
+			t.Fatalf("checkpoint count mismatch for %s", namespace)
+		}
+		for i := range sourceCheckpoints {
+			s, d := sourceCheckpoints[i], targetCheckpoints[i]
+			if s.ID != d.ID || s.Step != d.Step || s.Node != d.Node || !s.CreatedAt.Equal(d.CreatedAt) {
+				t.Fatalf("checkpoint mismatch %+v %+v", s, d)
+			}
+			if !reflect.DeepEqual(s.State.History, d.State.History) {
+				t.Fatalf("state mismatch %+v %+v", s.State, d.State)
+			}
+		}
+	}
+}
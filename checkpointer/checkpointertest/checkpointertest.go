@@ -0,0 +1,546 @@
+// Package checkpointertest provides a conformance test suite for flowcontract.Checkpointer
+// implementations. Backends call Run from their own tests:
+//
+//	func TestMyCheckpointer(t *testing.T) {
+//		checkpointertest.Run(t, func(t *testing.T) flowcontract.Checkpointer {
+//			return NewMyCheckpointer(...)
+//		})
+//	}
+//
+// Optional extensions (CheckpointLister, ThreadManager, ThreadLocker) are exercised when implemented.
+package checkpointertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/state"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+var (
+	// LargePayloadSize the size in bytes of the message used by the large payload test
+	LargePayloadSize = 4 << 20
+	// ConcurrentWriters the number of goroutines saving into one namespace at the same time
+	ConcurrentWriters = 16
+)
+
+// Factory returns a fresh, empty checkpointer for every sub test.
+type Factory func(t *testing.T) flowcontract.Checkpointer
+
+// Run runs the conformance suite against the checkpointers returned by newCheckpointer.
+func Run(t *testing.T, newCheckpointer Factory) {
+	t.Run("save and get by id", func(t *testing.T) {
+		testSaveAndGetByID(t, newCheckpointer(t))
+	})
+	t.Run("ordering", func(t *testing.T) {
+		testOrdering(t, newCheckpointer(t))
+	})
+	t.Run("not found", func(t *testing.T) {
+		testNotFound(t, newCheckpointer(t))
+	})
+	t.Run("namespace isolation", func(t *testing.T) {
+		testNamespaceIsolation(t, newCheckpointer(t))
+	})
+	t.Run("round trip", func(t *testing.T) {
+		testRoundTrip(t, newCheckpointer(t))
+	})
+	t.Run("caller mutation", func(t *testing.T) {
+		testCallerMutation(t, newCheckpointer(t))
+	})
+	t.Run("concurrent saves", func(t *testing.T) {
+		testConcurrentSaves(t, newCheckpointer(t))
+	})
+	t.Run("large payload", func(t *testing.T) {
+		testLargePayload(t, newCheckpointer(t))
+	})
+	t.Run("list", func(t *testing.T) {
+		checkpointer := newCheckpointer(t)
+		lister, ok := checkpointer.(flowcontract.CheckpointLister)
+		if !ok {
+			t.Skip("checkpointer does not implement CheckpointLister")
+		}
+		testList(t, checkpointer, lister)
+	})
+	t.Run("threads", func(t *testing.T) {
+		checkpointer := newCheckpointer(t)
+		manager, ok := checkpointer.(flowcontract.ThreadManager)
+		if !ok {
+			t.Skip("checkpointer does not implement ThreadManager")
+		}
+		testThreads(t, checkpointer, manager)
+	})
+	t.Run("lock", func(t *testing.T) {
+		locker, ok := newCheckpointer(t).(flowcontract.ThreadLocker)
+		if !ok {
+			t.Skip("checkpointer does not implement ThreadLocker")
+		}
+		testLock(t, locker)
+	})
+}
+
+func newState(text string) *state.State {
+	s := &state.State{
+		History:  []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, text)},
+		Metadata: map[string]interface{}{"text": text},
+	}
+	return s
+}
+
+func text(s *state.State) string {
+	if len(s.History) == 0 || len(s.History[0].Parts) == 0 {
+		return ""
+	}
+	content, _ := s.History[0].Parts[0].(llms.TextContent)
+	return content.Text
+}
+
+func save(t *testing.T, checkpointer flowcontract.Checkpointer, namespace string, s *state.State) string {
+	t.Helper()
+	id, err := checkpointer.Save(context.Background(), namespace, s)
+	if err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if id == "" {
+		t.Fatal("save returned an empty checkpoint id")
+	}
+	return id
+}
+
+func testSaveAndGetByID(t *testing.T, checkpointer flowcontract.Checkpointer) {
+	ctx := context.Background()
+	first := save(t, checkpointer, "thread", newState("first"))
+	second := save(t, checkpointer, "thread", newState("second"))
+	if first == second {
+		t.Fatalf("checkpoint ids must be unique, got %s twice", first)
+	}
+
+	for id, expected := range map[string]string{first: "first", second: "second"} {
+		s, err := checkpointer.GetByID(ctx, "thread", id)
+		if err != nil {
+			t.Fatalf("get by id failed: %v", err)
+		}
+		if text(s) != expected {
+			t.Fatalf("expected %q for %s, got %q", expected, id, text(s))
+		}
+	}
+}
+
+func testOrdering(t *testing.T, checkpointer flowcontract.Checkpointer) {
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		save(t, checkpointer, "thread", newState(fmt.Sprintf("message %d", i)))
+
+		latest, err := checkpointer.GetLastest(ctx, "thread")
+		if err != nil {
+			t.Fatalf("get latest failed: %v", err)
+		}
+		if text(latest) != fmt.Sprintf("message %d", i) {
+			t.Fatalf("get latest returned %q after saving message %d", text(latest), i)
+		}
+	}
+
+	all, err := checkpointer.GetAll(ctx, "thread")
+	if err != nil {
+		t.Fatalf("get all failed: %v", err)
+	}
+	if len(all) != 20 {
+		t.Fatalf("expected 20 states, got %d", len(all))
+	}
+	for i, s := range all {
+		if text(s) != fmt.Sprintf("message %d", i) {
+			t.Fatalf("get all returned %q at position %d", text(s), i)
+		}
+	}
+}
+
+func testNotFound(t *testing.T, checkpointer flowcontract.Checkpointer) {
+	ctx := context.Background()
+
+	assertNotFound := func(name string, err error) {
+		t.Helper()
+		if err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+		if !errors.Is(err, flowcontract.ErrCheckpointNotFound) {
+			t.Fatalf("%s: expected ErrCheckpointNotFound, got %v", name, err)
+		}
+	}
+
+	_, err := checkpointer.GetLastest(ctx, "empty")
+	assertNotFound("get latest on empty namespace", err)
+
+	_, err = checkpointer.GetAll(ctx, "empty")
+	assertNotFound("get all on empty namespace", err)
+
+	_, err = checkpointer.GetByID(ctx, "empty", "missing")
+	assertNotFound("get by id on empty namespace", err)
+
+	id := save(t, checkpointer, "thread", newState("exists"))
+	_, err = checkpointer.GetByID(ctx, "thread", "missing")
+	assertNotFound("get by unknown id", err)
+
+	_, err = checkpointer.GetByID(ctx, "other", id)
+	assertNotFound("get by id from another namespace", err)
+}
+
+func testNamespaceIsolation(t *testing.T, checkpointer flowcontract.Checkpointer) {
+	ctx := context.Background()
+	save(t, checkpointer, "a", newState("a"))
+	save(t, checkpointer, "b", newState("b"))
+	save(t, checkpointer, "a:b", newState("a:b"))
+
+	for _, namespace := range []string{"a", "b", "a:b"} {
+		all, err := checkpointer.GetAll(ctx, namespace)
+		if err != nil {
+			t.Fatalf("get all failed: %v", err)
+		}
+		if len(all) != 1 || text(all[0]) != namespace {
+			t.Fatalf("namespace %s leaked states: %d", namespace, len(all))
+		}
+	}
+}
+
+func testRoundTrip(t *testing.T, checkpointer flowcontract.Checkpointer) {
+	ctx := context.Background()
+
+	original := &state.State{
+		History: []llms.MessageContent{
+			llms.TextParts(llms.ChatMessageTypeSystem, "you are a helpful assistant"),
+			{
+				Role: llms.ChatMessageTypeHuman,
+				Parts: []llms.ContentPart{
+					llms.TextContent{Text: "what is in this image?"},
+					llms.ImageURLContent{URL: "https://example.com/cat.png", Detail: "low"},
+					llms.BinaryContent{MIMEType: "application/octet-stream", Data: []byte{0, 1, 2, 255}},
+				},
+			},
+			{
+				Role: llms.ChatMessageTypeAI,
+				Parts: []llms.ContentPart{
+					llms.TextContent{Text: "let me check"},
+					llms.ToolCall{
+						ID:   "call_1",
+						Type: "function",
+						FunctionCall: &llms.FunctionCall{
+							Name:      "fetch___fetch",
+							Arguments: `{"url":"https://example.com"}`,
+						},
+					},
+				},
+			},
+			{
+				Role: llms.ChatMessageTypeTool,
+				Parts: []llms.ContentPart{
+					llms.ToolCallResponse{ToolCallID: "call_1", Name: "fetch___fetch", Content: `[{"type":"text","text":"hello"}]`},
+				},
+			},
+		},
+		Metadata: map[string]interface{}{
+			"string": "value",
+			"number": 3.5,
+			"bool":   true,
+			"nested": map[string]interface{}{"list": []interface{}{"a", 1.0}},
+		},
+	}
+	original.SetThreadID("thread")
+	original.SetNode("chat")
+	original.SetNextNodes([]string{"tools", "__end__"})
+	original.SetPendingTasks([]state.PendingTask{{Node: "tools", State: newState("task")}})
+	original.SetPendingWrites([]state.PendingWrite{{Node: "search", State: newState("write")}})
+
+	id := save(t, checkpointer, "thread", original)
+
+	byID, err := checkpointer.GetByID(ctx, "thread", id)
+	if err != nil {
+		t.Fatalf("get by id failed: %v", err)
+	}
+	latest, err := checkpointer.GetLastest(ctx, "thread")
+	if err != nil {
+		t.Fatalf("get latest failed: %v", err)
+	}
+	all, err := checkpointer.GetAll(ctx, "thread")
+	if err != nil {
+		t.Fatalf("get all failed: %v", err)
+	}
+
+	for name, restored := range map[string]*state.State{"get by id": byID, "get latest": latest, "get all": all[0]} {
+		if restored.GetThreadID() != "thread" || restored.GetNode() != "chat" || !reflect.DeepEqual(restored.GetNextNodes(), original.GetNextNodes()) {
+			t.Fatalf("%s lost internal parameters: thread %q node %q next %v", name, restored.GetThreadID(), restored.GetNode(), restored.GetNextNodes())
+		}
+		if !reflect.DeepEqual(restored.History, original.History) {
+			t.Fatalf("%s history mismatch:\n got %#v\nwant %#v", name, restored.History, original.History)
+		}
+		if !reflect.DeepEqual(restored.Metadata, original.Metadata) {
+			t.Fatalf("%s metadata mismatch:\n got %#v\nwant %#v", name, restored.Metadata, original.Metadata)
+		}
+		tasks, writes := restored.GetPendingTasks(), restored.GetPendingWrites()
+		if len(tasks) != 1 || tasks[0].Node != "tools" || text(tasks[0].State) != "task" ||
+			len(writes) != 1 || writes[0].Node != "search" || text(writes[0].State) != "write" {
+			t.Fatalf("%s lost pending writes: tasks %+v writes %+v", name, tasks, writes)
+		}
+	}
+}
+
+func testCallerMutation(t *testing.T, checkpointer flowcontract.Checkpointer) {
+	ctx := context.Background()
+
+	s := newState("original")
+	id := save(t, checkpointer, "thread", s)
+
+	// 保存之后调用方继续修改状态，不应影响已保存的检查点
+	s.Metadata["text"] = "mutated"
+	s.History[0] = llms.TextParts(llms.ChatMessageTypeHuman, "mutated")
+
+	restored, err := checkpointer.GetByID(ctx, "thread", id)
+	if err != nil {
+		t.Fatalf("get by id failed: %v", err)
+	}
+	if text(restored) != "original" || restored.Metadata["text"] != "original" {
+		t.Fatalf("stored checkpoint was changed by the caller: %+v", restored)
+	}
+
+	// 修改读取结果同样不应影响已保存的检查点
+	restored.Metadata["text"] = "mutated"
+	again, err := checkpointer.GetByID(ctx, "thread", id)
+	if err != nil {
+		t.Fatalf("get by id failed: %v", err)
+	}
+	if again.Metadata["text"] != "original" {
+		t.Fatalf("stored checkpoint was changed through a returned state: %+v", again)
+	}
+}
+
+func testConcurrentSaves(t *testing.T, checkpointer flowcontract.Checkpointer) {
+	ctx := context.Background()
+	const perWriter = 8
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	ids := make(map[string]string)
+	errs := make(chan error, ConcurrentWriters*perWriter)
+
+	for w := 0; w < ConcurrentWriters; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				message := fmt.Sprintf("writer %d message %d", w, i)
+				id, err := checkpointer.Save(ctx, "thread", newState(message))
+				if err != nil {
+					errs <- err
+					return
+				}
+				mu.Lock()
+				ids[id] = message
+				mu.Unlock()
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("concurrent save failed: %v", err)
+	}
+
+	if len(ids) != ConcurrentWriters*perWriter {
+		t.Fatalf("expected %d unique ids, got %d", ConcurrentWriters*perWriter, len(ids))
+	}
+
+	all, err := checkpointer.GetAll(ctx, "thread")
+	if err != nil {
+		t.Fatalf("get all failed: %v", err)
+	}
+	if len(all) != len(ids) {
+		t.Fatalf("expected %d states, got %d", len(ids), len(all))
+	}
+
+	// 同一个 writer 的检查点必须保持保存顺序
+	last := make(map[string]int)
+	for _, s := range all {
+		var w, i int
+		if _, err := fmt.Sscanf(text(s), "writer %d message %d", &w, &i); err != nil {
+			t.Fatalf("unexpected state %q", text(s))
+		}
+		key := fmt.Sprint(w)
+		if previous, ok := last[key]; ok && previous > i {
+			t.Fatalf("writer %d checkpoints out of order: %d after %d", w, i, previous)
+		}
+		last[key] = i
+	}
+
+	for id, message := range ids {
+		s, err := checkpointer.GetByID(ctx, "thread", id)
+		if err != nil {
+			t.Fatalf("get by id failed: %v", err)
+		}
+		if text(s) != message {
+			t.Fatalf("expected %q for %s, got %q", message, id, text(s))
+		}
+	}
+}
+
+func testLargePayload(t *testing.T, checkpointer flowcontract.Checkpointer) {
+	ctx := context.Background()
+	large := strings.Repeat("golanggraph ", LargePayloadSize/len("golanggraph "))
+
+	id := save(t, checkpointer, "thread", newState(large))
+	restored, err := checkpointer.GetByID(ctx, "thread", id)
+	if err != nil {
+		t.Fatalf("get by id failed: %v", err)
+	}
+	if text(restored) != large {
+		t.Fatalf("large payload mismatch, got %d bytes want %d", len(text(restored)), len(large))
+	}
+}
+
+func testList(t *testing.T, checkpointer flowcontract.Checkpointer, lister flowcontract.CheckpointLister) {
+	ctx := context.Background()
+	ids := make([]string, 0)
+	for i := 0; i < 25; i++ {
+		s := newState(fmt.Sprintf("message %d", i))
+		s.SetNode(fmt.Sprintf("node%d", i%2))
+		ids = append(ids, save(t, checkpointer, "thread", s))
+	}
+
+	cursor := ""
+	seen := 0
+	for {
+		page, next, err := lister.List(ctx, "thread", flowcontract.WithLimit(10), flowcontract.WithCursor(cursor))
+		if err != nil {
+			t.Fatalf("list failed: %v", err)
+		}
+		for _, checkpoint := range page {
+			if checkpoint.ID != ids[seen] || checkpoint.Step != seen+1 || text(checkpoint.State) != fmt.Sprintf("message %d", seen) {
+				t.Fatalf("unexpected checkpoint at %d: %+v", seen, checkpoint)
+			}
+			seen++
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if seen != 25 {
+		t.Fatalf("expected 25 listed checkpoints, got %d", seen)
+	}
+
+	count := 0
+	for checkpoint, err := range lister.Iter(ctx, "thread", flowcontract.WithNode("node1"), flowcontract.WithReverse(), flowcontract.WithLimit(4)) {
+		if err != nil {
+			t.Fatalf("iter failed: %v", err)
+		}
+		if checkpoint.Node != "node1" {
+			t.Fatalf("node filter returned %s", checkpoint.Node)
+		}
+		count++
+	}
+	if count != 12 {
+		t.Fatalf("expected 12 checkpoints for node1, got %d", count)
+	}
+
+	page, next, err := lister.List(ctx, "empty")
+	if err != nil || len(page) != 0 || next != "" {
+		t.Fatalf("list on empty namespace returned %d checkpoints, cursor %q, error %v", len(page), next, err)
+	}
+}
+
+func testThreads(t *testing.T, checkpointer flowcontract.Checkpointer, manager flowcontract.ThreadManager) {
+	ctx := context.Background()
+	save(t, checkpointer, "a", newState("a"))
+	save(t, checkpointer, "b", newState("b"))
+	save(t, checkpointer, "b", newState("b"))
+
+	threads, err := manager.ListThreads(ctx)
+	if err != nil {
+		t.Fatalf("list threads failed: %v", err)
+	}
+	counts := make(map[string]int)
+	for _, thread := range threads {
+		counts[thread.ID] = thread.CheckpointCount
+	}
+	if counts["a"] != 1 || counts["b"] != 2 {
+		t.Fatalf("unexpected threads %v", counts)
+	}
+
+	if err := manager.DeleteThread(ctx, "b"); err != nil {
+		t.Fatalf("delete thread failed: %v", err)
+	}
+	if _, err := checkpointer.GetLastest(ctx, "b"); !errors.Is(err, flowcontract.ErrCheckpointNotFound) {
+		t.Fatalf("expected deleted thread to be not found, got %v", err)
+	}
+	if _, err := checkpointer.GetLastest(ctx, "a"); err != nil {
+		t.Fatalf("deleting a thread affected another thread: %v", err)
+	}
+}
+
+func testLock(t *testing.T, locker flowcontract.ThreadLocker) {
+	ctx := context.Background()
+
+	lock, err := locker.LockThread(ctx, "thread", false)
+	if err != nil {
+		t.Fatalf("lock failed: %v", err)
+	}
+
+	if _, err := locker.LockThread(ctx, "thread", false); !errors.Is(err, flowcontract.ErrThreadBusy) {
+		t.Fatalf("expected ErrThreadBusy for a locked thread, got %v", err)
+	}
+
+	other, err := locker.LockThread(ctx, "other", false)
+	if err != nil {
+		t.Fatalf("locking another thread failed: %v", err)
+	}
+	if err := other.Unlock(ctx); err != nil {
+		t.Fatalf("unlock failed: %v", err)
+	}
+
+	// 等待中的调用在 ctx 结束时返回
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := locker.LockThread(canceled, "thread", true); err == nil {
+		t.Fatal("expected waiting on a canceled context to fail")
+	}
+
+	acquired := make(chan flowcontract.ThreadLock)
+	go func() {
+		waiter, err := locker.LockThread(ctx, "thread", true)
+		if err != nil {
+			t.Errorf("waiting lock failed: %v", err)
+		}
+		acquired <- waiter
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("waiting lock acquired while the thread is locked")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := lock.Unlock(ctx); err != nil {
+		t.Fatalf("unlock failed: %v", err)
+	}
+	if err := lock.Unlock(ctx); err != nil {
+		t.Fatalf("repeated unlock failed: %v", err)
+	}
+
+	select {
+	case waiter := <-acquired:
+		if waiter == nil {
+			t.FailNow()
+		}
+		if err := waiter.Unlock(ctx); err != nil {
+			t.Fatalf("unlock failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting lock was not acquired after unlock")
+	}
+}
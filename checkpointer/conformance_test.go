@@ -0,0 +1,45 @@
+package checkpointer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/futurxlab/golanggraph/checkpointer/checkpointertest"
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+)
+
+func TestConformance(t *testing.T) {
+	t.Run("inmemory", func(t *testing.T) {
+		checkpointertest.Run(t, func(t *testing.T) flowcontract.Checkpointer {
+			return NewInMemoryCheckpointer()
+		})
+	})
+
+	t.Run("redis", func(t *testing.T) {
+		checkpointertest.Run(t, func(t *testing.T) flowcontract.Checkpointer {
+			checkpointer, _ := newTestRedisCheckpointer(t, WithHashTag())
+			return checkpointer
+		})
+	})
+
+	t.Run("delta", func(t *testing.T) {
+		checkpointertest.Run(t, func(t *testing.T) flowcontract.Checkpointer {
+			inner, _ := newTestRedisCheckpointer(t)
+			checkpointer, err := NewDeltaCheckpointer(inner)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return checkpointer
+		})
+	})
+
+	t.Run("encrypted", func(t *testing.T) {
+		checkpointertest.Run(t, func(t *testing.T) flowcontract.Checkpointer {
+			checkpointer, err := NewEncryptedCheckpointer(NewInMemoryCheckpointer(), EncryptionKey{ID: "v1", Secret: bytes.Repeat([]byte{1}, 32)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return checkpointer
+		})
+	})
+}
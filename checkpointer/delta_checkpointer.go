@@ -0,0 +1,482 @@
+package checkpointer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+	"time"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/utils/cache"
+	"github.com/futurxlab/golanggraph/xerror"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+const (
+	DefaultSnapshotInterval = 20
+
+	// deltaMetadataKey 增量检查点在 Metadata 中记录父检查点的保留 key
+	deltaMetadataKey = "__checkpoint_delta__"
+
+	deltaCacheTTL = time.Minute * 30
+)
+
+// deltaMarker 增量检查点的描述信息
+type deltaMarker struct {
+	Parent  string   `json:"parent"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// ErrDeltaBaseMissing 增量检查点依赖的父检查点已经不存在，无法还原完整状态
+var ErrDeltaBaseMissing = errors.New("delta checkpoint base is missing")
+
+// deltaHead 每个线程最近一次保存的完整状态，用于计算下一次的增量
+type deltaHead struct {
+	id    string
+	depth int
+	state *state.State
+}
+
+// deltaStore DeltaCheckpointer 清理时需要被包装的 Checkpointer 支持按 step 裁剪单个线程
+type deltaStore interface {
+	flowcontract.Checkpointer
+	flowcontract.CheckpointLister
+	flowcontract.ThreadManager
+	trimThread(ctx context.Context, namespace string, before int) error
+}
+
+// DeltaCheckpointer 包装任意 Checkpointer，只保存相对上一个检查点新增的消息和变化的 Metadata，
+// 每 SnapshotInterval 个检查点保存一次完整快照，读取时沿父检查点自动还原完整状态。
+// 父检查点不在缓存中（例如进程重启）或历史消息不是追加关系（例如并行分支）时同样保存完整快照。
+//
+// 被包装的 Checkpointer 按数量裁剪时会删除增量依赖的快照，因此保留策略需要配置在 DeltaCheckpointer 上：
+// WithMaxCheckpoints 保留最近的检查点以及还原它们所需的更早检查点，WithTTL 删除过期线程。
+// 同一线程的写入需要经过同一个 DeltaCheckpointer，例如在多个进程间使用线程锁
+type DeltaCheckpointer struct {
+	flowcontract.Checkpointer
+	options *Options
+	locks   memoryLocks
+	heads   *cache.MemCache
+	pruner  *pruner
+}
+
+// NewDeltaCheckpointer 创建一个增量检查点包装器，可通过 WithSnapshotInterval 配置快照间隔，
+// 通过 WithTTL 和 WithMaxCheckpoints 配置保留策略
+func NewDeltaCheckpointer(inner flowcontract.Checkpointer, opts ...Option) (*DeltaCheckpointer, error) {
+	options := newOptions(opts...)
+	if options.SnapshotInterval <= 0 {
+		options.SnapshotInterval = DefaultSnapshotInterval
+	}
+
+	if configured, ok := inner.(interface{ getOptions() *Options }); ok {
+		if innerOptions := configured.getOptions(); innerOptions.TTL > 0 || innerOptions.MaxCheckpoints > 0 {
+			return nil, xerror.New("retention policy of the inner checkpointer breaks delta checkpoints, configure it on the DeltaCheckpointer instead")
+		}
+	}
+	if _, ok := inner.(deltaStore); !ok && (options.TTL > 0 || options.MaxCheckpoints > 0) {
+		return nil, xerror.New("checkpointer does not support delta retention")
+	}
+
+	heads, err := cache.NewMemCache()
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+
+	c := &DeltaCheckpointer{
+		Checkpointer: inner,
+		options:      options,
+		heads:        heads,
+	}
+	c.pruner = startPruner(options, c.Prune)
+
+	return c, nil
+}
+
+// Close 停止后台清理，不会关闭被包装的 Checkpointer
+func (c *DeltaCheckpointer) Close() error {
+	c.pruner.stop()
+	return nil
+}
+
+// Save 保存增量或完整快照
+func (c *DeltaCheckpointer) Save(ctx context.Context, namespace string, current *state.State) (string, error) {
+	// 同一线程的增量依赖上一次保存的结果，需要串行
+	lock, err := c.locks.lock(ctx, namespace, true)
+	if err != nil {
+		return "", err
+	}
+	defer lock.Unlock(ctx)
+
+	var head *deltaHead
+	if value, ok := c.heads.Get(namespace); ok {
+		head = value.(*deltaHead)
+	}
+
+	full := cloneState(current)
+	payload, depth := full, 0
+	if head != nil && head.depth+1 < c.options.SnapshotInterval {
+		if delta, ok := diffState(head.id, head.state, full); ok {
+			payload, depth = delta, head.depth+1
+		}
+	}
+
+	id, err := c.Checkpointer.Save(ctx, namespace, payload)
+	if err != nil {
+		c.heads.Delete(namespace)
+		return "", xerror.Wrap(err)
+	}
+
+	c.heads.SetWithTTL(namespace, &deltaHead{id: id, depth: depth, state: full}, 1, deltaCacheTTL)
+
+	return id, nil
+}
+
+// GetByID 通过 ID 获取并还原完整状态
+func (c *DeltaCheckpointer) GetByID(ctx context.Context, namespace string, checkpointerID string) (*state.State, error) {
+	payload, err := c.Checkpointer.GetByID(ctx, namespace, checkpointerID)
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+	return c.restore(ctx, namespace, payload, nil)
+}
+
+// GetLastest 获取并还原最新的完整状态
+func (c *DeltaCheckpointer) GetLastest(ctx context.Context, namespace string) (*state.State, error) {
+	payload, err := c.Checkpointer.GetLastest(ctx, namespace)
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+	return c.restore(ctx, namespace, payload, nil)
+}
+
+// GetAll 按顺序获取并还原所有状态，需要被包装的 Checkpointer 实现 CheckpointLister
+func (c *DeltaCheckpointer) GetAll(ctx context.Context, namespace string) ([]*state.State, error) {
+	lister, ok := c.Checkpointer.(flowcontract.CheckpointLister)
+	if !ok {
+		return nil, xerror.New("checkpointer does not support listing")
+	}
+
+	states := make([]*state.State, 0)
+	restored := make(map[string]*state.State)
+	for checkpoint, err := range lister.Iter(ctx, namespace) {
+		if err != nil {
+			return nil, xerror.Wrap(err)
+		}
+
+		full, err := c.restore(ctx, namespace, checkpoint.State, restored)
+		if err != nil {
+			return nil, err
+		}
+		restored[checkpoint.ID] = full
+		states = append(states, full)
+	}
+
+	if len(states) == 0 {
+		return nil, xerror.Wrap(fmt.Errorf("no states found for namespace %s: %w", namespace, flowcontract.ErrCheckpointNotFound))
+	}
+
+	return states, nil
+}
+
+// List 按条件分页查询并还原检查点，需要被包装的 Checkpointer 实现 CheckpointLister
+func (c *DeltaCheckpointer) List(ctx context.Context, namespace string, opts ...flowcontract.ListOption) ([]*flowcontract.Checkpoint, string, error) {
+	lister, ok := c.Checkpointer.(flowcontract.CheckpointLister)
+	if !ok {
+		return nil, "", xerror.New("checkpointer does not support listing")
+	}
+
+	checkpoints, cursor, err := lister.List(ctx, namespace, opts...)
+	if err != nil {
+		return nil, "", xerror.Wrap(err)
+	}
+
+	restored := make(map[string]*state.State, len(checkpoints))
+	for _, checkpoint := range checkpoints {
+		full, err := c.restore(ctx, namespace, checkpoint.State, restored)
+		if err != nil {
+			return nil, "", err
+		}
+		restored[checkpoint.ID] = full
+		checkpoint.State = full
+	}
+
+	return checkpoints, cursor, nil
+}
+
+// Iter 流式读取并还原所有匹配的检查点
+func (c *DeltaCheckpointer) Iter(ctx context.Context, namespace string, opts ...flowcontract.ListOption) iter.Seq2[*flowcontract.Checkpoint, error] {
+	return iterate(ctx, c.List, namespace, opts...)
+}
+
+// LockThread 获取线程锁，需要被包装的 Checkpointer 实现 ThreadLocker
+func (c *DeltaCheckpointer) LockThread(ctx context.Context, namespace string, wait bool) (flowcontract.ThreadLock, error) {
+	locker, ok := c.Checkpointer.(flowcontract.ThreadLocker)
+	if !ok {
+		return nil, xerror.New("checkpointer does not support thread locking")
+	}
+	return locker.LockThread(ctx, namespace, wait)
+}
+
+// Import 按原样导入检查点，需要被包装的 Checkpointer 实现 CheckpointImporter
+func (c *DeltaCheckpointer) Import(ctx context.Context, namespace string, checkpoint *flowcontract.Checkpoint) (bool, error) {
+	importer, ok := c.Checkpointer.(flowcontract.CheckpointImporter)
+	if !ok {
+		return false, xerror.New("checkpointer does not support importing")
+	}
+
+	lock, err := c.locks.lock(ctx, namespace, true)
+	if err != nil {
+		return false, err
+	}
+	defer lock.Unlock(ctx)
+
+	// 导入的检查点成为最新的检查点，下一次保存需要重新从快照开始
+	c.heads.Delete(namespace)
+
+	return importer.Import(ctx, namespace, checkpoint)
+}
+
+// ListThreads 返回被包装的 Checkpointer 中的所有线程，需要被包装的 Checkpointer 实现 ThreadManager
+func (c *DeltaCheckpointer) ListThreads(ctx context.Context) ([]*flowcontract.Thread, error) {
+	manager, ok := c.Checkpointer.(flowcontract.ThreadManager)
+	if !ok {
+		return nil, xerror.New("checkpointer does not support thread management")
+	}
+	return manager.ListThreads(ctx)
+}
+
+// DeleteThread 删除线程及其所有检查点，需要被包装的 Checkpointer 实现 ThreadManager
+func (c *DeltaCheckpointer) DeleteThread(ctx context.Context, namespace string) error {
+	manager, ok := c.Checkpointer.(flowcontract.ThreadManager)
+	if !ok {
+		return xerror.New("checkpointer does not support thread management")
+	}
+
+	lock, err := c.locks.lock(ctx, namespace, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock(ctx)
+
+	c.heads.Delete(namespace)
+
+	return manager.DeleteThread(ctx, namespace)
+}
+
+// Prune 删除过期线程，并保留每个线程最近的 MaxCheckpoints 个检查点以及还原它们所需的更早检查点
+func (c *DeltaCheckpointer) Prune(ctx context.Context) error {
+	if c.options.TTL <= 0 && c.options.MaxCheckpoints <= 0 {
+		return nil
+	}
+
+	store := c.Checkpointer.(deltaStore)
+	threads, err := store.ListThreads(ctx)
+	if err != nil {
+		return xerror.Wrap(err)
+	}
+
+	now := time.Now()
+	for _, thread := range threads {
+		if c.options.TTL > 0 && now.Sub(thread.UpdatedAt) > c.options.TTL {
+			if err := c.DeleteThread(ctx, thread.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if c.options.MaxCheckpoints > 0 && thread.CheckpointCount > c.options.MaxCheckpoints {
+			if err := c.trimThread(ctx, store, thread.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// trimThread 裁剪线程，从最近的 MaxCheckpoints 个检查点沿父检查点回溯，只删除比它们依赖的最早检查点更早的检查点
+func (c *DeltaCheckpointer) trimThread(ctx context.Context, store deltaStore, namespace string) error {
+	lock, err := c.locks.lock(ctx, namespace, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock(ctx)
+
+	c.heads.Delete(namespace)
+
+	// 增量链的长度小于快照间隔，依赖的检查点都在最近的 MaxCheckpoints + SnapshotInterval 个检查点内
+	checkpoints, _, err := store.List(ctx, namespace, flowcontract.WithReverse(), flowcontract.WithLimit(c.options.MaxCheckpoints+c.options.SnapshotInterval))
+	if err != nil {
+		return xerror.Wrap(err)
+	}
+	if len(checkpoints) <= c.options.MaxCheckpoints {
+		return nil
+	}
+
+	index := make(map[string]int, len(checkpoints))
+	for i, checkpoint := range checkpoints {
+		index[checkpoint.ID] = i
+	}
+
+	oldest := c.options.MaxCheckpoints - 1
+	for i := 0; i < c.options.MaxCheckpoints; i++ {
+		for current := i; ; {
+			marker, err := getDeltaMarker(checkpoints[current].State)
+			if err != nil {
+				return err
+			}
+			if marker == nil {
+				break
+			}
+
+			// 父检查点不在最近的检查点中（例如导入的检查点）时无法确定依赖范围，不裁剪该线程
+			parent, ok := index[marker.Parent]
+			if !ok || parent <= current {
+				return nil
+			}
+			oldest = max(oldest, parent)
+			current = parent
+		}
+	}
+
+	return store.trimThread(ctx, namespace, checkpoints[oldest].Step)
+}
+
+// restore 沿父检查点回溯到最近的完整快照，再依次应用增量
+func (c *DeltaCheckpointer) restore(ctx context.Context, namespace string, payload *state.State, restored map[string]*state.State) (*state.State, error) {
+	chain := make([]*state.State, 0)
+	markers := make([]*deltaMarker, 0)
+
+	var base *state.State
+	for base == nil {
+		marker, err := getDeltaMarker(payload)
+		if err != nil {
+			return nil, err
+		}
+		if marker == nil {
+			base = cloneState(payload)
+			break
+		}
+
+		chain = append(chain, payload)
+		markers = append(markers, marker)
+
+		if parent, ok := restored[marker.Parent]; ok {
+			base = parent
+			break
+		}
+
+		payload, err = c.Checkpointer.GetByID(ctx, namespace, marker.Parent)
+		if errors.Is(err, flowcontract.ErrCheckpointNotFound) {
+			return nil, xerror.Wrap(fmt.Errorf("%w: parent checkpoint %s in namespace %s", ErrDeltaBaseMissing, marker.Parent, namespace))
+		}
+		if err != nil {
+			return nil, xerror.Wrap(fmt.Errorf("failed to get parent checkpoint %s: %w", marker.Parent, err))
+		}
+	}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		base = applyDelta(base, chain[i], markers[i])
+	}
+
+	return base, nil
+}
+
+// diffState 计算 next 相对 parent 的增量，历史消息不是追加关系时返回 false
+func diffState(parentID string, parent, next *state.State) (*state.State, bool) {
+	if len(next.History) < len(parent.History) {
+		return nil, false
+	}
+	for i := range parent.History {
+		if !reflect.DeepEqual(parent.History[i], next.History[i]) {
+			return nil, false
+		}
+	}
+
+	marker := &deltaMarker{Parent: parentID}
+	metadata := map[string]interface{}{deltaMetadataKey: marker}
+	for k, v := range next.Metadata {
+		if old, ok := parent.Metadata[k]; !ok || !reflect.DeepEqual(old, v) {
+			metadata[k] = v
+		}
+	}
+	for k := range parent.Metadata {
+		if _, ok := next.Metadata[k]; !ok {
+			marker.Removed = append(marker.Removed, k)
+		}
+	}
+
+	delta := &state.State{
+		History:  append([]llms.MessageContent{}, next.History[len(parent.History):]...),
+		Metadata: metadata,
+	}
+	delta.SetThreadID(next.GetThreadID())
+	delta.SetNode(next.GetNode())
+	delta.SetNextNodes(next.GetNextNodes())
+	delta.SetPendingTasks(next.GetPendingTasks())
+	delta.SetPendingWrites(next.GetPendingWrites())
+
+	return delta, true
+}
+
+// applyDelta 在 base 的基础上应用增量，返回新的完整状态
+func applyDelta(base, delta *state.State, marker *deltaMarker) *state.State {
+	full := cloneState(base)
+	full.History = append(full.History, delta.History...)
+
+	for k, v := range delta.Metadata {
+		if k != deltaMetadataKey {
+			full.Metadata[k] = v
+		}
+	}
+	for _, k := range marker.Removed {
+		delete(full.Metadata, k)
+	}
+
+	full.SetThreadID(delta.GetThreadID())
+	full.SetNode(delta.GetNode())
+	full.SetNextNodes(delta.GetNextNodes())
+	full.SetPendingTasks(delta.GetPendingTasks())
+	full.SetPendingWrites(delta.GetPendingWrites())
+
+	return full
+}
+
+// getDeltaMarker 读取增量描述，完整快照返回 nil
+// 经过序列化的存储中 marker 会变为 map，需要重新解析
+func getDeltaMarker(s *state.State) (*deltaMarker, error) {
+	value, ok := s.Metadata[deltaMetadataKey]
+	if !ok {
+		return nil, nil
+	}
+
+	if marker, ok := value.(*deltaMarker); ok {
+		return marker, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+
+	marker := &deltaMarker{}
+	if err := json.Unmarshal(data, marker); err != nil {
+		return nil, xerror.Wrap(fmt.Errorf("invalid checkpoint delta: %w", err))
+	}
+
+	return marker, nil
+}
+
+// cloneState 复制状态，避免与调用方共享 History 和 Metadata
+func cloneState(s *state.State) *state.State {
+	clone := s.Clone()
+	if clone.Metadata == nil {
+		clone.Metadata = make(map[string]interface{})
+	}
+	return clone
+}
@@ -0,0 +1,168 @@
+package checkpointer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/state"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestDeltaCheckpointer(t *testing.T) {
+	ctx := context.Background()
+	redisCheckpointer, _ := newTestRedisCheckpointer(t)
+
+	for name, inner := range map[string]flowcontract.Checkpointer{
+		"inmemory": NewInMemoryCheckpointer(),
+		"redis":    redisCheckpointer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			checkpointer, err := NewDeltaCheckpointer(inner, WithSnapshotInterval(4))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			current := &state.State{Metadata: map[string]interface{}{"removed": "soon"}}
+			ids := make([]string, 0)
+			for i := 0; i < 10; i++ {
+				current.History = append(current.History, llms.TextParts(llms.ChatMessageTypeHuman, fmt.Sprintf("message %d", i)))
+				current.Metadata["turn"] = fmt.Sprintf("%d", i)
+				if i == 2 {
+					delete(current.Metadata, "removed")
+				}
+				current.SetNode(fmt.Sprintf("node%d", i))
+
+				id, err := checkpointer.Save(ctx, "thread", current)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, id)
+			}
+
+			// 底层只保存了增量
+			payloads, err := inner.GetAll(ctx, "thread")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(payloads[0].History) != 1 || len(payloads[1].History) != 1 || len(payloads[4].History) != 5 {
+				t.Fatalf("unexpected payload sizes %d %d %d", len(payloads[0].History), len(payloads[1].History), len(payloads[4].History))
+			}
+
+			restored, err := checkpointer.GetByID(ctx, "thread", ids[6])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(restored.History) != 7 || restored.Metadata["turn"] != "6" || restored.Metadata["removed"] != nil {
+				t.Fatalf("unexpected restored state %+v", restored)
+			}
+			if _, ok := restored.Metadata[deltaMetadataKey]; ok {
+				t.Fatal("delta marker should not be exposed")
+			}
+
+			all, err := checkpointer.GetAll(ctx, "thread")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, s := range all {
+				if len(s.History) != i+1 {
+					t.Fatalf("unexpected history length %d at %d", len(s.History), i)
+				}
+			}
+
+			latest, err := checkpointer.GetLastest(ctx, "thread")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(latest.History) != 10 {
+				t.Fatalf("unexpected latest history length %d", len(latest.History))
+			}
+
+			checkpoints, _, err := checkpointer.List(ctx, "thread", flowcontract.WithStepRange(5, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(checkpoints) != 6 || len(checkpoints[5].State.History) != 10 {
+				t.Fatalf("unexpected listed checkpoints %d", len(checkpoints))
+			}
+		})
+	}
+}
+
+func TestDeltaCheckpointerRetention(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := NewDeltaCheckpointer(NewInMemoryCheckpointer(WithMaxCheckpoints(3))); err == nil {
+		t.Fatal("expected retention on the inner checkpointer to be rejected")
+	}
+
+	redisCheckpointer, _ := newTestRedisCheckpointer(t)
+	for name, inner := range map[string]deltaStore{
+		"inmemory": NewInMemoryCheckpointer(),
+		"redis":    redisCheckpointer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			checkpointer, err := NewDeltaCheckpointer(inner, WithSnapshotInterval(4), WithMaxCheckpoints(3))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = checkpointer.Close() })
+
+			// 快照位于 0、4、8，其余为增量
+			current := &state.State{Metadata: map[string]interface{}{}}
+			ids := make([]string, 0)
+			for i := 0; i < 10; i++ {
+				current.History = append(current.History, llms.TextParts(llms.ChatMessageTypeHuman, fmt.Sprintf("message %d", i)))
+				id, err := checkpointer.Save(ctx, "thread", current)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, id)
+			}
+
+			// 最近的 3 个检查点中 7 依赖 4、5、6，都需要保留
+			if err := checkpointer.Prune(ctx); err != nil {
+				t.Fatal(err)
+			}
+			all, err := checkpointer.GetAll(ctx, "thread")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(all) != 6 || len(all[0].History) != 5 || len(all[5].History) != 10 {
+				t.Fatalf("unexpected states after prune %d", len(all))
+			}
+			if _, err := checkpointer.GetByID(ctx, "thread", ids[3]); !errors.Is(err, flowcontract.ErrCheckpointNotFound) {
+				t.Fatalf("expected trimmed checkpoint to be not found, got %v", err)
+			}
+
+			// 快照被删除后增量无法还原
+			if err := inner.trimThread(ctx, "thread", 6); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := checkpointer.GetAll(ctx, "thread"); !errors.Is(err, ErrDeltaBaseMissing) {
+				t.Fatalf("expected missing base error, got %v", err)
+			}
+			if _, err := checkpointer.GetByID(ctx, "thread", ids[6]); !errors.Is(err, ErrDeltaBaseMissing) {
+				t.Fatalf("expected missing base error, got %v", err)
+			}
+
+			// 删除线程后不再基于缓存的检查点保存增量
+			if err := checkpointer.DeleteThread(ctx, "thread"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := checkpointer.Save(ctx, "thread", current); err != nil {
+				t.Fatal(err)
+			}
+			latest, err := checkpointer.GetLastest(ctx, "thread")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(latest.History) != 10 {
+				t.Fatalf("unexpected history length %d after delete", len(latest.History))
+			}
+		})
+	}
+}
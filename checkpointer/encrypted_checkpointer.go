@@ -0,0 +1,231 @@
+package checkpointer
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
+)
+
+const (
+	// encryptedMetadataKey 加密后的状态在 Metadata 中的保留 key
+	encryptedMetadataKey = "__checkpoint_encrypted__"
+)
+
+// ErrPlaintextCheckpoint 读取到未加密的检查点，可能是迁移前保存的，也可能是绕过加密写入的
+var ErrPlaintextCheckpoint = errors.New("checkpoint is not encrypted")
+
+// EncryptionKey AES 密钥，Secret 长度必须为 16、24 或 32 字节
+type EncryptionKey struct {
+	ID     string
+	Secret []byte
+}
+
+// sealedState 加密后的状态
+type sealedState struct {
+	KeyID string `json:"kid"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// EncryptedCheckpointer 包装任意 Checkpointer，使用 AES-GCM 加密完整的序列化状态。
+// 新的检查点使用主密钥加密，其他密钥只用于解密，轮换密钥时通过 WithPreviousKeys 传入旧的主密钥即可。
+// 只有节点名以明文保存，用于按节点过滤。未加密的检查点默认视为错误，
+// 迁移已有线程时可以通过 WithAllowPlaintext 按原样返回。
+type EncryptedCheckpointer struct {
+	flowcontract.Checkpointer
+	options *Options
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewEncryptedCheckpointer 创建一个加密检查点包装器，可通过 WithPreviousKeys 和 WithAllowPlaintext 配置
+func NewEncryptedCheckpointer(inner flowcontract.Checkpointer, primary EncryptionKey, opts ...Option) (*EncryptedCheckpointer, error) {
+	c := &EncryptedCheckpointer{
+		Checkpointer: inner,
+		options:      newOptions(opts...),
+		primary:      primary.ID,
+		aeads:        make(map[string]cipher.AEAD),
+	}
+
+	for _, key := range append([]EncryptionKey{primary}, c.options.PreviousKeys...) {
+		if key.ID == "" {
+			return nil, xerror.New("encryption key id cannot be empty")
+		}
+		if _, exists := c.aeads[key.ID]; exists {
+			return nil, xerror.New(fmt.Sprintf("duplicate encryption key id: %s", key.ID))
+		}
+
+		block, err := aes.NewCipher(key.Secret)
+		if err != nil {
+			return nil, xerror.Wrap(fmt.Errorf("invalid encryption key %s: %w", key.ID, err))
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, xerror.Wrap(err)
+		}
+		c.aeads[key.ID] = aead
+	}
+
+	return c, nil
+}
+
+// Save 加密并保存状态
+func (c *EncryptedCheckpointer) Save(ctx context.Context, namespace string, current *state.State) (string, error) {
+	sealed, err := c.seal(namespace, current)
+	if err != nil {
+		return "", err
+	}
+	return c.Checkpointer.Save(ctx, namespace, sealed)
+}
+
+// GetByID 通过 ID 获取并解密状态
+func (c *EncryptedCheckpointer) GetByID(ctx context.Context, namespace string, checkpointerID string) (*state.State, error) {
+	sealed, err := c.Checkpointer.GetByID(ctx, namespace, checkpointerID)
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+	return c.open(namespace, sealed)
+}
+
+// GetLastest 获取并解密最新的状态
+func (c *EncryptedCheckpointer) GetLastest(ctx context.Context, namespace string) (*state.State, error) {
+	sealed, err := c.Checkpointer.GetLastest(ctx, namespace)
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+	return c.open(namespace, sealed)
+}
+
+// GetAll 获取并解密所有状态
+func (c *EncryptedCheckpointer) GetAll(ctx context.Context, namespace string) ([]*state.State, error) {
+	sealed, err := c.Checkpointer.GetAll(ctx, namespace)
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+
+	states := make([]*state.State, len(sealed))
+	for i, s := range sealed {
+		if states[i], err = c.open(namespace, s); err != nil {
+			return nil, err
+		}
+	}
+
+	return states, nil
+}
+
+// List 按条件分页查询并解密检查点，需要被包装的 Checkpointer 实现 CheckpointLister
+func (c *EncryptedCheckpointer) List(ctx context.Context, namespace string, opts ...flowcontract.ListOption) ([]*flowcontract.Checkpoint, string, error) {
+	lister, ok := c.Checkpointer.(flowcontract.CheckpointLister)
+	if !ok {
+		return nil, "", xerror.New("checkpointer does not support listing")
+	}
+
+	checkpoints, cursor, err := lister.List(ctx, namespace, opts...)
+	if err != nil {
+		return nil, "", xerror.Wrap(err)
+	}
+
+	for _, checkpoint := range checkpoints {
+		if checkpoint.State, err = c.open(namespace, checkpoint.State); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return checkpoints, cursor, nil
+}
+
+// Iter 流式读取并解密所有匹配的检查点
+func (c *EncryptedCheckpointer) Iter(ctx context.Context, namespace string, opts ...flowcontract.ListOption) iter.Seq2[*flowcontract.Checkpoint, error] {
+	return iterate(ctx, c.List, namespace, opts...)
+}
+
+// LockThread 获取线程锁，需要被包装的 Checkpointer 实现 ThreadLocker
+func (c *EncryptedCheckpointer) LockThread(ctx context.Context, namespace string, wait bool) (flowcontract.ThreadLock, error) {
+	locker, ok := c.Checkpointer.(flowcontract.ThreadLocker)
+	if !ok {
+		return nil, xerror.New("checkpointer does not support thread locking")
+	}
+	return locker.LockThread(ctx, namespace, wait)
+}
+
+// seal 使用主密钥加密状态，namespace 作为附加数据，防止密文被挪用到其他线程
+func (c *EncryptedCheckpointer) seal(namespace string, current *state.State) (*state.State, error) {
+	plaintext, err := current.Serialize()
+	if err != nil {
+		return nil, xerror.Wrap(fmt.Errorf("failed to serialize state: %w", err))
+	}
+
+	aead := c.aeads[c.primary]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, xerror.Wrap(err)
+	}
+
+	sealed := &state.State{
+		Metadata: map[string]interface{}{
+			encryptedMetadataKey: &sealedState{
+				KeyID: c.primary,
+				Nonce: nonce,
+				Data:  aead.Seal(nil, nonce, plaintext, additionalData(namespace, c.primary)),
+			},
+		},
+	}
+	sealed.SetThreadID(current.GetThreadID())
+	sealed.SetNode(current.GetNode())
+
+	return sealed, nil
+}
+
+// open 解密状态，未加密的状态只在 AllowPlaintext 时原样返回
+func (c *EncryptedCheckpointer) open(namespace string, sealed *state.State) (*state.State, error) {
+	value, ok := sealed.Metadata[encryptedMetadataKey]
+	if !ok {
+		if c.options.AllowPlaintext {
+			return sealed, nil
+		}
+		return nil, xerror.Wrap(fmt.Errorf("%w: namespace %s", ErrPlaintextCheckpoint, namespace))
+	}
+
+	envelope, ok := value.(*sealedState)
+	if !ok {
+		// 经过序列化的存储中信封会变为 map，需要重新解析
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, xerror.Wrap(err)
+		}
+		envelope = &sealedState{}
+		if err := json.Unmarshal(data, envelope); err != nil {
+			return nil, xerror.Wrap(fmt.Errorf("invalid encrypted checkpoint: %w", err))
+		}
+	}
+
+	aead, ok := c.aeads[envelope.KeyID]
+	if !ok {
+		return nil, xerror.New(fmt.Sprintf("unknown encryption key id: %s", envelope.KeyID))
+	}
+
+	plaintext, err := aead.Open(nil, envelope.Nonce, envelope.Data, additionalData(namespace, envelope.KeyID))
+	if err != nil {
+		return nil, xerror.Wrap(fmt.Errorf("failed to decrypt checkpoint: %w", err))
+	}
+
+	opened := &state.State{}
+	if err := opened.Deserialize(plaintext); err != nil {
+		return nil, xerror.Wrap(fmt.Errorf("failed to deserialize state: %w", err))
+	}
+
+	return opened, nil
+}
+
+func additionalData(namespace, keyID string) []byte {
+	return []byte(namespace + "\x00" + keyID)
+}
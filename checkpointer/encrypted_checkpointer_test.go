@@ -0,0 +1,96 @@
+package checkpointer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/futurxlab/golanggraph/state"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestEncryptedCheckpointer(t *testing.T) {
+	ctx := context.Background()
+	inner, server := newTestRedisCheckpointer(t)
+
+	oldKey := EncryptionKey{ID: "v1", Secret: bytes.Repeat([]byte{1}, 32)}
+	newKey := EncryptionKey{ID: "v2", Secret: bytes.Repeat([]byte{2}, 32)}
+
+	before, err := NewEncryptedCheckpointer(inner, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := &state.State{
+		History:  []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "my card number is 4111")},
+		Metadata: map[string]interface{}{"email": "someone@example.com"},
+	}
+	secret.SetNode("chat")
+	secret.SetNextNodes([]string{"tools"})
+
+	oldID, err := before.Save(ctx, "thread", secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 轮换密钥后旧检查点仍可解密
+	after, err := NewEncryptedCheckpointer(inner, newKey, WithPreviousKeys(oldKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := after.Save(ctx, "thread", secret); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range server.Keys() {
+		if value, err := server.Get(key); err == nil && (strings.Contains(value, "4111") || strings.Contains(value, "someone@example.com")) {
+			t.Fatalf("plaintext found in redis key %s", key)
+		}
+	}
+
+	restored, err := after.GetByID(ctx, "thread", oldID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Metadata["email"] != "someone@example.com" || restored.GetNode() != "chat" || restored.GetNextNodes()[0] != "tools" {
+		t.Fatalf("unexpected restored state %+v", restored)
+	}
+
+	all, err := after.GetAll(ctx, "thread")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[1].History[0].Parts[0].(llms.TextContent).Text != "my card number is 4111" {
+		t.Fatalf("unexpected states %+v", all)
+	}
+
+	if _, err := before.GetLastest(ctx, "thread"); err == nil {
+		t.Fatal("expected error when decrypting with unknown key")
+	}
+
+	if _, err := after.GetLastest(ctx, "other"); err == nil {
+		t.Fatal("expected error for empty namespace")
+	}
+
+	// 未加密的检查点默认拒绝，迁移时才按原样返回
+	if _, err := inner.Save(ctx, "plain", secret); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := after.GetLastest(ctx, "plain"); !errors.Is(err, ErrPlaintextCheckpoint) {
+		t.Fatalf("expected plaintext checkpoint to be rejected, got %v", err)
+	}
+	migrating, err := NewEncryptedCheckpointer(inner, newKey, WithPreviousKeys(oldKey), WithAllowPlaintext())
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := migrating.GetLastest(ctx, "plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.Metadata["email"] != "someone@example.com" {
+		t.Fatalf("unexpected plaintext state %+v", plain)
+	}
+}
@@ -3,17 +3,25 @@ package checkpointer
 import (
 	"context"
 	"fmt"
+	"iter"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
 
+	flowcontract "github.com/futurxlab/golanggraph/contract"
 	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
 
 	"github.com/google/uuid"
 )
 
-// StateEntry 用于存储状态及其ID
+// StateEntry 用于存储状态及其ID，Step 为线程内单调递增的序号
 type StateEntry struct {
-	ID    string
-	State *state.State
+	ID        string
+	Step      int
+	State     *state.State
+	CreatedAt time.Time
 }
 
 // InMemoryCheckpointer 实现了 Checkpointer 接口，使用内存存储状态
@@ -21,14 +29,26 @@ type InMemoryCheckpointer struct {
 	mu sync.RWMutex
 	// 使用 map 存储不同 namespace 的状态切片
 	// key 是 namespace，value 是有序的状态切片
-	states map[string][]StateEntry
+	states  map[string][]StateEntry
+	options *Options
+	pruner  *pruner
+	locks   memoryLocks
 }
 
-// NewInMemoryCheckpointer 创建一个新的 InMemoryCheckpointer 实例
-func NewInMemoryCheckpointer() *InMemoryCheckpointer {
-	return &InMemoryCheckpointer{
-		states: make(map[string][]StateEntry),
+// NewInMemoryCheckpointer 创建一个新的 InMemoryCheckpointer 实例，配置了保留策略时会启动后台清理
+func NewInMemoryCheckpointer(opts ...Option) *InMemoryCheckpointer {
+	c := &InMemoryCheckpointer{
+		states:  make(map[string][]StateEntry),
+		options: newOptions(opts...),
 	}
+	c.pruner = startPruner(c.options, c.Prune)
+	return c
+}
+
+// Close 停止后台清理
+func (c *InMemoryCheckpointer) Close() error {
+	c.pruner.stop()
+	return nil
 }
 
 // Save 保存状态到内存中
@@ -39,8 +59,10 @@ func (c *InMemoryCheckpointer) Save(ctx context.Context, namespace string, state
 	// 使用时间戳作为 checkpointerID
 	checkpointerID := uuid.New().String()
 	entry := StateEntry{
-		ID:    checkpointerID,
-		State: state,
+		ID:        checkpointerID,
+		Step:      c.nextStep(namespace),
+		State:     state.Clone(),
+		CreatedAt: time.Now(),
 	}
 
 	// 将新状态追加到切片末尾
@@ -49,6 +71,37 @@ func (c *InMemoryCheckpointer) Save(ctx context.Context, namespace string, state
 	return checkpointerID, nil
 }
 
+// Import 按给定的 ID 和创建时间追加检查点，ID 已存在时跳过
+func (c *InMemoryCheckpointer) Import(ctx context.Context, namespace string, checkpoint *flowcontract.Checkpoint) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, entry := range c.states[namespace] {
+		if entry.ID == checkpoint.ID {
+			return false, nil
+		}
+	}
+
+	c.states[namespace] = append(c.states[namespace], StateEntry{
+		ID:        checkpoint.ID,
+		Step:      c.nextStep(namespace),
+		State:     checkpoint.State.Clone(),
+		CreatedAt: checkpoint.CreatedAt,
+	})
+
+	return true, nil
+}
+
+// nextStep 返回线程下一个检查点的 step，调用方需要持有写锁。
+// 只会从头部裁剪，最后一个检查点的 step 总是当前最大值
+func (c *InMemoryCheckpointer) nextStep(namespace string) int {
+	entries := c.states[namespace]
+	if len(entries) == 0 {
+		return 1
+	}
+	return entries[len(entries)-1].Step + 1
+}
+
 // GetByID 通过 ID 获取状态
 func (c *InMemoryCheckpointer) GetByID(ctx context.Context, namespace string, checkpointerID string) (*state.State, error) {
 	c.mu.RLock()
@@ -57,12 +110,12 @@ func (c *InMemoryCheckpointer) GetByID(ctx context.Context, namespace string, ch
 	if states, exists := c.states[namespace]; exists {
 		for _, entry := range states {
 			if entry.ID == checkpointerID {
-				return entry.State, nil
+				return entry.State.Clone(), nil
 			}
 		}
 	}
 
-	return nil, fmt.Errorf("state not found for namespace %s and ID %s", namespace, checkpointerID)
+	return nil, xerror.Wrap(fmt.Errorf("state not found for namespace %s and ID %s: %w", namespace, checkpointerID, flowcontract.ErrCheckpointNotFound))
 }
 
 // GetLastest 获取最新的状态
@@ -73,11 +126,11 @@ func (c *InMemoryCheckpointer) GetLastest(ctx context.Context, namespace string)
 	if states, exists := c.states[namespace]; exists {
 		if len(states) > 0 {
 			// 返回切片中的最后一个状态
-			return states[len(states)-1].State, nil
+			return states[len(states)-1].State.Clone(), nil
 		}
 	}
 
-	return nil, fmt.Errorf("no states found for namespace %s", namespace)
+	return nil, xerror.Wrap(fmt.Errorf("no states found for namespace %s: %w", namespace, flowcontract.ErrCheckpointNotFound))
 }
 
 // GetAll 获取所有状态
@@ -85,13 +138,143 @@ func (c *InMemoryCheckpointer) GetAll(ctx context.Context, namespace string) ([]
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	if states, exists := c.states[namespace]; exists {
+	if states, exists := c.states[namespace]; exists && len(states) > 0 {
 		result := make([]*state.State, len(states))
 		for i, entry := range states {
-			result[i] = entry.State
+			result[i] = entry.State.Clone()
 		}
 		return result, nil
 	}
 
-	return nil, fmt.Errorf("no states found for namespace %s", namespace)
+	return nil, xerror.Wrap(fmt.Errorf("no states found for namespace %s: %w", namespace, flowcontract.ErrCheckpointNotFound))
+}
+
+// List 按条件分页查询检查点
+func (c *InMemoryCheckpointer) List(ctx context.Context, namespace string, opts ...flowcontract.ListOption) ([]*flowcontract.Checkpoint, string, error) {
+	options := flowcontract.NewListOptions(opts...)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entries := c.states[namespace]
+	if len(entries) == 0 {
+		return []*flowcontract.Checkpoint{}, "", nil
+	}
+
+	// 现存检查点的 step 是连续的
+	first := entries[0].Step
+	steps, err := scanSteps(first, entries[len(entries)-1].Step, options)
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := make([]*flowcontract.Checkpoint, 0)
+	for i, step := range steps {
+		entry := entries[step-first]
+		checkpoint := &flowcontract.Checkpoint{
+			ID:        entry.ID,
+			Namespace: namespace,
+			Step:      entry.Step,
+			Node:      entry.State.GetNode(),
+			CreatedAt: entry.CreatedAt,
+			State:     entry.State.Clone(),
+		}
+		if !options.Match(checkpoint) {
+			continue
+		}
+
+		result = append(result, checkpoint)
+		if len(result) == options.Limit {
+			if i+1 < len(steps) {
+				return result, strconv.Itoa(steps[i+1]), nil
+			}
+			break
+		}
+	}
+
+	return result, "", nil
+}
+
+// Iter 流式读取所有匹配的检查点
+func (c *InMemoryCheckpointer) Iter(ctx context.Context, namespace string, opts ...flowcontract.ListOption) iter.Seq2[*flowcontract.Checkpoint, error] {
+	return iterate(ctx, c.List, namespace, opts...)
+}
+
+// ListThreads 按最近更新时间倒序返回所有线程
+func (c *InMemoryCheckpointer) ListThreads(ctx context.Context) ([]*flowcontract.Thread, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	threads := make([]*flowcontract.Thread, 0, len(c.states))
+	for namespace, entries := range c.states {
+		if len(entries) == 0 {
+			continue
+		}
+		threads = append(threads, &flowcontract.Thread{
+			ID:              namespace,
+			CheckpointCount: len(entries),
+			UpdatedAt:       entries[len(entries)-1].CreatedAt,
+		})
+	}
+
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i].UpdatedAt.After(threads[j].UpdatedAt)
+	})
+
+	return threads, nil
+}
+
+// DeleteThread 删除线程及其所有检查点
+func (c *InMemoryCheckpointer) DeleteThread(ctx context.Context, namespace string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.states, namespace)
+	return nil
+}
+
+// trimThread 删除线程中 step 小于 before 的检查点
+func (c *InMemoryCheckpointer) trimThread(ctx context.Context, namespace string, before int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := c.states[namespace]
+	i := sort.Search(len(entries), func(i int) bool {
+		return entries[i].Step >= before
+	})
+	switch {
+	case i == 0:
+	case i == len(entries):
+		delete(c.states, namespace)
+	default:
+		c.states[namespace] = append([]StateEntry(nil), entries[i:]...)
+	}
+
+	return nil
+}
+
+// getOptions 返回检查点配置
+func (c *InMemoryCheckpointer) getOptions() *Options {
+	return c.options
+}
+
+// Prune 删除过期线程，并只保留每个线程最近的 MaxCheckpoints 个检查点
+func (c *InMemoryCheckpointer) Prune(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for namespace, entries := range c.states {
+		if len(entries) == 0 || (c.options.TTL > 0 && now.Sub(entries[len(entries)-1].CreatedAt) > c.options.TTL) {
+			delete(c.states, namespace)
+			continue
+		}
+
+		if c.options.MaxCheckpoints > 0 && len(entries) > c.options.MaxCheckpoints {
+			// 复制到新切片，释放被裁剪的旧状态
+			c.states[namespace] = append([]StateEntry(nil), entries[len(entries)-c.options.MaxCheckpoints:]...)
+		}
+	}
+
+	return nil
 }
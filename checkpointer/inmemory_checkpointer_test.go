@@ -0,0 +1,144 @@
+package checkpointer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/state"
+)
+
+func TestInMemoryCheckpointerList(t *testing.T) {
+	ctx := context.Background()
+	checkpointer := NewInMemoryCheckpointer()
+
+	for i := 0; i < 10; i++ {
+		s := &state.State{}
+		s.SetNode(fmt.Sprintf("node%d", i%2))
+		if _, err := checkpointer.Save(ctx, "thread", s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("paginate with cursor", func(t *testing.T) {
+		page, cursor, err := checkpointer.List(ctx, "thread", flowcontract.WithLimit(4))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 4 || page[0].Step != 1 || cursor != "5" {
+			t.Fatalf("unexpected first page %d %s", len(page), cursor)
+		}
+
+		page, cursor, err = checkpointer.List(ctx, "thread", flowcontract.WithLimit(4), flowcontract.WithCursor(cursor))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 4 || page[0].Step != 5 {
+			t.Fatalf("unexpected second page %d", len(page))
+		}
+
+		page, cursor, err = checkpointer.List(ctx, "thread", flowcontract.WithLimit(4), flowcontract.WithCursor(cursor))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 2 || cursor != "" {
+			t.Fatalf("unexpected last page %d %s", len(page), cursor)
+		}
+	})
+
+	t.Run("filter by node and step", func(t *testing.T) {
+		page, _, err := checkpointer.List(ctx, "thread",
+			flowcontract.WithNode("node1"),
+			flowcontract.WithStepRange(3, 8),
+			flowcontract.WithReverse(),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 3 || page[0].Step != 8 || page[2].Step != 4 {
+			t.Fatalf("unexpected result %+v", page)
+		}
+	})
+
+	t.Run("iterate with early stop", func(t *testing.T) {
+		count := 0
+		for checkpoint, err := range checkpointer.Iter(ctx, "thread", flowcontract.WithLimit(3)) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			count++
+			if checkpoint.Step == 7 {
+				break
+			}
+		}
+		if count != 7 {
+			t.Fatalf("expected 7 checkpoints, got %d", count)
+		}
+	})
+}
+
+func TestInMemoryCheckpointerThreads(t *testing.T) {
+	ctx := context.Background()
+	checkpointer := NewInMemoryCheckpointer(WithMaxCheckpoints(2), WithTTL(time.Hour))
+	defer checkpointer.Close()
+
+	for _, namespace := range []string{"a", "b", "a", "a"} {
+		if _, err := checkpointer.Save(ctx, namespace, &state.State{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	threads, err := checkpointer.ListThreads(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(threads) != 2 || threads[0].ID != "a" || threads[0].CheckpointCount != 3 {
+		t.Fatalf("unexpected threads %+v", threads)
+	}
+
+	if err := checkpointer.Prune(ctx); err != nil {
+		t.Fatal(err)
+	}
+	all, err := checkpointer.GetAll(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected 2 checkpoints after prune, got %d", len(all))
+	}
+
+	// 裁剪后 step 不变，游标指向原来的检查点
+	page, cursor, err := checkpointer.List(ctx, "a", flowcontract.WithLimit(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].Step != 2 || cursor != "3" {
+		t.Fatalf("unexpected page after prune %+v %q", page, cursor)
+	}
+	if page, _, _ = checkpointer.List(ctx, "a", flowcontract.WithCursor("1")); len(page) != 2 || page[0].Step != 2 {
+		t.Fatalf("expected trimmed cursor to continue from the oldest checkpoint, got %+v", page)
+	}
+	if _, _, err := checkpointer.List(ctx, "a", flowcontract.WithCursor("abc")); !errors.Is(err, flowcontract.ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+
+	if err := checkpointer.DeleteThread(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkpointer.GetLastest(ctx, "a"); err == nil {
+		t.Fatal("expected error for deleted thread")
+	}
+
+	// 模拟过期
+	checkpointer.states["b"][0].CreatedAt = time.Now().Add(-2 * time.Hour)
+	if err := checkpointer.Prune(ctx); err != nil {
+		t.Fatal(err)
+	}
+	threads, _ = checkpointer.ListThreads(ctx)
+	if len(threads) != 0 {
+		t.Fatalf("expected expired thread to be pruned, got %+v", threads)
+	}
+}
@@ -0,0 +1,96 @@
+package checkpointer
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strconv"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/xerror"
+)
+
+type listFunc func(ctx context.Context, namespace string, opts ...flowcontract.ListOption) ([]*flowcontract.Checkpoint, string, error)
+
+// scanSteps 根据过滤条件和游标计算本次需要扫描的 step 序列，[first, last] 为线程中现存检查点的 step 范围。
+// step 在清理后保持不变，游标被清理时从最早的现存检查点继续
+func scanSteps(first, last int, options *flowcontract.ListOptions) ([]int, error) {
+	if options.FromStep > first {
+		first = options.FromStep
+	}
+	if options.ToStep > 0 && options.ToStep < last {
+		last = options.ToStep
+	}
+
+	if options.Cursor != "" {
+		cursor, err := strconv.Atoi(options.Cursor)
+		if err != nil || cursor <= 0 {
+			return nil, xerror.Wrap(fmt.Errorf("%w: %s", flowcontract.ErrInvalidCursor, options.Cursor))
+		}
+		if options.Reverse && cursor < last {
+			last = cursor
+		}
+		if !options.Reverse && cursor > first {
+			first = cursor
+		}
+	}
+
+	if first > last {
+		return nil, nil
+	}
+
+	steps := make([]int, 0, last-first+1)
+	for step := first; step <= last; step++ {
+		steps = append(steps, step)
+	}
+
+	if options.Reverse {
+		for i, j := 0, len(steps)-1; i < j; i, j = i+1, j-1 {
+			steps[i], steps[j] = steps[j], steps[i]
+		}
+	}
+
+	return steps, nil
+}
+
+// nextCursor 返回 step 之后下一页的游标，end 为本次扫描的最后一个 step，已经扫描完时返回空
+func nextCursor(step, end int, reverse bool) string {
+	if reverse {
+		step--
+		if step < end {
+			return ""
+		}
+	} else {
+		step++
+		if step > end {
+			return ""
+		}
+	}
+	return strconv.Itoa(step)
+}
+
+// iterate 基于 List 分页实现流式读取
+func iterate(ctx context.Context, list listFunc, namespace string, opts ...flowcontract.ListOption) iter.Seq2[*flowcontract.Checkpoint, error] {
+	return func(yield func(*flowcontract.Checkpoint, error) bool) {
+		cursor := ""
+		for {
+			pageOpts := append(append([]flowcontract.ListOption{}, opts...), flowcontract.WithCursor(cursor))
+			checkpoints, next, err := list(ctx, namespace, pageOpts...)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, checkpoint := range checkpoints {
+				if !yield(checkpoint, nil) {
+					return
+				}
+			}
+
+			if next == "" {
+				return
+			}
+			cursor = next
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package checkpointer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/xerror"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// LockRetryInterval 排队等待 Redis 线程锁时重试的间隔
+	LockRetryInterval = time.Millisecond * 100
+)
+
+// renewLockScript 锁仍由当前持有者持有时续期
+// KEYS[1] 锁 key, ARGV[1] 持有者 token, ARGV[2] 租期毫秒数
+var renewLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// unlockScript 锁仍由当前持有者持有时删除
+// KEYS[1] 锁 key, ARGV[1] 持有者 token
+var unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// threadLock 通用的线程锁实现，release 只会被调用一次
+type threadLock struct {
+	lost       chan struct{}
+	lostOnce   sync.Once
+	unlockOnce sync.Once
+	release    func(ctx context.Context) error
+}
+
+func newThreadLock(release func(ctx context.Context) error) *threadLock {
+	return &threadLock{
+		lost:    make(chan struct{}),
+		release: release,
+	}
+}
+
+func (l *threadLock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *threadLock) Unlock(ctx context.Context) error {
+	var err error
+	l.unlockOnce.Do(func() {
+		err = l.release(ctx)
+	})
+	return err
+}
+
+func (l *threadLock) markLost() {
+	l.lostOnce.Do(func() {
+		close(l.lost)
+	})
+}
+
+// memoryLock 进程内的线程锁，refs 为持有和等待的数量，归零时从 map 中删除
+type memoryLock struct {
+	sem  chan struct{}
+	refs int
+}
+
+// memoryLocks 按线程分配的进程内互斥锁，等待时可以被 ctx 取消
+type memoryLocks struct {
+	mu    sync.Mutex
+	locks map[string]*memoryLock
+}
+
+func (m *memoryLocks) lock(ctx context.Context, namespace string, wait bool) (flowcontract.ThreadLock, error) {
+	m.mu.Lock()
+	if m.locks == nil {
+		m.locks = make(map[string]*memoryLock)
+	}
+	entry, ok := m.locks[namespace]
+	if !ok {
+		entry = &memoryLock{sem: make(chan struct{}, 1)}
+		m.locks[namespace] = entry
+	}
+	entry.refs++
+	m.mu.Unlock()
+
+	if wait {
+		select {
+		case entry.sem <- struct{}{}:
+		case <-ctx.Done():
+			m.release(namespace, entry)
+			return nil, xerror.Wrap(ctx.Err())
+		}
+	} else {
+		select {
+		case entry.sem <- struct{}{}:
+		default:
+			m.release(namespace, entry)
+			return nil, xerror.Wrap(fmt.Errorf("%w: %s", flowcontract.ErrThreadBusy, namespace))
+		}
+	}
+
+	return newThreadLock(func(ctx context.Context) error {
+		<-entry.sem
+		m.release(namespace, entry)
+		return nil
+	}), nil
+}
+
+func (m *memoryLocks) release(namespace string, entry *memoryLock) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry.refs--
+	if entry.refs == 0 {
+		delete(m.locks, namespace)
+	}
+}
+
+// LockThread 获取进程内的线程锁
+func (c *InMemoryCheckpointer) LockThread(ctx context.Context, namespace string, wait bool) (flowcontract.ThreadLock, error) {
+	return c.locks.lock(ctx, namespace, wait)
+}
+
+// getLockKey 生成线程锁的 Redis key
+func (c *RedisCheckpointer) getLockKey(namespace string) string {
+	return fmt.Sprintf("%s:%s:lock", c.options.KeyPrefix, c.getThreadTag(namespace))
+}
+
+// LockThread 获取基于租期的 Redis 线程锁，持有期间在后台续期，
+// 续期发现锁已被其他持有者获取，或续期失败后剩余租期不足一次续期间隔时关闭 Lost，
+// 保证在锁过期、被其他持有者获取之前停止写入
+func (c *RedisCheckpointer) LockThread(ctx context.Context, namespace string, wait bool) (flowcontract.ThreadLock, error) {
+	key := c.getLockKey(namespace)
+	token := uuid.New().String()
+	lease := c.options.LockLease
+
+	var acquiredAt time.Time
+	for {
+		// 租期从发出请求时开始计算，本地认为的过期时间不会晚于 Redis 中的过期时间
+		acquiredAt = time.Now()
+		acquired, err := c.client.SetNX(ctx, key, token, lease).Result()
+		if err != nil {
+			return nil, xerror.Wrap(err)
+		}
+		if acquired {
+			break
+		}
+		if !wait {
+			return nil, xerror.Wrap(fmt.Errorf("%w: %s", flowcontract.ErrThreadBusy, namespace))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, xerror.Wrap(ctx.Err())
+		case <-time.After(LockRetryInterval):
+		}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	lock := newThreadLock(func(ctx context.Context) error {
+		close(done)
+		<-stopped
+		if err := unlockScript.Run(ctx, c.client, []string{key}, token).Err(); err != nil {
+			return xerror.Wrap(err)
+		}
+		return nil
+	})
+
+	go func() {
+		defer close(stopped)
+		c.renewLock(key, token, lease, acquiredAt.Add(lease), lock, done)
+	}()
+
+	return lock, nil
+}
+
+// renewLock 每隔租期的三分之一续期一次，直到 done 关闭或锁丢失。
+// 续期只在锁仍由 token 持有时执行；超过租期的三分之二没有续期成功时，下一次续期之前锁就可能过期，
+// 此时立即标记锁丢失，保证在锁过期、被其他持有者获取之前停止写入
+func (c *RedisCheckpointer) renewLock(key, token string, lease time.Duration, expires time.Time, lock *threadLock, done <-chan struct{}) {
+	interval := lease / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	deadline := time.NewTimer(time.Until(expires.Add(-interval)))
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-deadline.C:
+			lock.markLost()
+			return
+		case <-ticker.C:
+		}
+
+		// 续期请求不能拖过安全期限，否则阻塞期间锁可能已经过期
+		attempt := time.Now()
+		ctx, cancel := context.WithDeadline(context.Background(), expires.Add(-interval))
+		result, err := renewLockScript.Run(ctx, c.client, []string{key}, token, lease.Milliseconds()).Int()
+		cancel()
+
+		switch {
+		case err != nil:
+			if c.options.Logger != nil {
+				c.options.Logger.Errorf(ctx, "renew thread lock %s failed %s", key, err)
+			}
+			// 网络抖动时继续重试，剩余租期不足一次续期间隔时不再等待下一次续期
+			if time.Until(expires) <= interval {
+				lock.markLost()
+				return
+			}
+		case result == 0:
+			lock.markLost()
+			return
+		default:
+			expires = attempt.Add(lease)
+			deadline.Reset(time.Until(expires.Add(-interval)))
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package checkpointer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/futurxlab/golanggraph/logger"
+)
+
+const (
+	DefaultPruneInterval = time.Minute
+	DefaultKeyPrefix     = "checkpointer"
+	DefaultLockLease     = time.Second * 30
+)
+
+// Options 检查点的保留策略
+type Options struct {
+	// TTL 线程最后一次保存后超过该时长即被删除，0 表示永不过期
+	TTL time.Duration
+	// MaxCheckpoints 每个线程最多保留的检查点数量，0 表示不限制
+	MaxCheckpoints int
+	// PruneInterval 后台清理的间隔，配置了保留策略时默认为 DefaultPruneInterval
+	PruneInterval time.Duration
+	Logger        logger.ILogger
+
+	// KeyPrefix Redis key 前缀，默认为 DefaultKeyPrefix，仅 RedisCheckpointer 使用
+	KeyPrefix string
+	// HashTag 为 true 时 Redis key 中的 namespace 使用 {namespace} 包裹，
+	// 使同一线程的所有 key 落在同一个 cluster slot，使用 *redis.ClusterClient 时自动开启
+	HashTag bool
+
+	// LockLease Redis 线程锁的租期，持有期间每隔租期的三分之一续期一次，默认为 DefaultLockLease
+	LockLease time.Duration
+
+	// SnapshotInterval 增量检查点每隔多少个检查点保存一次完整快照，仅 DeltaCheckpointer 使用
+	SnapshotInterval int
+
+	// PreviousKeys 只用于解密的旧密钥，仅 EncryptedCheckpointer 使用
+	PreviousKeys []EncryptionKey
+	// AllowPlaintext 为 true 时未加密的历史检查点按原样返回，仅用于迁移，仅 EncryptedCheckpointer 使用
+	AllowPlaintext bool
+}
+
+type Option func(*Options)
+
+func WithTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.TTL = ttl
+	}
+}
+
+func WithMaxCheckpoints(max int) Option {
+	return func(o *Options) {
+		o.MaxCheckpoints = max
+	}
+}
+
+func WithPruneInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.PruneInterval = interval
+	}
+}
+
+func WithLogger(logger logger.ILogger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+func WithKeyPrefix(prefix string) Option {
+	return func(o *Options) {
+		o.KeyPrefix = prefix
+	}
+}
+
+func WithHashTag() Option {
+	return func(o *Options) {
+		o.HashTag = true
+	}
+}
+
+func WithLockLease(lease time.Duration) Option {
+	return func(o *Options) {
+		o.LockLease = lease
+	}
+}
+
+func WithSnapshotInterval(interval int) Option {
+	return func(o *Options) {
+		o.SnapshotInterval = interval
+	}
+}
+
+// WithPreviousKeys 添加只用于解密的旧密钥，轮换密钥时把旧的主密钥放入其中
+func WithPreviousKeys(keys ...EncryptionKey) Option {
+	return func(o *Options) {
+		o.PreviousKeys = append(o.PreviousKeys, keys...)
+	}
+}
+
+// WithAllowPlaintext 允许读取未加密的历史检查点，用于把已有的线程迁移到 EncryptedCheckpointer
+func WithAllowPlaintext() Option {
+	return func(o *Options) {
+		o.AllowPlaintext = true
+	}
+}
+
+func newOptions(opts ...Option) *Options {
+	options := &Options{
+		KeyPrefix: DefaultKeyPrefix,
+		LockLease: DefaultLockLease,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if options.PruneInterval == 0 && (options.TTL > 0 || options.MaxCheckpoints > 0) {
+		options.PruneInterval = DefaultPruneInterval
+	}
+
+	return options
+}
+
+// pruner 定期执行清理，直到 stop 被调用
+type pruner struct {
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func startPruner(options *Options, prune func(ctx context.Context) error) *pruner {
+	p := &pruner{done: make(chan struct{})}
+	if options.PruneInterval <= 0 {
+		return p
+	}
+
+	go func() {
+		ticker := time.NewTicker(options.PruneInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				ctx := context.Background()
+				if err := prune(ctx); err != nil && options.Logger != nil {
+					options.Logger.Errorf(ctx, "checkpointer prune failed %s", err)
+				}
+			}
+		}
+	}()
+
+	return p
+}
+
+func (p *pruner) stop() {
+	p.stopOnce.Do(func() {
+		close(p.done)
+	})
+}
@@ -3,8 +3,13 @@ package checkpointer
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"iter"
+	"strconv"
+	"time"
 
+	flowcontract "github.com/futurxlab/golanggraph/contract"
 	"github.com/futurxlab/golanggraph/state"
 	"github.com/futurxlab/golanggraph/xerror"
 
@@ -12,71 +17,230 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+const (
+	// redisBatchSize 单次 MGET/HMGET 读取的最大 key 数量
+	redisBatchSize = 100
+
+	// maxWatchRetries 乐观事务因线程被并发写入而失败时的最大重试次数
+	maxWatchRetries = 16
+)
+
+// checkpointMeta 检查点的元信息，用于在不读取完整状态的情况下过滤
+type checkpointMeta struct {
+	Node      string    `json:"node"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// rangeScript 原子地读取线程已裁剪的数量、现存检查点数量以及 step 在 [ARGV[1], ARGV[2]] 内的检查点 ID，
+// step 为已裁剪数量加上在列表中的位置（从 1 开始）
+// KEYS[1] 状态 ID 列表, KEYS[2] 已裁剪数量, ARGV[1] 起始 step, ARGV[2] 结束 step
+var rangeScript = redis.NewScript(`
+local trimmed = tonumber(redis.call('GET', KEYS[2]) or '0')
+local start = math.max(tonumber(ARGV[1]) - trimmed - 1, 0)
+local stop = tonumber(ARGV[2]) - trimmed - 1
+local ids = {}
+if stop >= start then
+	ids = redis.call('LRANGE', KEYS[1], start, stop)
+end
+return {trimmed, redis.call('LLEN', KEYS[1]), ids}
+`)
+
+// importScript 原子地按给定 ID 追加检查点，ID 已存在时跳过
+// KEYS[1] 状态 ID 列表, KEYS[2] 元信息 hash, KEYS[3] 状态 key, ARGV[1] ID, ARGV[2] 状态, ARGV[3] 元信息
+var importScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[2], ARGV[1]) == 1 then
+	return 0
+end
+redis.call('SET', KEYS[3], ARGV[2])
+redis.call('RPUSH', KEYS[1], ARGV[1])
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[3])
+return 1
+`)
+
+// removeExpiredScript 线程在索引中的更新时间不晚于 ARGV[2] 时才移除，避免移除删除后又写入的线程
+// KEYS[1] 线程索引, ARGV[1] 线程, ARGV[2] 截止时间
+var removeExpiredScript = redis.NewScript(`
+local score = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if score and tonumber(score) <= tonumber(ARGV[2]) then
+	return redis.call('ZREM', KEYS[1], ARGV[1])
+end
+return 0
+`)
+
 // RedisCheckpointer 实现了 Checkpointer 接口，使用 Redis 存储状态
+// 支持单机、Sentinel 和 Cluster 客户端。脚本和事务会访问同一线程的多个 key，
+// 传入 *redis.ClusterClient 时自动开启 HashTag 保证这些 key 在同一个 slot，
+// 通过其他 UniversalClient 实现访问 Cluster 时需要使用 WithHashTag
 type RedisCheckpointer struct {
-	client *redis.Client
+	client  redis.UniversalClient
+	options *Options
+	pruner  *pruner
+}
+
+// NewRedisCheckpointer 创建一个新的 RedisCheckpointer 实例，配置了保留策略时会启动后台清理
+func NewRedisCheckpointer(client redis.UniversalClient, opts ...Option) *RedisCheckpointer {
+	c := &RedisCheckpointer{
+		client:  client,
+		options: newOptions(opts...),
+	}
+	if _, ok := client.(*redis.ClusterClient); ok {
+		c.options.HashTag = true
+	}
+	c.pruner = startPruner(c.options, c.Prune)
+	return c
+}
+
+// Close 停止后台清理，不会关闭 Redis 客户端
+func (c *RedisCheckpointer) Close() error {
+	c.pruner.stop()
+	return nil
 }
 
-// NewRedisCheckpointer 创建一个新的 RedisCheckpointer 实例
-func NewRedisCheckpointer(client *redis.Client) *RedisCheckpointer {
-	return &RedisCheckpointer{
-		client: client,
+// getThreadsKey 生成线程索引的 Redis key，score 为线程最后更新时间
+func (c *RedisCheckpointer) getThreadsKey() string {
+	return fmt.Sprintf("%s:threads", c.options.KeyPrefix)
+}
+
+// getThreadTag 生成 key 中的线程部分，开启 HashTag 时使用 {namespace}
+func (c *RedisCheckpointer) getThreadTag(namespace string) string {
+	if c.options.HashTag {
+		return "{" + namespace + "}"
 	}
+	return namespace
 }
 
 // getNamespaceKey 生成 Redis key
-func getNamespaceKey(namespace string) string {
-	return fmt.Sprintf("checkpointer:%s:states", namespace)
+func (c *RedisCheckpointer) getNamespaceKey(namespace string) string {
+	return fmt.Sprintf("%s:%s:states", c.options.KeyPrefix, c.getThreadTag(namespace))
 }
 
 // getStateKey 生成单个状态的 Redis key
-func getStateKey(namespace, id string) string {
-	return fmt.Sprintf("checkpointer:%s:state:%s", namespace, id)
+func (c *RedisCheckpointer) getStateKey(namespace, id string) string {
+	return fmt.Sprintf("%s:%s:state:%s", c.options.KeyPrefix, c.getThreadTag(namespace), id)
+}
+
+// getStateKeys 生成一批状态的 Redis key
+func (c *RedisCheckpointer) getStateKeys(namespace string, ids []string) []string {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = c.getStateKey(namespace, id)
+	}
+	return keys
+}
+
+// getTrimmedKey 生成记录线程已裁剪检查点数量的 key
+func (c *RedisCheckpointer) getTrimmedKey(namespace string) string {
+	return fmt.Sprintf("%s:%s:trimmed", c.options.KeyPrefix, c.getThreadTag(namespace))
+}
+
+// getMetaKey 生成存储检查点元信息的 hash key
+func (c *RedisCheckpointer) getMetaKey(namespace string) string {
+	return fmt.Sprintf("%s:%s:meta", c.options.KeyPrefix, c.getThreadTag(namespace))
 }
 
 // Save 保存状态到 Redis
 func (c *RedisCheckpointer) Save(ctx context.Context, namespace string, state *state.State) (string, error) {
 	checkpointerID := uuid.New().String()
 
-	// 序列化状态
-	stateData, err := json.Marshal(state)
+	// 序列化状态，包括内部参数
+	stateData, err := state.Serialize()
 	if err != nil {
 		return "", xerror.Wrap(fmt.Errorf("failed to marshal state: %w", err))
 	}
 
-	// 使用 Redis Pipeline 来保证原子性
-	pipe := c.client.Pipeline()
+	metaData, err := json.Marshal(checkpointMeta{
+		Node:      state.GetNode(),
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return "", xerror.Wrap(fmt.Errorf("failed to marshal checkpoint meta: %w", err))
+	}
+
+	// 使用事务保证状态、ID 列表和元信息同时写入，这些 key 在开启 HashTag 时位于同一个 slot
+	pipe := c.client.TxPipeline()
 
 	// 保存状态数据
-	stateKey := getStateKey(namespace, checkpointerID)
+	stateKey := c.getStateKey(namespace, checkpointerID)
 	pipe.Set(ctx, stateKey, stateData, 0)
 
 	// 将 ID 添加到有序列表
-	namespaceKey := getNamespaceKey(namespace)
+	namespaceKey := c.getNamespaceKey(namespace)
 	pipe.RPush(ctx, namespaceKey, checkpointerID)
 
+	// 保存元信息
+	pipe.HSet(ctx, c.getMetaKey(namespace), checkpointerID, metaData)
+
 	_, err = pipe.Exec(ctx)
 	if err != nil {
 		return "", xerror.Wrap(fmt.Errorf("failed to save state: %w", err))
 	}
 
+	c.touchThread(ctx, namespace, time.Now(), false)
+
 	return checkpointerID, nil
 }
 
+// Import 按给定的 ID 和创建时间追加检查点，ID 已存在时跳过
+func (c *RedisCheckpointer) Import(ctx context.Context, namespace string, checkpoint *flowcontract.Checkpoint) (bool, error) {
+	stateData, err := checkpoint.State.Serialize()
+	if err != nil {
+		return false, xerror.Wrap(fmt.Errorf("failed to marshal state: %w", err))
+	}
+
+	metaData, err := json.Marshal(checkpointMeta{
+		Node:      checkpoint.State.GetNode(),
+		CreatedAt: checkpoint.CreatedAt,
+	})
+	if err != nil {
+		return false, xerror.Wrap(fmt.Errorf("failed to marshal checkpoint meta: %w", err))
+	}
+
+	keys := []string{c.getNamespaceKey(namespace), c.getMetaKey(namespace), c.getStateKey(namespace, checkpoint.ID)}
+	imported, err := importScript.Run(ctx, c.client, keys, checkpoint.ID, stateData, metaData).Int()
+	if err != nil {
+		return false, xerror.Wrap(fmt.Errorf("failed to import state: %w", err))
+	}
+
+	if imported == 0 {
+		return false, nil
+	}
+
+	// 线程索引只会向后更新
+	c.touchThread(ctx, namespace, checkpoint.CreatedAt, true)
+
+	return true, nil
+}
+
+// touchThread 更新线程索引。线程索引是所有线程共用的 key，和线程的 key 不在同一个 slot，
+// 因此在事务之外单独写入，只是尽力而为：失败时只记录日志，检查点本身已经保存成功，
+// 下一次保存会再次更新索引
+func (c *RedisCheckpointer) touchThread(ctx context.Context, namespace string, updatedAt time.Time, onlyGreater bool) {
+	member := redis.Z{Score: float64(updatedAt.UnixMilli()), Member: namespace}
+	var err error
+	if onlyGreater {
+		err = c.client.ZAddGT(ctx, c.getThreadsKey(), member).Err()
+	} else {
+		err = c.client.ZAdd(ctx, c.getThreadsKey(), member).Err()
+	}
+	if err != nil && c.options.Logger != nil {
+		c.options.Logger.Warnf(ctx, "update thread index for %s failed %s", namespace, err)
+	}
+}
+
 // GetByID 通过 ID 获取状态
 func (c *RedisCheckpointer) GetByID(ctx context.Context, namespace string, checkpointerID string) (*state.State, error) {
-	stateKey := getStateKey(namespace, checkpointerID)
+	stateKey := c.getStateKey(namespace, checkpointerID)
 	data, err := c.client.Get(ctx, stateKey).Bytes()
 	if err != nil {
 		if err == redis.Nil {
-			return nil, xerror.Wrap(fmt.Errorf("state not found for namespace %s and ID %s", namespace, checkpointerID))
+			return nil, xerror.Wrap(fmt.Errorf("state not found for namespace %s and ID %s: %w", namespace, checkpointerID, flowcontract.ErrCheckpointNotFound))
 		}
 		return nil, xerror.Wrap(fmt.Errorf("failed to get state: %w", err))
 	}
 
 	var state state.State
-	if err := json.Unmarshal(data, &state); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+	if err := state.Deserialize(data); err != nil {
+		return nil, xerror.Wrap(fmt.Errorf("failed to unmarshal state: %w", err))
 	}
 
 	return &state, nil
@@ -84,13 +248,13 @@ func (c *RedisCheckpointer) GetByID(ctx context.Context, namespace string, check
 
 // GetLastest 获取最新的状态
 func (c *RedisCheckpointer) GetLastest(ctx context.Context, namespace string) (*state.State, error) {
-	namespaceKey := getNamespaceKey(namespace)
+	namespaceKey := c.getNamespaceKey(namespace)
 
 	// 获取最后一个 ID
 	lastID, err := c.client.LIndex(ctx, namespaceKey, -1).Result()
 	if err != nil {
 		if err == redis.Nil {
-			return nil, xerror.Wrap(fmt.Errorf("no states found for namespace %s", namespace))
+			return nil, xerror.Wrap(fmt.Errorf("no states found for namespace %s: %w", namespace, flowcontract.ErrCheckpointNotFound))
 		}
 		return nil, xerror.Wrap(fmt.Errorf("failed to get latest state ID: %w", err))
 	}
@@ -100,7 +264,7 @@ func (c *RedisCheckpointer) GetLastest(ctx context.Context, namespace string) (*
 
 // GetAll 获取所有状态
 func (c *RedisCheckpointer) GetAll(ctx context.Context, namespace string) ([]*state.State, error) {
-	namespaceKey := getNamespaceKey(namespace)
+	namespaceKey := c.getNamespaceKey(namespace)
 
 	// 获取所有 ID
 	ids, err := c.client.LRange(ctx, namespaceKey, 0, -1).Result()
@@ -109,17 +273,373 @@ func (c *RedisCheckpointer) GetAll(ctx context.Context, namespace string) ([]*st
 	}
 
 	if len(ids) == 0 {
-		return nil, fmt.Errorf("no states found for namespace %s", namespace)
+		return nil, xerror.Wrap(fmt.Errorf("no states found for namespace %s: %w", namespace, flowcontract.ErrCheckpointNotFound))
 	}
 
-	states := make([]*state.State, len(ids))
-	for i, id := range ids {
-		state, err := c.GetByID(ctx, namespace, id)
+	return c.loadStates(ctx, namespace, ids)
+}
+
+// loadStates 使用 MGET 批量读取状态，结果顺序与 ids 一致
+func (c *RedisCheckpointer) loadStates(ctx context.Context, namespace string, ids []string) ([]*state.State, error) {
+	states := make([]*state.State, 0, len(ids))
+	for start := 0; start < len(ids); start += redisBatchSize {
+		end := min(start+redisBatchSize, len(ids))
+
+		values, err := c.client.MGet(ctx, c.getStateKeys(namespace, ids[start:end])...).Result()
 		if err != nil {
-			return nil, xerror.Wrap(fmt.Errorf("failed to get state %s: %w", id, err))
+			return nil, xerror.Wrap(fmt.Errorf("failed to get states: %w", err))
+		}
+
+		for i, value := range values {
+			data, ok := value.(string)
+			if !ok {
+				return nil, xerror.Wrap(fmt.Errorf("state not found for namespace %s and ID %s: %w", namespace, ids[start+i], flowcontract.ErrCheckpointNotFound))
+			}
+
+			var state state.State
+			if err := state.Deserialize([]byte(data)); err != nil {
+				return nil, xerror.Wrap(fmt.Errorf("failed to unmarshal state %s: %w", ids[start+i], err))
+			}
+			states = append(states, &state)
 		}
-		states[i] = state
 	}
 
 	return states, nil
 }
+
+// loadMetas 使用 HMGET 批量读取元信息，缺失元信息的检查点返回零值
+func (c *RedisCheckpointer) loadMetas(ctx context.Context, namespace string, ids []string) ([]checkpointMeta, error) {
+	values, err := c.client.HMGet(ctx, c.getMetaKey(namespace), ids...).Result()
+	if err != nil {
+		return nil, xerror.Wrap(fmt.Errorf("failed to get checkpoint metas: %w", err))
+	}
+
+	metas := make([]checkpointMeta, len(ids))
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal([]byte(data), &metas[i]); err != nil {
+			return nil, xerror.Wrap(fmt.Errorf("failed to unmarshal checkpoint meta %s: %w", ids[i], err))
+		}
+	}
+
+	return metas, nil
+}
+
+// listRange 读取 step 在 [low, high] 内现存的检查点 ID，返回已裁剪数量和现存检查点数量，
+// 第一个 ID 的 step 为 max(low, trimmed+1)
+func (c *RedisCheckpointer) listRange(ctx context.Context, namespace string, low, high int) (int, int, []string, error) {
+	keys := []string{c.getNamespaceKey(namespace), c.getTrimmedKey(namespace)}
+	values, err := rangeScript.Run(ctx, c.client, keys, low, high).Slice()
+	if err != nil {
+		return 0, 0, nil, xerror.Wrap(fmt.Errorf("failed to get state IDs: %w", err))
+	}
+	if len(values) != 3 {
+		return 0, 0, nil, xerror.New(fmt.Sprintf("unexpected range result %v", values))
+	}
+
+	trimmed, _ := values[0].(int64)
+	total, _ := values[1].(int64)
+	items, _ := values[2].([]interface{})
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		id, _ := item.(string)
+		ids = append(ids, id)
+	}
+
+	return int(trimmed), int(total), ids, nil
+}
+
+// List 按条件分页查询检查点，先批量读取元信息过滤，再用 MGET 读取命中的状态
+func (c *RedisCheckpointer) List(ctx context.Context, namespace string, opts ...flowcontract.ListOption) ([]*flowcontract.Checkpoint, string, error) {
+	options := flowcontract.NewListOptions(opts...)
+
+	trimmed, total, _, err := c.listRange(ctx, namespace, 1, 0)
+	if err != nil {
+		return nil, "", err
+	}
+
+	steps, err := scanSteps(trimmed+1, trimmed+total, options)
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := make([]*flowcontract.Checkpoint, 0)
+	cursor := ""
+
+	for start := 0; start < len(steps) && cursor == "" && len(result) < options.Limit; start += redisBatchSize {
+		window := steps[start:min(start+redisBatchSize, len(steps))]
+
+		// window 中的 step 是连续的，可以一次读取；读取期间被裁剪的检查点会被跳过
+		low, high := window[0], window[len(window)-1]
+		if low > high {
+			low, high = high, low
+		}
+		trimmed, _, ids, err := c.listRange(ctx, namespace, low, high)
+		if err != nil {
+			return nil, "", err
+		}
+
+		first := max(low, trimmed+1)
+		windowSteps := make([]int, len(ids))
+		for i := range ids {
+			windowSteps[i] = first + i
+		}
+		if options.Reverse {
+			for i, j := 0, len(ids)-1; i < j; i, j = i+1, j-1 {
+				ids[i], ids[j] = ids[j], ids[i]
+				windowSteps[i], windowSteps[j] = windowSteps[j], windowSteps[i]
+			}
+		}
+
+		metas, err := c.loadMetas(ctx, namespace, ids)
+		if err != nil {
+			return nil, "", err
+		}
+
+		for i, id := range ids {
+			checkpoint := &flowcontract.Checkpoint{
+				ID:        id,
+				Namespace: namespace,
+				Step:      windowSteps[i],
+				Node:      metas[i].Node,
+				CreatedAt: metas[i].CreatedAt,
+			}
+			if !options.Match(checkpoint) {
+				continue
+			}
+
+			result = append(result, checkpoint)
+			if len(result) == options.Limit {
+				cursor = nextCursor(checkpoint.Step, steps[len(steps)-1], options.Reverse)
+				break
+			}
+		}
+	}
+
+	ids := make([]string, len(result))
+	for i, checkpoint := range result {
+		ids[i] = checkpoint.ID
+	}
+
+	states, err := c.loadStates(ctx, namespace, ids)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for i, state := range states {
+		result[i].State = state
+	}
+
+	return result, cursor, nil
+}
+
+// Iter 流式读取所有匹配的检查点
+func (c *RedisCheckpointer) Iter(ctx context.Context, namespace string, opts ...flowcontract.ListOption) iter.Seq2[*flowcontract.Checkpoint, error] {
+	return iterate(ctx, c.List, namespace, opts...)
+}
+
+// ListThreads 按最近更新时间倒序返回线程索引中的所有线程
+func (c *RedisCheckpointer) ListThreads(ctx context.Context) ([]*flowcontract.Thread, error) {
+	members, err := c.client.ZRevRangeWithScores(ctx, c.getThreadsKey(), 0, -1).Result()
+	if err != nil {
+		return nil, xerror.Wrap(fmt.Errorf("failed to list threads: %w", err))
+	}
+
+	pipe := c.client.Pipeline()
+	counts := make([]*redis.IntCmd, len(members))
+	for i, member := range members {
+		counts[i] = pipe.LLen(ctx, c.getNamespaceKey(member.Member.(string)))
+	}
+	if len(members) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, xerror.Wrap(fmt.Errorf("failed to count checkpoints: %w", err))
+		}
+	}
+
+	threads := make([]*flowcontract.Thread, len(members))
+	for i, member := range members {
+		threads[i] = &flowcontract.Thread{
+			ID:              member.Member.(string),
+			CheckpointCount: int(counts[i].Val()),
+			UpdatedAt:       time.UnixMilli(int64(member.Score)),
+		}
+	}
+
+	return threads, nil
+}
+
+// DeleteThread 删除线程的所有状态、元信息以及索引
+func (c *RedisCheckpointer) DeleteThread(ctx context.Context, namespace string) error {
+	if _, err := c.deleteThread(ctx, namespace, time.Time{}); err != nil {
+		return err
+	}
+
+	if err := c.client.ZRem(ctx, c.getThreadsKey(), namespace).Err(); err != nil {
+		return xerror.Wrap(fmt.Errorf("failed to remove thread %s from index: %w", namespace, err))
+	}
+
+	return nil
+}
+
+// deleteThread 在乐观事务中删除线程的所有状态、元信息、ID 列表和已裁剪数量。
+// expiredBefore 不为零值时先在同一个事务中检查最新检查点的创建时间，
+// 线程在 expiredBefore 之后有写入时不删除并返回 false
+func (c *RedisCheckpointer) deleteThread(ctx context.Context, namespace string, expiredBefore time.Time) (bool, error) {
+	namespaceKey := c.getNamespaceKey(namespace)
+	deleted := false
+	err := c.watchThread(ctx, namespace, func(tx *redis.Tx) error {
+		deleted = false
+		ids, err := tx.LRange(ctx, namespaceKey, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+
+		if !expiredBefore.IsZero() && len(ids) > 0 {
+			data, err := tx.HGet(ctx, c.getMetaKey(namespace), ids[len(ids)-1]).Result()
+			if err != nil && err != redis.Nil {
+				return err
+			}
+			var meta checkpointMeta
+			if data != "" {
+				if err := json.Unmarshal([]byte(data), &meta); err != nil {
+					return err
+				}
+			}
+			if meta.CreatedAt.After(expiredBefore) {
+				return nil
+			}
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for start := 0; start < len(ids); start += redisBatchSize {
+				pipe.Del(ctx, c.getStateKeys(namespace, ids[start:min(start+redisBatchSize, len(ids))])...)
+			}
+			pipe.Del(ctx, namespaceKey, c.getMetaKey(namespace), c.getTrimmedKey(namespace))
+			return nil
+		})
+		deleted = err == nil
+		return err
+	})
+	if err != nil {
+		return false, xerror.Wrap(fmt.Errorf("failed to delete thread %s: %w", namespace, err))
+	}
+	return deleted, nil
+}
+
+// Prune 删除过期线程，并只保留每个线程最近的 MaxCheckpoints 个检查点
+func (c *RedisCheckpointer) Prune(ctx context.Context) error {
+	if c.options.TTL > 0 {
+		deadline := time.Now().Add(-c.options.TTL).UnixMilli()
+		expired, err := c.client.ZRangeByScore(ctx, c.getThreadsKey(), &redis.ZRangeBy{
+			Min: "-inf",
+			Max: strconv.FormatInt(deadline, 10),
+		}).Result()
+		if err != nil {
+			return xerror.Wrap(fmt.Errorf("failed to get expired threads: %w", err))
+		}
+
+		// 索引是读取时的快照，删除时在线程自己的事务中重新检查最后写入时间，
+		// 只移除删除后仍未被更新的索引项
+		for _, namespace := range expired {
+			deleted, err := c.deleteThread(ctx, namespace, time.UnixMilli(deadline))
+			if err != nil {
+				return err
+			}
+			if !deleted {
+				continue
+			}
+			if err := removeExpiredScript.Run(ctx, c.client, []string{c.getThreadsKey()}, namespace, deadline).Err(); err != nil {
+				return xerror.Wrap(fmt.Errorf("failed to remove thread %s from index: %w", namespace, err))
+			}
+		}
+	}
+
+	if c.options.MaxCheckpoints <= 0 {
+		return nil
+	}
+
+	namespaces, err := c.client.ZRange(ctx, c.getThreadsKey(), 0, -1).Result()
+	if err != nil {
+		return xerror.Wrap(fmt.Errorf("failed to list threads: %w", err))
+	}
+
+	for _, namespace := range namespaces {
+		if err := c.trim(ctx, namespace, c.options.MaxCheckpoints, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// trimThread 删除线程中 step 小于 before 的检查点
+func (c *RedisCheckpointer) trimThread(ctx context.Context, namespace string, before int) error {
+	return c.trim(ctx, namespace, 0, before)
+}
+
+// trim 只保留最近的 keep 个检查点，before 大于 0 时只删除 step 小于 before 的检查点，
+// 并累加已裁剪数量使现存检查点的 step 保持不变
+func (c *RedisCheckpointer) trim(ctx context.Context, namespace string, keep int, before int) error {
+	namespaceKey := c.getNamespaceKey(namespace)
+	trimmedKey := c.getTrimmedKey(namespace)
+	err := c.watchThread(ctx, namespace, func(tx *redis.Tx) error {
+		total, err := tx.LLen(ctx, namespaceKey).Result()
+		if err != nil {
+			return err
+		}
+
+		overflow := int(total) - keep
+		if before > 0 {
+			trimmed, err := tx.Get(ctx, trimmedKey).Int()
+			if err != nil && err != redis.Nil {
+				return err
+			}
+			overflow = min(overflow, before-trimmed-1)
+		}
+		if overflow <= 0 {
+			return nil
+		}
+
+		ids, err := tx.LRange(ctx, namespaceKey, 0, int64(overflow-1)).Result()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.LTrim(ctx, namespaceKey, int64(overflow), -1)
+			pipe.IncrBy(ctx, trimmedKey, int64(overflow))
+			for start := 0; start < len(ids); start += redisBatchSize {
+				batch := ids[start:min(start+redisBatchSize, len(ids))]
+				pipe.Del(ctx, c.getStateKeys(namespace, batch)...)
+				pipe.HDel(ctx, c.getMetaKey(namespace), batch...)
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return xerror.Wrap(fmt.Errorf("failed to trim thread %s: %w", namespace, err))
+	}
+	return nil
+}
+
+// watchThread 在 WATCH 线程 ID 列表的乐观事务中执行 fn，列表被并发修改时重试。
+// 线程的每次写入都会修改 ID 列表，事务中访问的 key 都由客户端显式给出，
+// 开启 HashTag 时和 ID 列表位于同一个 slot
+func (c *RedisCheckpointer) watchThread(ctx context.Context, namespace string, fn func(tx *redis.Tx) error) error {
+	for range maxWatchRetries {
+		err := c.client.Watch(ctx, fn, c.getNamespaceKey(namespace))
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return redis.TxFailedErr
+}
+
+// getOptions 返回检查点配置
+func (c *RedisCheckpointer) getOptions() *Options {
+	return c.options
+}
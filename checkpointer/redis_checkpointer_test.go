@@ -0,0 +1,267 @@
+package checkpointer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/state"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisCheckpointer(t *testing.T, opts ...Option) (*RedisCheckpointer, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	checkpointer := NewRedisCheckpointer(client, opts...)
+	t.Cleanup(func() {
+		_ = checkpointer.Close()
+	})
+
+	return checkpointer, server
+}
+
+func TestRedisCheckpointer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("key prefix and hash tag", func(t *testing.T) {
+		checkpointer, server := newTestRedisCheckpointer(t, WithKeyPrefix("staging"), WithHashTag())
+
+		id, err := checkpointer.Save(ctx, "thread", &state.State{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, key := range []string{
+			"staging:{thread}:states",
+			"staging:{thread}:meta",
+			"staging:{thread}:state:" + id,
+			"staging:threads",
+		} {
+			if !server.Exists(key) {
+				t.Fatalf("expected key %s to exist, got %v", key, server.Keys())
+			}
+		}
+	})
+
+	t.Run("list with filters", func(t *testing.T) {
+		checkpointer, _ := newTestRedisCheckpointer(t)
+
+		for i := 0; i < 250; i++ {
+			s := &state.State{Metadata: map[string]interface{}{"i": i}}
+			s.SetNode(fmt.Sprintf("node%d", i%5))
+			if _, err := checkpointer.Save(ctx, "thread", s); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		page, cursor, err := checkpointer.List(ctx, "thread", flowcontract.WithNode("node0"), flowcontract.WithLimit(30))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 30 || page[29].Step != 146 || cursor != "147" {
+			t.Fatalf("unexpected page %d %d %s", len(page), page[len(page)-1].Step, cursor)
+		}
+		if page[1].State.Metadata["i"].(float64) != 5 {
+			t.Fatalf("unexpected state %+v", page[1].State)
+		}
+
+		count := 0
+		for checkpoint, err := range checkpointer.Iter(ctx, "thread", flowcontract.WithReverse(), flowcontract.WithStepRange(0, 120)) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count == 0 && checkpoint.Step != 120 {
+				t.Fatalf("expected reverse order, got step %d", checkpoint.Step)
+			}
+			count++
+		}
+		if count != 120 {
+			t.Fatalf("expected 120 checkpoints, got %d", count)
+		}
+	})
+
+	t.Run("cluster client uses hash tags", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{server.Addr()}})
+		t.Cleanup(func() {
+			_ = client.Close()
+		})
+
+		checkpointer := NewRedisCheckpointer(client)
+		id, err := checkpointer.Save(ctx, "thread", &state.State{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !server.Exists("checkpointer:{thread}:state:"+id) || !server.Exists("checkpointer:{thread}:states") {
+			t.Fatalf("expected hash tagged keys, got %v", server.Keys())
+		}
+	})
+
+	t.Run("prune and delete thread", func(t *testing.T) {
+		checkpointer, server := newTestRedisCheckpointer(t, WithMaxCheckpoints(3))
+
+		ids := make([]string, 0)
+		for i := 0; i < 5; i++ {
+			id, err := checkpointer.Save(ctx, "thread", &state.State{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, id)
+		}
+
+		page, cursor, err := checkpointer.List(ctx, "thread", flowcontract.WithLimit(3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 3 || cursor != "4" {
+			t.Fatalf("unexpected page %d %q", len(page), cursor)
+		}
+
+		if err := checkpointer.Prune(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		// 裁剪后 step 和游标不变
+		page, cursor, err = checkpointer.List(ctx, "thread", flowcontract.WithCursor(cursor))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 2 || cursor != "" || page[0].ID != ids[3] || page[0].Step != 4 || page[1].Step != 5 {
+			t.Fatalf("unexpected page after prune %+v", page)
+		}
+		page, _, err = checkpointer.List(ctx, "thread", flowcontract.WithReverse())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 3 || page[2].ID != ids[2] || page[2].Step != 3 {
+			t.Fatalf("unexpected reversed page after prune %+v", page)
+		}
+
+		threads, err := checkpointer.ListThreads(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(threads) != 1 || threads[0].CheckpointCount != 3 {
+			t.Fatalf("unexpected threads %+v", threads)
+		}
+		// 3 个状态 + 列表 + 元信息 + 已裁剪数量 + 线程索引
+		if len(server.Keys()) != 7 {
+			t.Fatalf("unexpected keys after prune %v", server.Keys())
+		}
+
+		if err := checkpointer.DeleteThread(ctx, "thread"); err != nil {
+			t.Fatal(err)
+		}
+		if len(server.Keys()) != 0 {
+			t.Fatalf("unexpected keys after delete %v", server.Keys())
+		}
+	})
+
+	t.Run("prune rechecks expired threads", func(t *testing.T) {
+		checkpointer, server := newTestRedisCheckpointer(t, WithTTL(time.Hour))
+
+		if _, err := checkpointer.Save(ctx, "thread", &state.State{}); err != nil {
+			t.Fatal(err)
+		}
+		// 模拟读取索引之后线程又有写入：索引中的时间已经过期，但最新检查点是新的
+		if _, err := server.ZAdd("checkpointer:threads", float64(time.Now().Add(-2*time.Hour).UnixMilli()), "thread"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := checkpointer.Prune(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := checkpointer.GetLastest(ctx, "thread"); err != nil {
+			t.Fatalf("expected thread to survive prune, got %v", err)
+		}
+		if !server.Exists("checkpointer:threads") {
+			t.Fatal("expected thread to stay in the index")
+		}
+	})
+}
+
+func TestRedisCheckpointerLockLease(t *testing.T) {
+	ctx := context.Background()
+	checkpointer, server := newTestRedisCheckpointer(t, WithLockLease(30*time.Millisecond), WithHashTag())
+
+	lock, err := checkpointer.LockThread(ctx, "thread", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !server.Exists("checkpointer:{thread}:lock") {
+		t.Fatal("expected lock key to exist")
+	}
+
+	// 续期保持锁的租期
+	time.Sleep(50 * time.Millisecond)
+	if ttl := server.TTL("checkpointer:{thread}:lock"); ttl <= 0 || ttl > 30*time.Millisecond {
+		t.Fatalf("unexpected lock ttl %s", ttl)
+	}
+	select {
+	case <-lock.Lost():
+		t.Fatal("lock lost while renewing")
+	default:
+	}
+
+	// 租期过期后被其他执行获取，原持有者续期失败并感知锁丢失
+	server.FastForward(time.Second)
+	other, err := checkpointer.LockThread(ctx, "thread", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("expected lock to be lost")
+	}
+
+	// 原持有者释放时不能删除其他执行的锁
+	if err := lock.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkpointer.LockThread(ctx, "thread", false); !errors.Is(err, flowcontract.ErrThreadBusy) {
+		t.Fatalf("expected ErrThreadBusy, got %v", err)
+	}
+
+	if err := other.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if server.Exists("checkpointer:{thread}:lock") {
+		t.Fatal("expected lock key to be deleted")
+	}
+}
+
+func TestRedisCheckpointerLockLostBeforeExpiry(t *testing.T) {
+	ctx := context.Background()
+	lease := 300 * time.Millisecond
+	checkpointer, server := newTestRedisCheckpointer(t, WithLockLease(lease), WithHashTag())
+
+	lock, err := checkpointer.LockThread(ctx, "thread", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Unlock(ctx)
+
+	// Redis 不可用时续期失败，锁需要在租期结束之前标记为丢失
+	acquired := time.Now()
+	server.SetError("connection refused")
+	defer server.SetError("")
+
+	select {
+	case <-lock.Lost():
+		if elapsed := time.Since(acquired); elapsed >= lease {
+			t.Fatalf("lock marked lost after %s, lease is %s", elapsed, lease)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected lock to be lost")
+	}
+}
@@ -0,0 +1,102 @@
+// checkpoint 在 Redis 与 JSONL 归档之间导出和导入线程。
+//
+//	checkpoint export -redis redis://localhost:6379/0 -o archive.jsonl [thread ...]
+//	checkpoint import -redis redis://localhost:6379/0 -i archive.jsonl
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/futurxlab/golanggraph/checkpointer"
+	"github.com/futurxlab/golanggraph/checkpointer/archive"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage:\n")
+	fmt.Fprintf(os.Stderr, "  checkpoint export -redis <url> [-prefix checkpointer] [-hashtag] [-o archive.jsonl] [thread ...]\n")
+	fmt.Fprintf(os.Stderr, "  checkpoint import -redis <url> [-prefix checkpointer] [-hashtag] [-i archive.jsonl]\n")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	command := os.Args[1]
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	redisURL := flags.String("redis", "", "redis url, e.g. redis://localhost:6379/0")
+	prefix := flags.String("prefix", checkpointer.DefaultKeyPrefix, "redis key prefix")
+	hashTag := flags.Bool("hashtag", false, "wrap thread ids in redis cluster hash tags")
+	output := flags.String("o", "-", "archive to write, - for stdout")
+	input := flags.String("i", "-", "archive to read, - for stdin")
+	_ = flags.Parse(os.Args[2:])
+
+	if *redisURL == "" {
+		usage()
+	}
+
+	redisOptions, err := redis.ParseURL(*redisURL)
+	if err != nil {
+		fail(err)
+	}
+	client := redis.NewClient(redisOptions)
+	defer client.Close()
+
+	opts := []checkpointer.Option{checkpointer.WithKeyPrefix(*prefix)}
+	if *hashTag {
+		opts = append(opts, checkpointer.WithHashTag())
+	}
+	redisCheckpointer := checkpointer.NewRedisCheckpointer(client, opts...)
+	defer redisCheckpointer.Close()
+
+	ctx := context.Background()
+
+	switch command {
+	case "export":
+		var w io.Writer = os.Stdout
+		if *output != "-" {
+			file, err := os.Create(*output)
+			if err != nil {
+				fail(err)
+			}
+			defer file.Close()
+			w = file
+		}
+
+		stats, err := archive.Export(ctx, redisCheckpointer, w, flags.Args()...)
+		if err != nil {
+			fail(err)
+		}
+		fmt.Fprintf(os.Stderr, "exported %d checkpoints from %d threads\n", stats.Checkpoints, stats.Threads)
+	case "import":
+		var r io.Reader = os.Stdin
+		if *input != "-" {
+			file, err := os.Open(*input)
+			if err != nil {
+				fail(err)
+			}
+			defer file.Close()
+			r = file
+		}
+
+		stats, err := archive.Import(ctx, redisCheckpointer, r)
+		if err != nil {
+			fail(err)
+		}
+		fmt.Fprintf(os.Stderr, "imported %d checkpoints into %d threads, skipped %d existing\n", stats.Checkpoints, stats.Threads, stats.Skipped)
+	default:
+		usage()
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -2,13 +2,173 @@ package flowcontract
 
 import (
 	"context"
+	"errors"
+	"iter"
+	"time"
 
 	"github.com/futurxlab/golanggraph/state"
 )
 
+const (
+	DefaultListLimit = 100
+)
+
+var (
+	// ErrCheckpointNotFound 检查点或线程不存在，实现需要保证 errors.Is 可以识别
+	ErrCheckpointNotFound = errors.New("checkpoint not found")
+	// ErrInvalidCursor 分页游标无法解析，实现需要保证 errors.Is 可以识别
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrThreadBusy 线程正在被其他执行占用
+	ErrThreadBusy = errors.New("thread is busy")
+	// ErrThreadLockLost 线程锁已经丢失，执行不再写入检查点
+	ErrThreadLockLost = errors.New("thread lock lost")
+)
+
 type Checkpointer interface {
 	Save(ctx context.Context, namespace string, state *state.State) (string, error)
 	GetByID(ctx context.Context, namespace string, checkpointerID string) (*state.State, error)
 	GetLastest(ctx context.Context, namespace string) (*state.State, error)
 	GetAll(ctx context.Context, namespace string) ([]*state.State, error)
 }
+
+// CheckpointLister 是 Checkpointer 的可选扩展，支持按条件分页查询检查点
+type CheckpointLister interface {
+	// List 返回一页检查点以及下一页的游标，游标为空表示没有更多数据
+	List(ctx context.Context, namespace string, opts ...ListOption) ([]*Checkpoint, string, error)
+	// Iter 按页流式读取所有匹配的检查点，调用方停止迭代时不再读取后续页
+	Iter(ctx context.Context, namespace string, opts ...ListOption) iter.Seq2[*Checkpoint, error]
+}
+
+// Checkpoint 检查点及其元信息，Step 从 1 开始，表示该检查点在 namespace 中的顺序。
+// Step 在检查点被清理后保持不变，List 的游标同样基于 Step，不会因为清理指向其他检查点
+type Checkpoint struct {
+	ID        string
+	Namespace string
+	Step      int
+	Node      string
+	CreatedAt time.Time
+	State     *state.State
+}
+
+type ListOptions struct {
+	Node     string
+	Since    time.Time
+	Until    time.Time
+	FromStep int
+	ToStep   int
+	Limit    int
+	Cursor   string
+	Reverse  bool
+}
+
+type ListOption func(*ListOptions)
+
+// WithNode 只返回指定节点保存的检查点
+func WithNode(node string) ListOption {
+	return func(o *ListOptions) {
+		o.Node = node
+	}
+}
+
+// WithTimeRange 只返回创建时间在 [since, until) 内的检查点，零值表示不限制
+func WithTimeRange(since, until time.Time) ListOption {
+	return func(o *ListOptions) {
+		o.Since = since
+		o.Until = until
+	}
+}
+
+// WithStepRange 只返回 step 在 [from, to] 内的检查点，0 表示不限制
+func WithStepRange(from, to int) ListOption {
+	return func(o *ListOptions) {
+		o.FromStep = from
+		o.ToStep = to
+	}
+}
+
+func WithLimit(limit int) ListOption {
+	return func(o *ListOptions) {
+		o.Limit = limit
+	}
+}
+
+func WithCursor(cursor string) ListOption {
+	return func(o *ListOptions) {
+		o.Cursor = cursor
+	}
+}
+
+// WithReverse 从最新的检查点开始倒序返回
+func WithReverse() ListOption {
+	return func(o *ListOptions) {
+		o.Reverse = true
+	}
+}
+
+func NewListOptions(opts ...ListOption) *ListOptions {
+	options := &ListOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.Limit <= 0 {
+		options.Limit = DefaultListLimit
+	}
+	return options
+}
+
+// Match 判断检查点元信息是否满足过滤条件
+func (o *ListOptions) Match(checkpoint *Checkpoint) bool {
+	if o.Node != "" && checkpoint.Node != o.Node {
+		return false
+	}
+	if !o.Since.IsZero() && checkpoint.CreatedAt.Before(o.Since) {
+		return false
+	}
+	if !o.Until.IsZero() && !checkpoint.CreatedAt.Before(o.Until) {
+		return false
+	}
+	if o.FromStep > 0 && checkpoint.Step < o.FromStep {
+		return false
+	}
+	if o.ToStep > 0 && checkpoint.Step > o.ToStep {
+		return false
+	}
+	return true
+}
+
+// ThreadManager 是 Checkpointer 的可选扩展，支持线程级别的查询、删除和过期清理
+type ThreadManager interface {
+	// ListThreads 按最近更新时间倒序返回所有线程
+	ListThreads(ctx context.Context) ([]*Thread, error)
+	// DeleteThread 删除线程及其所有检查点
+	DeleteThread(ctx context.Context, namespace string) error
+	// Prune 按保留策略删除过期线程和多余的检查点
+	Prune(ctx context.Context) error
+}
+
+type Thread struct {
+	ID              string
+	CheckpointCount int
+	UpdatedAt       time.Time
+}
+
+// CheckpointImporter 是 Checkpointer 的可选扩展，按给定的 ID 和创建时间追加检查点，用于在不同存储之间迁移
+type CheckpointImporter interface {
+	// Import 在 namespace 末尾追加检查点，ID 已存在时跳过，返回是否写入
+	Import(ctx context.Context, namespace string, checkpoint *Checkpoint) (bool, error)
+}
+
+// ThreadLocker 是 Checkpointer 的可选扩展，保证同一线程同一时间只有一个执行在写入检查点
+type ThreadLocker interface {
+	// LockThread 获取线程锁，wait 为 false 时线程被占用立即返回 ErrThreadBusy，
+	// 为 true 时排队等待，直到获取成功或 ctx 结束
+	LockThread(ctx context.Context, namespace string, wait bool) (ThreadLock, error)
+}
+
+// ThreadLock 已获取的线程锁
+type ThreadLock interface {
+	// Lost 在锁因续期失败等原因被其他执行获取时关闭，持有者应当停止写入
+	Lost() <-chan struct{}
+	// Unlock 释放锁，可以重复调用
+	Unlock(ctx context.Context) error
+}
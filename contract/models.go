@@ -2,13 +2,79 @@ package flowcontract
 
 import (
 	"context"
+	"time"
 
 	"github.com/futurxlab/golanggraph/state"
 )
 
 type StreamFunc func(ctx context.Context, event *FlowStreamEvent) error
 
+// EventType 流式事件的类型
+type EventType string
+
+const (
+	// EventRunStart 一次 Exec/Resume 开始执行
+	EventRunStart EventType = "run_start"
+	// EventRunEnd 一次执行结束，失败时 Err 为执行的错误，成功时 FullState 为最终状态
+	EventRunEnd EventType = "run_end"
+	// EventNodeStart 节点开始执行
+	EventNodeStart EventType = "node_start"
+	// EventNodeEnd 节点执行成功，FullState 为节点的输出，Duration 为执行耗时
+	EventNodeEnd EventType = "node_end"
+	// EventLLMToken LLM 流式输出的文本，Chunk 为本次输出的内容
+	EventLLMToken EventType = "llm_token"
+	// EventToolCallStart 开始调用工具，ToolCall 为调用的工具和参数
+	EventToolCallStart EventType = "tool_call_start"
+	// EventToolCallResult 工具调用结束，ToolCall.Result 为调用结果，调用失败时 Err 不为空
+	EventToolCallResult EventType = "tool_call_result"
+	// EventCheckpointSaved 检查点已保存，CheckpointID 为检查点 ID
+	EventCheckpointSaved EventType = "checkpoint_saved"
+	// EventRoute 节点完成后的路由结果，NextNodes 为下一步执行的节点
+	EventRoute EventType = "route"
+	// EventError 节点执行失败或 panic，Err 为失败的原因；执行本身的错误通过 EventRunEnd 返回
+	EventError EventType = "error"
+	// EventCustom 节点自定义的事件，节点发送未设置 Type 的事件时使用
+	EventCustom EventType = "custom"
+)
+
+// StreamMode 流式输出的内容，可以同时选择多个
+type StreamMode string
+
+const (
+	// StreamModeValues 每个节点完成后输出完整状态（EventNodeEnd.FullState），以及工具调用和自定义事件
+	StreamModeValues StreamMode = "values"
+	// StreamModeUpdates 每个节点完成后只输出节点对状态的修改（EventNodeEnd.Update），以及工具调用和自定义事件
+	StreamModeUpdates StreamMode = "updates"
+	// StreamModeMessages 只输出 LLM 流式文本
+	StreamModeMessages StreamMode = "messages"
+	// StreamModeDebug 输出全部事件，包括节点开始、检查点保存和路由结果
+	StreamModeDebug StreamMode = "debug"
+)
+
+// ToolCallEvent 工具调用的信息
+type ToolCallEvent struct {
+	ID        string
+	Name      string
+	Arguments string
+	Result    string
+}
+
+// FlowStreamEvent 执行过程中的流式事件。Node、ThreadID 和 RunID 由 Flow 填充，
+// 节点发送事件时只需要设置 Type 和事件相关的字段
 type FlowStreamEvent struct {
+	Type     EventType
+	Node     string
+	ThreadID string
+	RunID    string
+
 	Chunk     string
 	FullState *state.State
+	// Update 节点对状态的修改：新增的历史消息和变化的 Metadata，被删除的 key 值为 nil；
+	// 节点缩短了历史消息（例如摘要）时 History 为节点输出的完整历史
+	Update       *state.State
+	NextNodes    []string
+	Duration     time.Duration
+	ToolCall     *ToolCallEvent
+	CheckpointID string
+	Err          error
 }
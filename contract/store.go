@@ -0,0 +1,91 @@
+package flowcontract
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	DefaultStoreLimit = 10
+)
+
+var (
+	// ErrItemNotFound 记忆不存在
+	ErrItemNotFound = errors.New("item not found")
+	// ErrSearchNotSupported 未配置 Embedder 的 Store 不支持语义搜索
+	ErrSearchNotSupported = errors.New("store search requires an embedder")
+)
+
+// Store 跨线程的长期记忆存储，与只在单个线程内有效的 Checkpointer 相互独立。
+// namespace 是分层的，例如 []string{"user-123", "preferences"}，List 和 Search 按前缀匹配。
+type Store interface {
+	Put(ctx context.Context, namespace []string, key string, value map[string]interface{}) error
+	Get(ctx context.Context, namespace []string, key string) (*Item, error)
+	Delete(ctx context.Context, namespace []string, key string) error
+	// List 按更新时间倒序返回 namespace 前缀下的记忆
+	List(ctx context.Context, namespacePrefix []string, opts ...StoreOption) ([]*Item, error)
+	// Search 按与 query 的语义相似度倒序返回 namespace 前缀下的记忆
+	Search(ctx context.Context, namespacePrefix []string, query string, opts ...StoreOption) ([]*Item, error)
+}
+
+type Item struct {
+	Namespace []string
+	Key       string
+	Value     map[string]interface{}
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// Score 语义搜索的相似度，List 返回的记忆为 0
+	Score float64
+}
+
+type StoreOptions struct {
+	Filter map[string]interface{}
+	Limit  int
+	Offset int
+}
+
+type StoreOption func(*StoreOptions)
+
+// WithFilter 只返回 Value 中对应字段相等的记忆
+func WithFilter(filter map[string]interface{}) StoreOption {
+	return func(o *StoreOptions) {
+		o.Filter = filter
+	}
+}
+
+func WithStoreLimit(limit int) StoreOption {
+	return func(o *StoreOptions) {
+		o.Limit = limit
+	}
+}
+
+func WithStoreOffset(offset int) StoreOption {
+	return func(o *StoreOptions) {
+		o.Offset = offset
+	}
+}
+
+func NewStoreOptions(opts ...StoreOption) *StoreOptions {
+	options := &StoreOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.Limit <= 0 {
+		options.Limit = DefaultStoreLimit
+	}
+	return options
+}
+
+type storeContextKey struct{}
+
+// WithStore 将 Store 放入 context，Flow 执行时会自动注入，节点通过 StoreFromContext 获取
+func WithStore(ctx context.Context, store Store) context.Context {
+	return context.WithValue(ctx, storeContextKey{}, store)
+}
+
+// StoreFromContext 获取 context 中的 Store
+func StoreFromContext(ctx context.Context) (Store, bool) {
+	store, ok := ctx.Value(storeContextKey{}).(Store)
+	return store, ok
+}
@@ -87,8 +87,13 @@ func main() {
 		},
 	}, func(ctx context.Context, event *flowcontract.FlowStreamEvent) error {
 
-		if event.Chunk != "" {
+		switch event.Type {
+		case flowcontract.EventLLMToken:
 			fmt.Print(event.Chunk)
+		case flowcontract.EventToolCallStart:
+			fmt.Printf("\n[calling %s %s]\n", event.ToolCall.Name, event.ToolCall.Arguments)
+		case flowcontract.EventToolCallResult:
+			fmt.Printf("[%s finished]\n", event.ToolCall.Name)
 		}
 
 		return nil
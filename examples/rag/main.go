@@ -161,8 +161,8 @@ func main() {
 			},
 		},
 	}, func(ctx context.Context, event *flowcontract.FlowStreamEvent) error {
-		// Print streaming output
-		if event.Chunk != "" {
+		// Print LLM tokens and the retrieved knowledge emitted by RAGNode
+		if event.Type == flowcontract.EventLLMToken || event.Type == flowcontract.EventCustom {
 			fmt.Print(event.Chunk)
 		}
 		return nil
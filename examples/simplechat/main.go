@@ -62,7 +62,7 @@ func main() {
 	}, func(ctx context.Context, event *flowcontract.FlowStreamEvent) error {
 
 		// print in stream
-		if event.Chunk != "" {
+		if event.Type == flowcontract.EventLLMToken {
 			fmt.Print(event.Chunk)
 		}
 
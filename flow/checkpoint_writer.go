@@ -0,0 +1,169 @@
+package flow
+
+import (
+	"context"
+	"sync"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
+)
+
+// Durability 检查点的持久化模式
+type Durability int
+
+const (
+	// DurabilitySync 每个节点执行完成后同步保存检查点
+	DurabilitySync Durability = iota
+	// DurabilityAsync 检查点写入缓冲区由后台按顺序保存，保存错误在执行结束时返回
+	DurabilityAsync
+	// DurabilityExit 只在执行结束时保存一个检查点：成功时为执行的最终状态，失败时为待恢复的检查点
+	DurabilityExit
+)
+
+var (
+	// AsyncCheckpointBufferSize 异步模式下缓冲区的大小，缓冲区满时保存会阻塞
+	AsyncCheckpointBufferSize = 64
+)
+
+type checkpointWriter interface {
+	Save(ctx context.Context, namespace string, state *state.State) error
+	// Close 等待所有检查点写入完成，并返回写入过程中的第一个错误。
+	// final 为执行成功时合并后的最终状态，执行失败时为 nil
+	Close(ctx context.Context, final *state.State) error
+}
+
+// savedFunc 检查点实际写入成功后调用，id 为 Checkpointer 返回的检查点 ID，返回的错误视为写入失败
+type savedFunc func(ctx context.Context, state *state.State, id string) error
+
+func newCheckpointWriter(ctx context.Context, durability Durability, checkpointer flowcontract.Checkpointer, onSaved savedFunc) checkpointWriter {
+	if onSaved == nil {
+		onSaved = func(ctx context.Context, state *state.State, id string) error { return nil }
+	}
+	checkpointer = guardCheckpointer(ctx, checkpointer)
+
+	switch durability {
+	case DurabilityAsync:
+		return newAsyncCheckpointWriter(ctx, checkpointer, onSaved)
+	case DurabilityExit:
+		return &exitCheckpointWriter{checkpointer: checkpointer, onSaved: onSaved}
+	default:
+		return &syncCheckpointWriter{checkpointer: checkpointer, onSaved: onSaved}
+	}
+}
+
+type syncCheckpointWriter struct {
+	checkpointer flowcontract.Checkpointer
+	onSaved      savedFunc
+}
+
+func (w *syncCheckpointWriter) Save(ctx context.Context, namespace string, state *state.State) error {
+	id, err := w.checkpointer.Save(ctx, namespace, state)
+	if err != nil {
+		return xerror.Wrap(err)
+	}
+	return w.onSaved(ctx, state, id)
+}
+
+func (w *syncCheckpointWriter) Close(ctx context.Context, final *state.State) error {
+	return nil
+}
+
+type checkpointItem struct {
+	namespace string
+	state     *state.State
+}
+
+type asyncCheckpointWriter struct {
+	checkpointer flowcontract.Checkpointer
+	onSaved      savedFunc
+	items        chan checkpointItem
+	done         chan struct{}
+	closeOnce    sync.Once
+	err          error
+}
+
+func newAsyncCheckpointWriter(ctx context.Context, checkpointer flowcontract.Checkpointer, onSaved savedFunc) *asyncCheckpointWriter {
+	w := &asyncCheckpointWriter{
+		checkpointer: checkpointer,
+		onSaved:      onSaved,
+		items:        make(chan checkpointItem, AsyncCheckpointBufferSize),
+		done:         make(chan struct{}),
+	}
+
+	// 执行被取消后仍需要把缓冲区中的检查点写完
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		defer close(w.done)
+		for item := range w.items {
+			if w.err != nil {
+				continue
+			}
+			id, err := w.checkpointer.Save(ctx, item.namespace, item.state)
+			if err != nil {
+				w.err = xerror.Wrap(err)
+				continue
+			}
+			w.err = w.onSaved(ctx, item.state, id)
+		}
+	}()
+
+	return w
+}
+
+func (w *asyncCheckpointWriter) Save(ctx context.Context, namespace string, state *state.State) error {
+	// 复制状态，避免后续节点修改 Metadata 时与后台写入竞争
+	item := checkpointItem{namespace: namespace, state: state.Clone()}
+	select {
+	case w.items <- item:
+		return nil
+	case <-ctx.Done():
+		return xerror.Wrap(ctx.Err())
+	}
+}
+
+func (w *asyncCheckpointWriter) Close(ctx context.Context, final *state.State) error {
+	w.closeOnce.Do(func() {
+		close(w.items)
+	})
+	<-w.done
+	return w.err
+}
+
+type exitCheckpointWriter struct {
+	sync.Mutex
+	checkpointer flowcontract.Checkpointer
+	onSaved      savedFunc
+	last         *checkpointItem
+}
+
+func (w *exitCheckpointWriter) Save(ctx context.Context, namespace string, state *state.State) error {
+	w.Lock()
+	defer w.Unlock()
+	w.last = &checkpointItem{namespace: namespace, state: state.Clone()}
+	return nil
+}
+
+// Close 保存执行的最终状态。并行分支的检查点按完成顺序缓冲，最后一次缓冲的检查点不一定是合并后的状态，
+// 因此执行成功时保存 final；执行失败时最后一次缓冲的是执行结束后保存的待恢复检查点
+func (w *exitCheckpointWriter) Close(ctx context.Context, final *state.State) error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.last == nil {
+		return nil
+	}
+
+	last := w.last
+	w.last = nil
+	if final != nil {
+		last.state = final.Clone()
+	}
+	ctx = context.WithoutCancel(ctx)
+	id, err := w.checkpointer.Save(ctx, last.namespace, last.state)
+	if err != nil {
+		return xerror.Wrap(err)
+	}
+	return w.onSaved(ctx, last.state, id)
+}
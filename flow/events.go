@@ -0,0 +1,107 @@
+package flow
+
+import (
+	"context"
+	"time"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/logger"
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
+)
+
+// eventEmitter 为一次执行发送流式事件，填充线程 ID 和执行 ID，并按输出模式过滤
+type eventEmitter struct {
+	streamFunc flowcontract.StreamFunc
+	logger     logger.ILogger
+	threadID   string
+	runID      string
+	modes      map[flowcontract.StreamMode]bool
+}
+
+// emit 发送 Flow 自身产生的事件，streamFunc 返回错误时执行以该错误失败
+func (e *eventEmitter) emit(ctx context.Context, event *flowcontract.FlowStreamEvent) error {
+	if err := e.send(ctx, event); err != nil {
+		e.logger.Errorf(ctx, "streaming %s event failed node: %s, error: %s", event.Type, event.Node, err)
+		return xerror.Wrap(err)
+	}
+	return nil
+}
+
+func (e *eventEmitter) send(ctx context.Context, event *flowcontract.FlowStreamEvent) error {
+	event.ThreadID = e.threadID
+	event.RunID = e.runID
+	if event = filterEvent(e.modes, event); event == nil {
+		return nil
+	}
+	return e.streamFunc(ctx, event)
+}
+
+// forNode 返回传给节点的 StreamFunc，节点发送的事件自动带上节点名，未设置类型时视为自定义事件
+func (e *eventEmitter) forNode(node string) flowcontract.StreamFunc {
+	return func(ctx context.Context, event *flowcontract.FlowStreamEvent) error {
+		if event.Node == "" {
+			event.Node = node
+		}
+		if event.Type == "" {
+			event.Type = flowcontract.EventCustom
+		}
+		return e.send(ctx, event)
+	}
+}
+
+// runNode 执行节点并发送节点开始、结束或失败事件
+func (e *eventEmitter) runNode(ctx context.Context, name string, node flowcontract.Node, currentState *state.State) error {
+	if err := e.emit(ctx, &flowcontract.FlowStreamEvent{Type: flowcontract.EventNodeStart, Node: name}); err != nil {
+		return err
+	}
+
+	// 只有需要输出节点修改时才复制输入状态
+	var base *state.State
+	if e.modes[flowcontract.StreamModeUpdates] || e.modes[flowcontract.StreamModeDebug] {
+		base = currentState.Clone()
+	}
+
+	start := time.Now()
+	if err := node.Run(ctx, currentState, e.forNode(name)); err != nil {
+		// 节点已经失败，错误事件发送失败时只记录日志
+		_ = e.emit(ctx, &flowcontract.FlowStreamEvent{
+			Type:     flowcontract.EventError,
+			Node:     name,
+			Duration: time.Since(start),
+			Err:      err,
+		})
+		return err
+	}
+	currentState.SetNode(name)
+
+	event := &flowcontract.FlowStreamEvent{
+		Type:      flowcontract.EventNodeEnd,
+		Node:      name,
+		Duration:  time.Since(start),
+		FullState: currentState,
+	}
+	if base != nil {
+		event.Update = stateDelta(base, currentState)
+	}
+	return e.emit(ctx, event)
+}
+
+// route 发送节点完成后的路由结果
+func (e *eventEmitter) route(ctx context.Context, node string, nextNodes []string) error {
+	return e.emit(ctx, &flowcontract.FlowStreamEvent{
+		Type:      flowcontract.EventRoute,
+		Node:      node,
+		NextNodes: nextNodes,
+	})
+}
+
+// checkpointSaved 检查点写入成功后发送事件，异步模式下在后台写入时调用
+func (e *eventEmitter) checkpointSaved(ctx context.Context, saved *state.State, checkpointID string) error {
+	return e.emit(ctx, &flowcontract.FlowStreamEvent{
+		Type:         flowcontract.EventCheckpointSaved,
+		Node:         saved.GetNode(),
+		CheckpointID: checkpointID,
+		FullState:    saved,
+	})
+}
@@ -0,0 +1,411 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/state"
+	libutils "github.com/futurxlab/golanggraph/utils"
+	"github.com/futurxlab/golanggraph/xerror"
+)
+
+// nodeRun 节点在一次执行中的运行状态
+type nodeRun struct {
+	*nodeEntry
+	executing  bool
+	completion []state.State
+}
+
+// executor 保存一次 Exec/Resume 的全部运行状态，每次执行单独创建，
+// 同一个 Flow 上的并发执行之间不共享任何锁
+type executor struct {
+	flow      *Flow
+	events    *eventEmitter
+	writer    checkpointWriter
+	tracker   *pendingTracker
+	scheduler *scheduler
+
+	// mu 保护 nodes 的运行状态、joins 和 fullState，finished 表示已经到达结束节点
+	mu        sync.Mutex
+	nodes     map[string]*nodeRun
+	fullState state.State
+	finished  bool
+
+	// joins 因依赖未全部完成而跳过的汇合节点访问，调度结束时用于检查依赖永远不会完成的汇合节点
+	joins map[string]workItem
+
+	errOnce  sync.Once
+	firstErr error
+}
+
+// newExecutor 创建执行器，writes 为上次执行中已经完成、但尚未被依赖节点消费的输出
+func newExecutor(ctx context.Context, f *Flow, writes []state.PendingWrite, events *eventEmitter) *executor {
+	e := &executor{
+		flow:      f,
+		events:    events,
+		writer:    newCheckpointWriter(ctx, f.durability, f.checkpointer, events.checkpointSaved),
+		tracker:   newPendingTracker(),
+		scheduler: newScheduler(),
+		nodes:     make(map[string]*nodeRun, len(f.nodes)),
+		joins:     make(map[string]workItem),
+	}
+
+	for name, entry := range f.nodes {
+		e.nodes[name] = &nodeRun{nodeEntry: entry}
+	}
+	for _, write := range writes {
+		if run, ok := e.nodes[write.Node]; ok {
+			run.completion = append(run.completion, *write.State.Clone())
+		}
+	}
+
+	return e
+}
+
+// run 从给定的节点开始执行，直到没有就绪、正在执行的节点
+func (e *executor) run(ctx context.Context, tasks []workItem) (state.State, error) {
+	// 添加起始节点到队列
+	for _, task := range tasks {
+		e.enqueue(task)
+	}
+
+	// 启动工作线程，调度结束后全部退出
+	var workers sync.WaitGroup
+	for i := 0; i < max(FlowWorkerCount, 1); i++ {
+		workers.Add(1)
+		libutils.SafeGo(ctx, e.flow.logger, func() {
+			defer workers.Done()
+			e.work(ctx)
+		})
+	}
+	workers.Wait()
+
+	// 没有失败但仍有节点在等待依赖，说明依赖节点永远不会完成（例如条件边没有选择的分支）
+	if e.firstErr == nil {
+		if waiting := e.waiting(); len(waiting) > 0 {
+			e.mu.Lock()
+			missing := e.missingDependencies(waiting[0].node)
+			e.mu.Unlock()
+			e.fail(waiting[0].id, xerror.New(fmt.Sprintf("dependencies of node %s never completed: %v", waiting[0].node, missing)))
+		}
+	}
+
+	// 执行失败时保存未完成的节点和已完成节点的输出，Resume 时只重新执行失败的分支
+	if e.firstErr != nil {
+		// 执行可能因为 ctx 结束而失败，此时仍需要保存
+		if err := e.savePending(context.WithoutCancel(ctx)); err != nil {
+			e.flow.logger.Errorf(ctx, "failed to save pending writes: %s", err)
+		}
+	}
+
+	// 等待缓冲的检查点写入完成，写入失败时同样视为执行失败
+	var final *state.State
+	if e.firstErr == nil && e.finished {
+		final = &e.fullState
+	}
+	if err := e.writer.Close(ctx, final); err != nil {
+		e.fail(-1, err)
+	}
+
+	if e.firstErr != nil {
+		return state.State{}, xerror.Wrap(e.firstErr)
+	}
+
+	e.flow.logger.Infof(ctx, "flow finished")
+
+	return e.fullState, nil
+}
+
+// NodePanicError 节点执行过程中发生 panic，执行以该错误失败
+type NodePanicError struct {
+	Node  string
+	Value interface{}
+	Stack []byte
+}
+
+func (e *NodePanicError) Error() string {
+	return fmt.Sprintf("node %s panicked: %v\n%s", e.Node, e.Value, e.Stack)
+}
+
+// work 工作线程，出错或 ctx 结束后继续出队，剩余的节点记录为待恢复，直到调度结束
+func (e *executor) work(ctx context.Context) {
+	for {
+		work, ok := e.scheduler.pop()
+		if !ok {
+			return
+		}
+
+		if err := e.safeProcessNode(ctx, work); err != nil {
+			e.fail(work.id, err)
+		}
+		e.scheduler.done()
+	}
+}
+
+// safeProcessNode 把节点、条件函数和 streamFunc 中的 panic 转换为错误，工作线程继续处理后续节点
+func (e *executor) safeProcessNode(ctx context.Context, work workItem) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e.flow.logger.Errorf(ctx, "node %s panicked %+v", work.node, r)
+			err = xerror.Wrap(&NodePanicError{Node: work.node, Value: r, Stack: debug.Stack()})
+			_ = e.events.emit(ctx, &flowcontract.FlowStreamEvent{Type: flowcontract.EventError, Node: work.node, Err: err})
+		}
+	}()
+
+	return e.processNode(ctx, work)
+}
+
+func (e *executor) enqueue(work workItem) {
+	e.scheduler.push(e.tracker.add(work))
+}
+
+// fail 记录第一个错误，id 为出错的节点编号
+func (e *executor) fail(id int, err error) {
+	e.errOnce.Do(func() {
+		e.firstErr = err
+	})
+	e.tracker.fail(id)
+}
+
+// processNode 处理单个节点，替代原来的递归execNode方法
+func (e *executor) processNode(ctx context.Context, work workItem) error {
+	// 执行已经失败，节点保留为待恢复
+	if e.tracker.isFailed() {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return xerror.Wrap(err)
+	}
+
+	node, fullState := work.node, work.state
+	run, ok := e.nodes[node]
+	if !ok {
+		return xerror.New(fmt.Sprintf("node %s not found", node))
+	}
+
+	version := e.scheduler.version()
+
+	e.mu.Lock()
+	if run.executing {
+		// 节点仍在执行（例如循环图再次访问），本次访问暂存，当前执行结束后重新调度
+		e.mu.Unlock()
+		e.flow.logger.Infof(ctx, "node already executing %s, visit deferred", node)
+		e.scheduler.park(work, version)
+		return nil
+	}
+
+	// 如果有依赖节点，所有依赖节点都有可用的输出后一次性取出，
+	// 未就绪时不会消费任何输出，保证执行失败后这些输出仍可作为待恢复的结果保存
+	var states []state.State
+	if len(run.dependencies) > 0 {
+		var ready bool
+		if states, ready = e.takeCompletions(run.dependencies); !ready {
+			if run.joinByEdges {
+				// 依赖节点都有指向当前节点的边，由最后一个完成的依赖节点触发执行
+				e.joins[node] = work
+				e.mu.Unlock()
+				e.tracker.done(work.id)
+			} else {
+				e.mu.Unlock()
+				e.flow.logger.Infof(ctx, "waiting for dependencies %s, %+v", node, run.dependencies)
+				e.scheduler.park(work, version)
+			}
+			return nil
+		}
+	}
+
+	e.flow.logger.Infof(ctx, "executing node %s", node)
+	run.executing = true
+	e.mu.Unlock()
+
+	defer e.finishNode(run)
+
+	succeeded := false
+
+	if len(states) > 0 {
+		// 执行失败时归还依赖节点的输出，作为待恢复的结果保存
+		defer func() {
+			if !succeeded {
+				e.restoreCompletions(run.dependencies, states)
+			}
+		}()
+
+		for _, state := range states {
+			fullState.Merge(&state)
+		}
+	}
+
+	if node == EndNode {
+		e.flow.logger.Infof(ctx, "reached end node %s", node)
+		e.mu.Lock()
+		e.fullState = fullState
+		e.finished = true
+		e.mu.Unlock()
+		succeeded = true
+		e.tracker.done(work.id)
+		return nil
+	}
+
+	if node != StartNode {
+		// 执行节点
+		if err := e.events.runNode(ctx, node, run.node, &fullState); err != nil {
+			return xerror.Wrap(err)
+		}
+	}
+
+	nextNodes := make([]string, 0)
+
+	// 处理所有边缘，计算下一个节点
+	for _, edge := range e.flow.graph[node] {
+		nextNode := edge.To
+
+		if len(edge.ConditionalTo) > 0 {
+			condition, err := edge.ConditionFunc(ctx, fullState)
+			if err != nil {
+				return xerror.Wrap(err)
+			}
+
+			if condition != "" {
+				nextNode = condition
+			}
+		}
+
+		if nextNode == "" {
+			return xerror.New(fmt.Sprintf("no next node found for edge %s", edge.To))
+		}
+
+		nextNodes = append(nextNodes, nextNode)
+	}
+	if err := e.events.route(ctx, node, nextNodes); err != nil {
+		return err
+	}
+
+	// 保存检查点，保存成功后节点的输出才对依赖节点可见
+	namespace := fullState.GetThreadID()
+	fullState.SetNextNodes(nextNodes)
+	if err := e.writer.Save(ctx, namespace, &fullState); err != nil {
+		return xerror.Wrap(err)
+	}
+
+	if node != StartNode {
+		e.mu.Lock()
+		run.completion = append(run.completion, fullState)
+		e.mu.Unlock()
+	}
+
+	succeeded = true
+	e.tracker.done(work.id)
+
+	// 添加下一个节点到就绪队列
+	for _, nextNode := range nextNodes {
+		e.enqueue(workItem{node: nextNode, state: *fullState.Clone()})
+	}
+
+	return nil
+}
+
+// finishNode 结束节点的本次执行，唤醒暂存的节点重新检查依赖和执行状态
+func (e *executor) finishNode(run *nodeRun) {
+	e.mu.Lock()
+	run.executing = false
+	e.mu.Unlock()
+
+	e.scheduler.wake()
+}
+
+// waiting 返回调度结束时仍在等待依赖的节点：暂存的节点，以及部分依赖节点的输出没有被消费的汇合节点。
+// 汇合节点执行后其他依赖节点触发的多余访问不会留下未消费的输出，不视为等待
+func (e *executor) waiting() []workItem {
+	waiting := e.scheduler.stalled()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	names := make([]string, 0, len(e.joins))
+	for name := range e.joins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if len(e.missingDependencies(name)) < len(e.nodes[name].dependencies) {
+			waiting = append(waiting, e.joins[name])
+		}
+	}
+	return waiting
+}
+
+// missingDependencies 返回节点没有可用输出的依赖节点，调用方需要持有 e.mu
+func (e *executor) missingDependencies(node string) []string {
+	missing := make([]string, 0)
+	for _, dependency := range e.nodes[node].dependencies {
+		if run, ok := e.nodes[dependency]; !ok || len(run.completion) == 0 {
+			missing = append(missing, dependency)
+		}
+	}
+	return missing
+}
+
+// takeCompletions 所有依赖节点都有可用的输出时一次性取出，调用方需要持有 e.mu
+func (e *executor) takeCompletions(dependencies []string) ([]state.State, bool) {
+	for _, dependency := range dependencies {
+		run, ok := e.nodes[dependency]
+		if !ok || len(run.completion) == 0 {
+			return nil, false
+		}
+	}
+
+	states := make([]state.State, 0, len(dependencies))
+	for _, dependency := range dependencies {
+		run := e.nodes[dependency]
+		states = append(states, run.completion[0])
+		run.completion = run.completion[1:]
+	}
+	return states, true
+}
+
+func (e *executor) restoreCompletions(dependencies []string, states []state.State) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i, dependency := range dependencies {
+		run := e.nodes[dependency]
+		run.completion = append([]state.State{states[i]}, run.completion...)
+	}
+}
+
+// savePending 以失败节点的输入状态为基础保存一个检查点，记录待恢复的节点和可复用的节点输出
+func (e *executor) savePending(ctx context.Context) error {
+	tasks := e.tracker.pendingTasks()
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	// 只有被其他节点依赖的节点输出需要保留
+	writes := make([]state.PendingWrite, 0)
+	dependencies := make(map[string]bool)
+	for _, run := range e.nodes {
+		for _, dependency := range run.dependencies {
+			dependencies[dependency] = true
+		}
+	}
+	for name, run := range e.nodes {
+		if !dependencies[name] {
+			continue
+		}
+		for _, completion := range run.completion {
+			writes = append(writes, state.PendingWrite{Node: name, State: completion.Clone()})
+		}
+	}
+
+	pending := tasks[0].State.Clone()
+	pending.SetNode(tasks[0].Node)
+	pending.SetNextNodes(nil)
+	pending.SetPendingTasks(tasks)
+	pending.SetPendingWrites(writes)
+
+	return e.writer.Save(ctx, pending.GetThreadID(), pending)
+}
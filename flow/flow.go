@@ -2,16 +2,13 @@ package flow
 
 import (
 	"context"
-	"fmt"
-	"sync"
-	"time"
+	"errors"
 
 	flowcontract "github.com/futurxlab/golanggraph/contract"
 	"github.com/futurxlab/golanggraph/edge"
 	"github.com/futurxlab/golanggraph/state"
 
 	"github.com/futurxlab/golanggraph/logger"
-	libutils "github.com/futurxlab/golanggraph/utils"
 	"github.com/futurxlab/golanggraph/xerror"
 
 	"github.com/google/uuid"
@@ -27,251 +24,212 @@ var (
 )
 
 type workItem struct {
+	id    int
 	node  string
 	state state.State
 }
 
+// nodeEntry 编译后的节点定义，执行期间只读，运行状态保存在 nodeRun 中
 type nodeEntry struct {
-	executing    bool
 	node         flowcontract.Node
 	dependencies []string
-	completion   []state.State
+	// joinByEdges 所有依赖节点都有指向该节点的边
+	joinByEdges bool
 }
 
 type Flow struct {
-	sync.Mutex
 	name         string
 	logger       logger.ILogger
 	checkpointer flowcontract.Checkpointer
-	graph        map[string][]edge.Edge
-	nodes        map[string]*nodeEntry
+	durability   Durability
+	store        flowcontract.Store
+	// threadLocking 同一线程并发执行时的处理方式
+	threadLocking ThreadLocking
+	mode          ExecutionMode
+	graph         map[string][]edge.Edge
+	nodes         map[string]*nodeEntry
 }
 
 func (f *Flow) Name() string {
 	return f.name
 }
 
-func (f *Flow) Exec(ctx context.Context, initState state.State, streamFunc flowcontract.StreamFunc) (state.State, error) {
+// Checkpointer 返回执行时保存检查点使用的 Checkpointer
+func (f *Flow) Checkpointer() flowcontract.Checkpointer {
+	return f.checkpointer
+}
+
+// Exec 从起始节点开始执行，opts 可以选择流式输出的内容
+func (f *Flow) Exec(ctx context.Context, initState state.State, streamFunc flowcontract.StreamFunc, opts ...ExecOption) (state.State, error) {
 	if initState.GetThreadID() == "" {
 		initState.SetThreadID(uuid.New().String())
 	}
 
-	if streamFunc == nil {
-		streamFunc = func(ctx context.Context, event *flowcontract.FlowStreamEvent) error {
-			f.logger.Infof(ctx, "flow processing event streamFunc empty %+v", event)
-			return nil
-		}
-	}
+	return f.run(ctx, []workItem{{node: StartNode, state: initState}}, nil, streamFunc, newExecOptions(opts...))
+}
 
-	queue := make(chan workItem, FlowWorkerCount*10)
-	var wg sync.WaitGroup
+// Resume 从 lastState（通常是线程最新的检查点）继续执行。
+// 上次执行失败时只重新执行失败和尚未执行的节点，已经成功的并行分支的输出直接复用；
+// 否则从检查点记录的下一个节点继续执行，没有下一个节点时直接返回 lastState。
+func (f *Flow) Resume(ctx context.Context, lastState state.State, streamFunc flowcontract.StreamFunc, opts ...ExecOption) (state.State, error) {
+	threadID := lastState.GetThreadID()
+	if threadID == "" {
+		return state.State{}, xerror.New("thread id is required to resume a flow")
+	}
 
-	var fullState state.State
+	tasks := resumeTasks(lastState, threadID)
+	if len(tasks) == 0 {
+		f.logger.Infof(ctx, "nothing to resume for thread %s", threadID)
+		return lastState, nil
+	}
 
-	// 错误处理
-	var firstErr error
-	var errOnce sync.Once
+	return f.run(ctx, tasks, lastState.GetPendingWrites(), streamFunc, newExecOptions(opts...))
+}
 
-	// copy nodes
-	copiedNodes := make(map[string]*nodeEntry)
-	for k, v := range f.nodes {
-		copiedNodes[k] = &nodeEntry{
-			node:         v.node,
-			dependencies: v.dependencies,
-			completion:   make([]state.State, 0),
-		}
+// ResumeThread 与 Resume 相同，但在获取线程锁之后才读取线程最新的检查点，
+// 排队等待的恢复不会基于已经过时的检查点重复执行。线程不存在时返回 ErrCheckpointNotFound
+func (f *Flow) ResumeThread(ctx context.Context, threadID string, streamFunc flowcontract.StreamFunc, opts ...ExecOption) (state.State, error) {
+	if threadID == "" {
+		return state.State{}, xerror.New("thread id is required to resume a flow")
 	}
 
-	// 启动工作处理函数
-	worker := func() {
-		for {
-			select {
-			case <-ctx.Done():
-				f.logger.Infof(ctx, "context canceled, queue closed")
-				return
-			case work, ok := <-queue:
-				if !ok {
-					f.logger.Infof(ctx, "manually queue closed")
-					return
-				}
-
-				if work.node == EndNode {
-					fullState = work.state
-				}
-
-				if err := f.processNode(ctx, work.node, copiedNodes, work.state, queue, &wg, streamFunc); err != nil {
-					errOnce.Do(func() {
-						firstErr = err
-						close(queue)
-					})
-				}
-			}
-		}
+	ctx, release, err := f.lockThread(ctx, threadID)
+	if err != nil {
+		return state.State{}, err
 	}
+	defer release()
 
-	// 启动工作线程
-	for i := 0; i < FlowWorkerCount; i++ {
-		libutils.SafeGo(ctx, f.logger, worker)
+	lastState, err := f.checkpointer.GetLastest(ctx, threadID)
+	if err != nil {
+		return state.State{}, xerror.Wrap(err)
 	}
 
-	// 添加起始节点到队列
-	wg.Add(1)
-	queue <- workItem{node: StartNode, state: initState}
-
-	// 等待所有工作完成或出错
-	wg.Wait()
-
-	if firstErr != nil {
-		return state.State{}, xerror.Wrap(firstErr)
+	tasks := resumeTasks(*lastState, threadID)
+	if len(tasks) == 0 {
+		f.logger.Infof(ctx, "nothing to resume for thread %s", threadID)
+		return *lastState, nil
 	}
 
-	f.logger.Infof(ctx, "flow finished")
-
-	close(queue)
-
-	return fullState, nil
-}
-
-func (f *Flow) Resume(ctx context.Context, lastState state.State, streamFunc flowcontract.StreamFunc) error {
-	panic("not implemented")
+	return f.execute(ctx, tasks, lastState.GetPendingWrites(), streamFunc, newExecOptions(opts...))
 }
 
-// processNode 处理单个节点，替代原来的递归execNode方法
-func (f *Flow) processNode(ctx context.Context, node string, copiedNodes map[string]*nodeEntry, fullState state.State, queue chan<- workItem, wg *sync.WaitGroup, streamFunc flowcontract.StreamFunc) error {
-	defer wg.Done()
-	nodeEntry, ok := copiedNodes[node]
-	if !ok {
-		return xerror.New(fmt.Sprintf("node %s not found", node))
+// resumeTasks 根据检查点生成恢复执行的节点：上次执行失败时为待恢复节点，否则为检查点记录的下一个节点
+func resumeTasks(lastState state.State, threadID string) []workItem {
+	tasks := make([]workItem, 0)
+	if pending := lastState.GetPendingTasks(); len(pending) > 0 {
+		for _, task := range pending {
+			taskState := task.State.Clone()
+			taskState.SetThreadID(threadID)
+			tasks = append(tasks, workItem{node: task.Node, state: *taskState})
+		}
+		return tasks
 	}
 
-	f.Lock()
-	if nodeEntry.executing {
-		f.logger.Warnf(ctx, "node already executing %s", node)
-		f.Unlock()
-		return nil
+	for _, next := range lastState.GetNextNodes() {
+		taskState := lastState.Clone()
+		taskState.SetPendingTasks(nil)
+		taskState.SetPendingWrites(nil)
+		tasks = append(tasks, workItem{node: next, state: *taskState})
 	}
+	return tasks
+}
 
-	f.logger.Infof(ctx, "executing node %s", node)
-	nodeEntry.executing = true
-	f.Unlock()
-
-	// 如果有依赖节点，等待前置节点完成
-	if len(nodeEntry.dependencies) > 0 {
-		f.logger.Infof(ctx, "waiting for dependencies %s, %+v", node, nodeEntry.dependencies)
-		states, err := f.waitDependencies(ctx, copiedNodes, nodeEntry.dependencies)
-		if err != nil {
-			return xerror.Wrap(err)
-		}
-
-		for _, state := range states {
-			fullState.Merge(&state)
-		}
+// Continue 在线程上执行新一轮：获取线程锁后读取线程最新的检查点，丢弃上次执行的下一步和待恢复节点，
+// 由 update 写入本轮的输入后从起始节点执行。线程不存在时从空状态开始，threadID 为空时创建新线程。
+// 与先读取检查点再调用 Exec 相比，读取发生在持有锁之后，排队等待的执行不会覆盖上一个执行的结果。
+func (f *Flow) Continue(ctx context.Context, threadID string, update func(current *state.State) error, streamFunc flowcontract.StreamFunc, opts ...ExecOption) (state.State, error) {
+	if threadID == "" {
+		threadID = uuid.New().String()
 	}
 
-	if node == EndNode {
-		f.logger.Infof(ctx, "reached end node %s", node)
-		return nil
+	ctx, release, err := f.lockThread(ctx, threadID)
+	if err != nil {
+		return state.State{}, err
+	}
+	defer release()
+
+	input := &state.State{}
+	latest, err := f.checkpointer.GetLastest(ctx, threadID)
+	switch {
+	case err == nil:
+		input = latest.Clone()
+		input.SetNextNodes(nil)
+		input.SetPendingTasks(nil)
+		input.SetPendingWrites(nil)
+	case !errors.Is(err, flowcontract.ErrCheckpointNotFound):
+		return state.State{}, xerror.Wrap(err)
+	}
+	if input.Metadata == nil {
+		input.Metadata = make(map[string]interface{})
 	}
 
-	if node != StartNode {
-		// 执行节点
-		if err := nodeEntry.node.Run(ctx, &fullState, streamFunc); err != nil {
-			return xerror.Wrap(err)
-		}
-
-		fullState.SetNode(node)
-		nodeEntry.completion = append(nodeEntry.completion, fullState)
-
-		if streamFuncErr := streamFunc(ctx, &flowcontract.FlowStreamEvent{
-			FullState: &fullState,
-		}); streamFuncErr != nil {
-			f.logger.Errorf(ctx, "streaming failed state: %+v, error: %s", fullState, streamFuncErr)
+	if update != nil {
+		if err := update(input); err != nil {
+			return state.State{}, err
 		}
-
 	}
+	input.SetThreadID(threadID)
 
-	nodeEntry.executing = false
-
-	nextNodes := make([]string, 0)
+	return f.execute(ctx, []workItem{{node: StartNode, state: *input}}, nil, streamFunc, newExecOptions(opts...))
+}
 
-	// 处理所有边缘，并发添加下一个节点到队列
-	for _, edge := range f.graph[node] {
-		nextNode := edge.To
+// run 获取线程锁后从给定的节点开始执行
+func (f *Flow) run(ctx context.Context, tasks []workItem, writes []state.PendingWrite, streamFunc flowcontract.StreamFunc, options *execOptions) (state.State, error) {
+	// 同一线程同一时间只允许一个执行写入检查点
+	ctx, release, err := f.lockThread(ctx, tasks[0].state.GetThreadID())
+	if err != nil {
+		return state.State{}, err
+	}
+	defer release()
 
-		if len(edge.ConditionalTo) > 0 {
-			condition, err := edge.ConditionFunc(ctx, fullState)
-			if err != nil {
-				return xerror.Wrap(err)
-			}
+	return f.execute(ctx, tasks, writes, streamFunc, options)
+}
 
-			if condition != "" {
-				nextNode = condition
-			}
-		}
+// execute 创建执行器，从给定的节点开始执行，调用方需要已经持有线程锁
+func (f *Flow) execute(ctx context.Context, tasks []workItem, writes []state.PendingWrite, streamFunc flowcontract.StreamFunc, options *execOptions) (state.State, error) {
+	// 节点通过 flowcontract.StoreFromContext 访问跨线程的长期记忆
+	if f.store != nil {
+		ctx = flowcontract.WithStore(ctx, f.store)
+	}
 
-		if nextNode == "" {
-			return xerror.New(fmt.Sprintf("no next node found for edge %s", edge.To))
+	if streamFunc == nil {
+		streamFunc = func(ctx context.Context, event *flowcontract.FlowStreamEvent) error {
+			f.logger.Infof(ctx, "flow processing event streamFunc empty %+v", event)
+			return nil
 		}
-
-		nextNodes = append(nextNodes, nextNode)
-
-		wg.Add(1)
-		queue <- workItem{node: nextNode, state: fullState}
 	}
 
-	// 保存检查点
-	namespace := fullState.GetThreadID()
-	fullState.SetNextNodes(nextNodes)
-	if _, err := f.checkpointer.Save(ctx, namespace, &fullState); err != nil {
-		return xerror.Wrap(err)
+	events := &eventEmitter{
+		streamFunc: streamFunc,
+		logger:     f.logger,
+		threadID:   tasks[0].state.GetThreadID(),
+		runID:      uuid.New().String(),
+		modes:      options.streamModes,
 	}
-
-	return nil
-}
-
-func (f *Flow) waitDependencies(ctx context.Context, copiedNodes map[string]*nodeEntry, dependencies []string) ([]state.State, error) {
-	wg := sync.WaitGroup{}
-	wg.Add(len(dependencies))
-
-	timer := time.NewTimer(time.Minute * 2)
-
-	var err error
-	var states []state.State
-
-	for _, dependency := range dependencies {
-		libutils.SafeGo(ctx, f.logger, func() {
-			defer wg.Done()
-			f.logger.Infof(ctx, "waiting for dependency %s", dependency)
-			for {
-				select {
-				case <-ctx.Done():
-					err = xerror.New("context canceled")
-					return
-				case <-timer.C:
-					err = xerror.New(fmt.Sprintf("dependency node %s timeout", dependency))
-					return
-				default:
-				}
-
-				if len(copiedNodes[dependency].completion) > 0 {
-					firstState := copiedNodes[dependency].completion[0]
-					states = append(states, firstState)
-					copiedNodes[dependency].completion = copiedNodes[dependency].completion[1:]
-					return
-				}
-
-				time.Sleep(time.Second * 2)
-			}
-		})
+	if err := events.emit(ctx, &flowcontract.FlowStreamEvent{Type: flowcontract.EventRunStart}); err != nil {
+		return state.State{}, err
 	}
 
-	wg.Wait()
+	var (
+		finalState state.State
+		err        error
+	)
+	if f.mode == ExecutionModeSuperstep {
+		finalState, err = newSuperstepExecutor(ctx, f, events).run(ctx, tasks, writes)
+	} else {
+		finalState, err = newExecutor(ctx, f, writes, events).run(ctx, tasks)
+	}
 
 	if err != nil {
-		return nil, err
+		_ = events.emit(ctx, &flowcontract.FlowStreamEvent{Type: flowcontract.EventRunEnd, Err: err})
+		return state.State{}, err
+	}
+
+	if err := events.emit(ctx, &flowcontract.FlowStreamEvent{Type: flowcontract.EventRunEnd, FullState: &finalState}); err != nil {
+		return state.State{}, err
 	}
 
-	return states, nil
+	return finalState, nil
 }
 
 func (f *Flow) Draw(ctx context.Context) {
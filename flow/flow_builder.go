@@ -7,7 +7,6 @@ import (
 	flowcontract "github.com/futurxlab/golanggraph/contract"
 	"github.com/futurxlab/golanggraph/edge"
 	"github.com/futurxlab/golanggraph/logger"
-	"github.com/futurxlab/golanggraph/state"
 )
 
 type FlowBuilder struct {
@@ -16,6 +15,10 @@ type FlowBuilder struct {
 	nodes        []flowcontract.Node
 	dependencies map[string][]string
 	checkpointer flowcontract.Checkpointer
+	durability   Durability
+	store        flowcontract.Store
+	locking      ThreadLocking
+	mode         ExecutionMode
 	logger       logger.ILogger
 }
 
@@ -40,6 +43,30 @@ func (b *FlowBuilder) SetCheckpointer(checkpointer flowcontract.Checkpointer) *F
 	return b
 }
 
+// SetDurability 设置检查点的持久化模式，默认为 DurabilitySync
+func (b *FlowBuilder) SetDurability(durability Durability) *FlowBuilder {
+	b.durability = durability
+	return b
+}
+
+// SetStore 设置跨线程的长期记忆存储，节点通过 flowcontract.StoreFromContext 获取
+func (b *FlowBuilder) SetStore(store flowcontract.Store) *FlowBuilder {
+	b.store = store
+	return b
+}
+
+// SetThreadLocking 设置同一线程被并发执行时的处理方式，默认不加锁
+func (b *FlowBuilder) SetThreadLocking(locking ThreadLocking) *FlowBuilder {
+	b.locking = locking
+	return b
+}
+
+// SetExecutionMode 设置节点的调度方式，默认为 ExecutionModeConcurrent
+func (b *FlowBuilder) SetExecutionMode(mode ExecutionMode) *FlowBuilder {
+	b.mode = mode
+	return b
+}
+
 func (b *FlowBuilder) Compile() (*Flow, error) {
 
 	if b.name == "" {
@@ -57,6 +84,9 @@ func (b *FlowBuilder) Compile() (*Flow, error) {
 	if b.checkpointer == nil {
 		return nil, fmt.Errorf("checkpointer is required")
 	}
+	if _, ok := b.checkpointer.(flowcontract.ThreadLocker); b.locking != ThreadLockingNone && !ok {
+		return nil, fmt.Errorf("checkpointer does not support thread locking")
+	}
 
 	// 将所有节点添加到 nodes map 中
 	for _, node := range b.nodes {
@@ -69,7 +99,6 @@ func (b *FlowBuilder) Compile() (*Flow, error) {
 		nodes[node.Name()] = &nodeEntry{
 			node:         node,
 			dependencies: b.dependencies[node.Name()],
-			completion:   make([]state.State, 0),
 		}
 	}
 
@@ -119,13 +148,33 @@ func (b *FlowBuilder) Compile() (*Flow, error) {
 		}
 	}
 
+	// 所有依赖节点都有指向该节点的边时，由最后完成的依赖节点触发执行，无需等待
+	for name, node := range nodes {
+		if len(node.dependencies) == 0 {
+			continue
+		}
+		node.joinByEdges = true
+		for _, dependency := range node.dependencies {
+			if !slices.ContainsFunc(graph[dependency], func(e edge.Edge) bool {
+				return e.To == name || slices.Contains(e.ConditionalTo, name)
+			}) {
+				node.joinByEdges = false
+				break
+			}
+		}
+	}
+
 	// 返回构建好的 Flow
 	return &Flow{
-		name:         b.name,
-		checkpointer: b.checkpointer,
-		logger:       b.logger,
-		graph:        graph,
-		nodes:        nodes,
+		name:          b.name,
+		checkpointer:  b.checkpointer,
+		durability:    b.durability,
+		store:         b.store,
+		threadLocking: b.locking,
+		mode:          b.mode,
+		logger:        b.logger,
+		graph:         graph,
+		nodes:         nodes,
 	}, nil
 }
 
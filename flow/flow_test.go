@@ -2,8 +2,15 @@ package flow
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/futurxlab/golanggraph/checkpointer"
 	flowcontract "github.com/futurxlab/golanggraph/contract"
@@ -11,6 +18,9 @@ import (
 
 	"github.com/futurxlab/golanggraph/logger"
 	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/store"
+
+	"github.com/tmc/langchaingo/llms"
 )
 
 type sample1Node struct{}
@@ -73,6 +83,7 @@ func TestFlow(t *testing.T) {
 		checkpointer := checkpointer.NewInMemoryCheckpointer()
 
 		flow, err := NewFlowBuilder(logger).
+			SetName("parallel").
 			SetCheckpointer(checkpointer).
 			AddNode(sample1).
 			AddNode(sample2).
@@ -106,3 +117,1323 @@ func TestFlow(t *testing.T) {
 		flow.Exec(context.Background(), state.State{}, nil)
 	})
 }
+
+type countingCheckpointer struct {
+	flowcontract.Checkpointer
+	sync.Mutex
+	saves int
+	err   error
+}
+
+func (c *countingCheckpointer) Save(ctx context.Context, namespace string, state *state.State) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+	c.saves++
+	if c.err != nil {
+		return "", c.err
+	}
+	return c.Checkpointer.Save(ctx, namespace, state)
+}
+
+func TestFlowDurability(t *testing.T) {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	build := func(checkpointer flowcontract.Checkpointer, durability Durability) *Flow {
+		sample1 := &sample1Node{}
+		sample2 := &sample2Node{}
+		flow, err := NewFlowBuilder(logger).
+			SetName("durability").
+			SetCheckpointer(checkpointer).
+			SetDurability(durability).
+			AddNode(sample1).
+			AddNode(sample2).
+			AddEdge(edge.Edge{From: StartNode, To: sample1.Name()}).
+			AddEdge(edge.Edge{From: sample1.Name(), To: sample2.Name()}).
+			AddEdge(edge.Edge{From: sample2.Name(), To: EndNode}).
+			Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return flow
+	}
+
+	for name, tc := range map[string]struct {
+		durability Durability
+		saves      int
+	}{
+		"sync":  {durability: DurabilitySync, saves: 3},
+		"async": {durability: DurabilityAsync, saves: 3},
+		"exit":  {durability: DurabilityExit, saves: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			inMemory := checkpointer.NewInMemoryCheckpointer()
+			counting := &countingCheckpointer{Checkpointer: inMemory}
+
+			initState := state.State{}
+			initState.SetThreadID(name)
+			if _, err := build(counting, tc.durability).Exec(context.Background(), initState, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if counting.saves != tc.saves {
+				t.Fatalf("expected %d saves, got %d", tc.saves, counting.saves)
+			}
+
+			latest, err := inMemory.GetLastest(context.Background(), name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if latest.Metadata["sample2"] != "sample2" {
+				t.Fatalf("unexpected latest checkpoint %+v", latest)
+			}
+		})
+	}
+
+	t.Run("exit saves the final state instead of the last buffered checkpoint", func(t *testing.T) {
+		inMemory := checkpointer.NewInMemoryCheckpointer()
+		writer := newCheckpointWriter(context.Background(), DurabilityExit, inMemory, nil)
+
+		// 并行分支按完成顺序缓冲，最后缓冲的不一定是最终状态
+		final := &state.State{Metadata: map[string]interface{}{"branch": "a"}}
+		other := &state.State{Metadata: map[string]interface{}{"branch": "b"}}
+		for _, s := range []*state.State{final, other} {
+			if err := writer.Save(context.Background(), "exit-final", s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := writer.Close(context.Background(), final); err != nil {
+			t.Fatal(err)
+		}
+
+		all, err := inMemory.GetAll(context.Background(), "exit-final")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 1 || all[0].Metadata["branch"] != "a" {
+			t.Fatalf("unexpected checkpoints %+v", all)
+		}
+	})
+
+	t.Run("async error surfaces at end of run", func(t *testing.T) {
+		counting := &countingCheckpointer{
+			Checkpointer: checkpointer.NewInMemoryCheckpointer(),
+			err:          errors.New("redis down"),
+		}
+
+		if _, err := build(counting, DurabilityAsync).Exec(context.Background(), state.State{}, nil); err == nil {
+			t.Fatal("expected checkpoint error")
+		}
+	})
+}
+
+type rememberNode struct{}
+
+func (n *rememberNode) Name() string {
+	return "remember"
+}
+
+func (n *rememberNode) Run(ctx context.Context, state *state.State, streamFunc flowcontract.StreamFunc) error {
+	memory, ok := flowcontract.StoreFromContext(ctx)
+	if !ok {
+		return errors.New("store not found in context")
+	}
+	return memory.Put(ctx, []string{"user", "preferences"}, "language", map[string]interface{}{"value": "english"})
+}
+
+func TestFlowStore(t *testing.T) {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	memory := store.NewInMemoryStore()
+	remember := &rememberNode{}
+	flow, err := NewFlowBuilder(logger).
+		SetName("store").
+		SetCheckpointer(checkpointer.NewInMemoryCheckpointer()).
+		SetStore(memory).
+		AddNode(remember).
+		AddEdge(edge.Edge{From: StartNode, To: remember.Name()}).
+		AddEdge(edge.Edge{From: remember.Name(), To: EndNode}).
+		Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := flow.Exec(context.Background(), state.State{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	item, err := memory.Get(context.Background(), []string{"user", "preferences"}, "language")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Value["value"] != "english" {
+		t.Fatalf("unexpected item %+v", item)
+	}
+}
+
+type expensiveNode struct {
+	runs atomic.Int32
+}
+
+func (n *expensiveNode) Name() string {
+	return "expensive"
+}
+
+func (n *expensiveNode) Run(ctx context.Context, state *state.State, streamFunc flowcontract.StreamFunc) error {
+	n.runs.Add(1)
+	state.Metadata["expensive"] = "done"
+	return nil
+}
+
+type flakyNode struct {
+	runs atomic.Int32
+	fail atomic.Bool
+}
+
+func (n *flakyNode) Name() string {
+	return "flaky"
+}
+
+func (n *flakyNode) Run(ctx context.Context, state *state.State, streamFunc flowcontract.StreamFunc) error {
+	n.runs.Add(1)
+	state.Metadata["flaky"] = "partial"
+	if n.fail.Load() {
+		return errors.New("rate limited")
+	}
+	state.Metadata["flaky"] = "done"
+	return nil
+}
+
+type joinNode struct{}
+
+func (n *joinNode) Name() string {
+	return "join"
+}
+
+func (n *joinNode) Run(ctx context.Context, state *state.State, streamFunc flowcontract.StreamFunc) error {
+	state.Metadata["join"] = fmt.Sprintf("%v+%v", state.Metadata["expensive"], state.Metadata["flaky"])
+	return nil
+}
+
+func TestFlowResume(t *testing.T) {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expensive := &expensiveNode{}
+	flaky := &flakyNode{}
+	join := &joinNode{}
+	flaky.fail.Store(true)
+
+	checkpointer := checkpointer.NewInMemoryCheckpointer()
+	flow, err := NewFlowBuilder(logger).
+		SetName("resume").
+		SetCheckpointer(checkpointer).
+		AddNode(expensive).
+		AddNode(flaky).
+		AddNode(join, expensive.Name(), flaky.Name()).
+		AddEdge(edge.Edge{From: StartNode, To: expensive.Name()}).
+		AddEdge(edge.Edge{From: StartNode, To: flaky.Name()}).
+		AddEdge(edge.Edge{From: expensive.Name(), To: join.Name()}).
+		AddEdge(edge.Edge{From: flaky.Name(), To: join.Name()}).
+		AddEdge(edge.Edge{From: join.Name(), To: EndNode}).
+		Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	initState := state.State{Metadata: map[string]interface{}{}}
+	initState.SetThreadID("resume")
+	if _, err := flow.Exec(ctx, initState, nil); err == nil {
+		t.Fatal("expected flaky node to fail")
+	}
+
+	lastState, err := checkpointer.GetLastest(ctx, "resume")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks := lastState.GetPendingTasks()
+	if len(tasks) == 0 || tasks[0].Node != flaky.Name() {
+		t.Fatalf("expected flaky to be the first pending task, got %+v", tasks)
+	}
+	if _, ok := tasks[0].State.Metadata["flaky"]; ok {
+		t.Fatalf("pending task input was modified by the failed run: %+v", tasks[0].State.Metadata)
+	}
+
+	flaky.fail.Store(false)
+	finalState, err := flow.Resume(ctx, *lastState, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if runs := expensive.runs.Load(); runs != 1 {
+		t.Fatalf("expected the succeeded branch to run once, got %d", runs)
+	}
+	if runs := flaky.runs.Load(); runs != 2 {
+		t.Fatalf("expected the failed branch to run twice, got %d", runs)
+	}
+	if finalState.Metadata["join"] != "done+done" {
+		t.Fatalf("unexpected final state %+v", finalState.Metadata)
+	}
+
+	t.Run("resume a finished thread", func(t *testing.T) {
+		lastState, err := checkpointer.GetLastest(ctx, "resume")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := flow.Resume(ctx, *lastState, nil); err != nil {
+			t.Fatal(err)
+		}
+		if runs := flaky.runs.Load(); runs != 2 {
+			t.Fatalf("finished thread should not run nodes again, got %d runs", runs)
+		}
+	})
+
+	t.Run("resume thread reads the latest checkpoint", func(t *testing.T) {
+		flaky.fail.Store(true)
+		initState := state.State{Metadata: map[string]interface{}{}}
+		initState.SetThreadID("resume-thread")
+		if _, err := flow.Exec(ctx, initState, nil); err == nil {
+			t.Fatal("expected flaky node to fail")
+		}
+
+		flaky.fail.Store(false)
+		finalState, err := flow.ResumeThread(ctx, "resume-thread", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if finalState.Metadata["join"] != "done+done" {
+			t.Fatalf("unexpected final state %+v", finalState.Metadata)
+		}
+
+		if _, err := flow.ResumeThread(ctx, "missing", nil); !errors.Is(err, flowcontract.ErrCheckpointNotFound) {
+			t.Fatalf("expected ErrCheckpointNotFound, got %v", err)
+		}
+	})
+}
+
+type blockingNode struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (n *blockingNode) Name() string {
+	return "blocking"
+}
+
+func (n *blockingNode) Run(ctx context.Context, state *state.State, streamFunc flowcontract.StreamFunc) error {
+	n.started <- struct{}{}
+	<-n.release
+	return nil
+}
+
+func TestFlowThreadLocking(t *testing.T) {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	build := func(checkpointer flowcontract.Checkpointer, locking ThreadLocking, node flowcontract.Node) (*Flow, error) {
+		return NewFlowBuilder(logger).
+			SetName("locking").
+			SetCheckpointer(checkpointer).
+			SetThreadLocking(locking).
+			AddNode(node).
+			AddEdge(edge.Edge{From: StartNode, To: node.Name()}).
+			AddEdge(edge.Edge{From: node.Name(), To: EndNode}).
+			Compile()
+	}
+
+	t.Run("checkpointer without locking support", func(t *testing.T) {
+		counting := &countingCheckpointer{Checkpointer: checkpointer.NewInMemoryCheckpointer()}
+		if _, err := build(counting, ThreadLockingFail, &sample1Node{}); err == nil {
+			t.Fatal("expected compile error")
+		}
+	})
+
+	for name, locking := range map[string]ThreadLocking{"fail": ThreadLockingFail, "wait": ThreadLockingWait} {
+		t.Run(name, func(t *testing.T) {
+			blocking := &blockingNode{started: make(chan struct{}, 2), release: make(chan struct{})}
+			flow, err := build(checkpointer.NewInMemoryCheckpointer(), locking, blocking)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			initState := state.State{}
+			initState.SetThreadID("thread")
+
+			errs := make(chan error, 2)
+			exec := func() {
+				_, err := flow.Exec(context.Background(), initState, nil)
+				errs <- err
+			}
+
+			go exec()
+			<-blocking.started
+			go exec()
+
+			if locking == ThreadLockingFail {
+				if err := <-errs; !errors.Is(err, flowcontract.ErrThreadBusy) {
+					t.Fatalf("expected ErrThreadBusy, got %v", err)
+				}
+				close(blocking.release)
+				if err := <-errs; err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			// 第二次执行排队，直到第一次执行结束才开始
+			select {
+			case <-blocking.started:
+				t.Fatal("queued execution started while the thread is locked")
+			case err := <-errs:
+				t.Fatalf("queued execution returned early: %v", err)
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			close(blocking.release)
+			for i := 0; i < 2; i++ {
+				if err := <-errs; err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+
+	t.Run("queued continue builds on the previous run", func(t *testing.T) {
+		blocking := &blockingNode{started: make(chan struct{}, 2), release: make(chan struct{})}
+		memory := checkpointer.NewInMemoryCheckpointer()
+		flow, err := build(memory, ThreadLockingWait, blocking)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		errs := make(chan error, 2)
+		turn := func(message string) {
+			_, err := flow.Continue(context.Background(), "thread", func(current *state.State) error {
+				current.History = append(current.History, llms.TextParts(llms.ChatMessageTypeHuman, message))
+				return nil
+			}, nil)
+			errs <- err
+		}
+
+		go turn("first")
+		<-blocking.started
+		go turn("second")
+
+		// 第二次执行在持有锁之后才读取最新状态，包含第一次执行的输入
+		time.Sleep(50 * time.Millisecond)
+		close(blocking.release)
+		for i := 0; i < 2; i++ {
+			if err := <-errs; err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		latest, err := memory.GetLastest(context.Background(), "thread")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(latest.History) != 2 {
+			t.Fatalf("expected both turns in the history, got %d messages", len(latest.History))
+		}
+	})
+
+	t.Run("update thread waits for the running execution", func(t *testing.T) {
+		blocking := &blockingNode{started: make(chan struct{}, 1), release: make(chan struct{})}
+		flow, err := build(checkpointer.NewInMemoryCheckpointer(), ThreadLockingWait, blocking)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		initState := state.State{}
+		initState.SetThreadID("thread")
+
+		errs := make(chan error, 2)
+		go func() {
+			_, err := flow.Exec(context.Background(), initState, nil)
+			errs <- err
+		}()
+		<-blocking.started
+
+		updated := make(chan struct{})
+		go func() {
+			errs <- flow.UpdateThread(context.Background(), "thread", func(ctx context.Context) error {
+				close(updated)
+				return nil
+			})
+		}()
+
+		select {
+		case <-updated:
+			t.Fatal("update ran while the thread is executing")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(blocking.release)
+		<-updated
+		for i := 0; i < 2; i++ {
+			if err := <-errs; err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	t.Run("lost lock stops checkpoint writes", func(t *testing.T) {
+		store := &losingLocker{InMemoryCheckpointer: checkpointer.NewInMemoryCheckpointer(), lost: make(chan struct{})}
+		blocking := &blockingNode{started: make(chan struct{}, 1), release: make(chan struct{})}
+		flow, err := build(store, ThreadLockingFail, blocking)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		initState := state.State{}
+		initState.SetThreadID("thread")
+
+		errs := make(chan error, 1)
+		go func() {
+			_, err := flow.Exec(context.Background(), initState, nil)
+			errs <- err
+		}()
+
+		<-blocking.started
+		close(store.lost)
+		close(blocking.release)
+		if err := <-errs; err == nil {
+			t.Fatal("expected execution to fail after the lock was lost")
+		}
+
+		// 只有锁丢失之前起始节点的检查点，失败后的待恢复检查点不再写入
+		all, err := store.GetAll(context.Background(), "thread")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 1 {
+			t.Fatalf("expected no checkpoints after the lock was lost, got %d", len(all))
+		}
+	})
+}
+
+// losingLocker 返回可以手动标记丢失的线程锁
+type losingLocker struct {
+	*checkpointer.InMemoryCheckpointer
+	lost chan struct{}
+}
+
+func (c *losingLocker) LockThread(ctx context.Context, namespace string, wait bool) (flowcontract.ThreadLock, error) {
+	return &losingLock{lost: c.lost}, nil
+}
+
+type losingLock struct {
+	lost chan struct{}
+}
+
+func (l *losingLock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *losingLock) Unlock(ctx context.Context) error {
+	return nil
+}
+
+type slowNode struct {
+	runs atomic.Int32
+}
+
+func (n *slowNode) Name() string {
+	return "slow"
+}
+
+func (n *slowNode) Run(ctx context.Context, state *state.State, streamFunc flowcontract.StreamFunc) error {
+	n.runs.Add(1)
+	time.Sleep(50 * time.Millisecond)
+	return nil
+}
+
+func TestFlowExecutor(t *testing.T) {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("revisit of an executing node is deferred", func(t *testing.T) {
+		slow := &slowNode{}
+		fast := &sample1Node{}
+		flow, err := NewFlowBuilder(logger).
+			SetName("revisit").
+			SetCheckpointer(checkpointer.NewInMemoryCheckpointer()).
+			AddNode(slow).
+			AddNode(fast).
+			AddEdge(edge.Edge{From: StartNode, To: slow.Name()}).
+			AddEdge(edge.Edge{From: StartNode, To: fast.Name()}).
+			AddEdge(edge.Edge{From: fast.Name(), To: slow.Name()}).
+			AddEdge(edge.Edge{From: slow.Name(), To: EndNode}).
+			Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := flow.Exec(context.Background(), state.State{}, nil); err != nil {
+			t.Fatal(err)
+		}
+		if runs := slow.runs.Load(); runs != 2 {
+			t.Fatalf("expected slow node to run twice, got %d", runs)
+		}
+	})
+
+	t.Run("concurrent executions share one flow", func(t *testing.T) {
+		sample1 := &sample1Node{}
+		sample2 := &sample2Node{}
+		sample3 := &sample3Node{}
+		flow, err := NewFlowBuilder(logger).
+			SetName("concurrent").
+			SetCheckpointer(checkpointer.NewInMemoryCheckpointer()).
+			AddNode(sample1).
+			AddNode(sample2).
+			AddNode(sample3, sample1.Name(), sample2.Name()).
+			AddEdge(edge.Edge{From: StartNode, To: sample1.Name()}).
+			AddEdge(edge.Edge{From: StartNode, To: sample2.Name()}).
+			AddEdge(edge.Edge{From: sample1.Name(), To: sample3.Name()}).
+			AddEdge(edge.Edge{From: sample2.Name(), To: sample3.Name()}).
+			AddEdge(edge.Edge{From: sample3.Name(), To: EndNode}).
+			Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				finalState, err := flow.Exec(context.Background(), state.State{Metadata: map[string]interface{}{}}, nil)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				for _, key := range []string{"sample1", "sample2", "sample3"} {
+					if finalState.Metadata[key] != key {
+						t.Errorf("missing %s in final state %+v", key, finalState.Metadata)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+type panicNode struct{}
+
+func (n *panicNode) Name() string {
+	return "panic"
+}
+
+func (n *panicNode) Run(ctx context.Context, state *state.State, streamFunc flowcontract.StreamFunc) error {
+	var metadata map[string]interface{}
+	metadata["boom"] = true
+	return nil
+}
+
+func TestFlowNodePanic(t *testing.T) {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 只有一个工作线程时，panic 后剩余的节点仍需要被处理
+	workerCount := FlowWorkerCount
+	FlowWorkerCount = 1
+	defer func() {
+		FlowWorkerCount = workerCount
+	}()
+
+	boom := &panicNode{}
+	sample1 := &sample1Node{}
+	checkpointer := checkpointer.NewInMemoryCheckpointer()
+	flow, err := NewFlowBuilder(logger).
+		SetName("panic").
+		SetCheckpointer(checkpointer).
+		AddNode(boom).
+		AddNode(sample1).
+		AddEdge(edge.Edge{From: StartNode, To: boom.Name()}).
+		AddEdge(edge.Edge{From: StartNode, To: sample1.Name()}).
+		AddEdge(edge.Edge{From: boom.Name(), To: EndNode}).
+		AddEdge(edge.Edge{From: sample1.Name(), To: EndNode}).
+		Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initState := state.State{}
+	initState.SetThreadID("panic")
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := flow.Exec(context.Background(), initState, nil)
+		errs <- err
+	}()
+
+	select {
+	case err := <-errs:
+		var panicErr *NodePanicError
+		if !errors.As(err, &panicErr) || panicErr.Node != boom.Name() || len(panicErr.Stack) == 0 {
+			t.Fatalf("expected node panic error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("flow hung after node panic")
+	}
+
+	lastState, err := checkpointer.GetLastest(context.Background(), "panic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tasks := lastState.GetPendingTasks(); len(tasks) == 0 || tasks[0].Node != boom.Name() {
+		t.Fatalf("expected panicked node to be pending, got %+v", tasks)
+	}
+}
+
+type countNode struct {
+	name string
+	runs *atomic.Int32
+}
+
+func (n *countNode) Name() string {
+	return n.name
+}
+
+func (n *countNode) Run(ctx context.Context, state *state.State, streamFunc flowcontract.StreamFunc) error {
+	n.runs.Add(1)
+	if state.Metadata == nil {
+		state.Metadata = make(map[string]interface{})
+	}
+	state.Metadata[n.name] = true
+	return nil
+}
+
+func TestFlowScheduler(t *testing.T) {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	execWithin := func(t *testing.T, flow *Flow) (state.State, error) {
+		t.Helper()
+		type result struct {
+			state state.State
+			err   error
+		}
+		results := make(chan result, 1)
+		go func() {
+			finalState, err := flow.Exec(context.Background(), state.State{}, nil)
+			results <- result{finalState, err}
+		}()
+		select {
+		case r := <-results:
+			return r.state, r.err
+		case <-time.After(30 * time.Second):
+			t.Fatal("flow did not finish")
+			return state.State{}, nil
+		}
+	}
+
+	t.Run("wide fan-out", func(t *testing.T) {
+		var runs atomic.Int32
+		builder := NewFlowBuilder(logger).SetName("wide").SetCheckpointer(checkpointer.NewInMemoryCheckpointer())
+		for i := 0; i < 500; i++ {
+			node := &countNode{name: fmt.Sprintf("branch%d", i), runs: &runs}
+			builder.AddNode(node).
+				AddEdge(edge.Edge{From: StartNode, To: node.Name()}).
+				AddEdge(edge.Edge{From: node.Name(), To: EndNode})
+		}
+		flow, err := builder.Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := execWithin(t, flow); err != nil {
+			t.Fatal(err)
+		}
+		if runs.Load() != 500 {
+			t.Fatalf("expected 500 runs, got %d", runs.Load())
+		}
+	})
+
+	t.Run("wide fan-in", func(t *testing.T) {
+		var runs, joins atomic.Int32
+		join := &countNode{name: "join", runs: &joins}
+		builder := NewFlowBuilder(logger).SetName("fan-in").SetCheckpointer(checkpointer.NewInMemoryCheckpointer())
+		dependencies := make([]string, 0)
+		for i := 0; i < 200; i++ {
+			node := &countNode{name: fmt.Sprintf("branch%d", i), runs: &runs}
+			dependencies = append(dependencies, node.Name())
+			builder.AddNode(node).
+				AddEdge(edge.Edge{From: StartNode, To: node.Name()}).
+				AddEdge(edge.Edge{From: node.Name(), To: join.Name()})
+		}
+		flow, err := builder.
+			AddNode(join, dependencies...).
+			AddEdge(edge.Edge{From: join.Name(), To: EndNode}).
+			Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		finalState, err := execWithin(t, flow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if joins.Load() != 1 {
+			t.Fatalf("expected join to run once, got %d", joins.Load())
+		}
+		for _, dependency := range dependencies {
+			if finalState.Metadata[dependency] != true {
+				t.Fatalf("join state is missing %s", dependency)
+			}
+		}
+	})
+
+	t.Run("deep chain", func(t *testing.T) {
+		var runs atomic.Int32
+		builder := NewFlowBuilder(logger).SetName("deep").SetCheckpointer(checkpointer.NewInMemoryCheckpointer())
+		previous := StartNode
+		for i := 0; i < 1000; i++ {
+			node := &countNode{name: fmt.Sprintf("step%d", i), runs: &runs}
+			builder.AddNode(node).AddEdge(edge.Edge{From: previous, To: node.Name()})
+			previous = node.Name()
+		}
+		flow, err := builder.AddEdge(edge.Edge{From: previous, To: EndNode}).Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		finalState, err := execWithin(t, flow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if runs.Load() != 1000 || len(finalState.Metadata) != 1000 {
+			t.Fatalf("expected 1000 steps, got %d runs and %d keys", runs.Load(), len(finalState.Metadata))
+		}
+	})
+
+	t.Run("dependency without edge does not hold a worker", func(t *testing.T) {
+		workerCount := FlowWorkerCount
+		FlowWorkerCount = 1
+		defer func() {
+			FlowWorkerCount = workerCount
+		}()
+
+		var runs atomic.Int32
+		a := &countNode{name: "a", runs: &runs}
+		b := &countNode{name: "b", runs: &runs}
+		c := &countNode{name: "c", runs: &runs}
+		flow, err := NewFlowBuilder(logger).
+			SetName("park").
+			SetCheckpointer(checkpointer.NewInMemoryCheckpointer()).
+			AddNode(a).
+			AddNode(b).
+			AddNode(c, a.Name()).
+			AddEdge(edge.Edge{From: StartNode, To: b.Name()}).
+			AddEdge(edge.Edge{From: StartNode, To: a.Name()}).
+			AddEdge(edge.Edge{From: b.Name(), To: c.Name()}).
+			AddEdge(edge.Edge{From: a.Name(), To: EndNode}).
+			AddEdge(edge.Edge{From: c.Name(), To: EndNode}).
+			Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := execWithin(t, flow); err != nil {
+			t.Fatal(err)
+		}
+		if runs.Load() != 3 {
+			t.Fatalf("expected 3 runs, got %d", runs.Load())
+		}
+	})
+
+	t.Run("dependency that never completes", func(t *testing.T) {
+		var runs atomic.Int32
+		a := &countNode{name: "a", runs: &runs}
+		b := &countNode{name: "b", runs: &runs}
+		c := &countNode{name: "c", runs: &runs}
+		flow, err := NewFlowBuilder(logger).
+			SetName("stalled").
+			SetCheckpointer(checkpointer.NewInMemoryCheckpointer()).
+			AddNode(a).
+			AddNode(b).
+			AddNode(c, a.Name()).
+			AddEdge(edge.Edge{From: StartNode, To: b.Name()}).
+			AddEdge(edge.Edge{From: b.Name(), To: c.Name()}).
+			AddEdge(edge.Edge{From: a.Name(), To: EndNode}).
+			AddEdge(edge.Edge{From: c.Name(), To: a.Name()}).
+			Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := execWithin(t, flow); err == nil {
+			t.Fatal("expected stalled dependency error")
+		}
+	})
+
+	t.Run("join after an untaken conditional branch", func(t *testing.T) {
+		var runs atomic.Int32
+		router := &countNode{name: "router", runs: &runs}
+		a := &countNode{name: "a", runs: &runs}
+		b := &countNode{name: "b", runs: &runs}
+		join := &countNode{name: "join", runs: &runs}
+		flow, err := NewFlowBuilder(logger).
+			SetName("untaken").
+			SetCheckpointer(checkpointer.NewInMemoryCheckpointer()).
+			AddNode(router).
+			AddNode(a).
+			AddNode(b).
+			AddNode(join, a.Name(), b.Name()).
+			AddEdge(edge.Edge{From: StartNode, To: router.Name()}).
+			AddEdge(edge.Edge{From: StartNode, To: a.Name()}).
+			AddEdge(edge.Edge{
+				From:          router.Name(),
+				ConditionalTo: []string{b.Name(), EndNode},
+				ConditionFunc: func(ctx context.Context, state state.State) (string, error) {
+					return EndNode, nil
+				},
+			}).
+			AddEdge(edge.Edge{From: a.Name(), To: join.Name()}).
+			AddEdge(edge.Edge{From: b.Name(), To: join.Name()}).
+			AddEdge(edge.Edge{From: join.Name(), To: EndNode}).
+			Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = execWithin(t, flow)
+		if err == nil || !strings.Contains(err.Error(), "dependencies of node join never completed: [b]") {
+			t.Fatalf("expected join to report the missing dependency, got %v", err)
+		}
+	})
+}
+
+type appendNode struct {
+	name string
+	runs atomic.Int32
+	fail atomic.Bool
+}
+
+func (n *appendNode) Name() string {
+	return n.name
+}
+
+func (n *appendNode) Run(ctx context.Context, state *state.State, streamFunc flowcontract.StreamFunc) error {
+	n.runs.Add(1)
+	// 打乱完成顺序
+	time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+	if n.fail.Load() {
+		return errors.New("branch failed")
+	}
+	state.History = append(state.History, llms.TextParts(llms.ChatMessageTypeAI, n.name))
+	if state.Metadata == nil {
+		state.Metadata = make(map[string]interface{})
+	}
+	state.Metadata["last"] = n.name
+	state.Metadata[n.name] = true
+	return nil
+}
+
+func TestFlowSuperstep(t *testing.T) {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// __start__ -> a, b, c；a -> a2；a2, b, c -> join
+	build := func(checkpointer flowcontract.Checkpointer) (*Flow, map[string]*appendNode) {
+		nodes := make(map[string]*appendNode)
+		for _, name := range []string{"c", "a", "b", "a2", "join"} {
+			nodes[name] = &appendNode{name: name}
+		}
+		flow, err := NewFlowBuilder(logger).
+			SetName("superstep").
+			SetCheckpointer(checkpointer).
+			SetExecutionMode(ExecutionModeSuperstep).
+			AddNode(nodes["a"]).
+			AddNode(nodes["b"]).
+			AddNode(nodes["c"]).
+			AddNode(nodes["a2"]).
+			AddNode(nodes["join"], "a2", "b", "c").
+			AddEdge(edge.Edge{From: StartNode, To: "c"}).
+			AddEdge(edge.Edge{From: StartNode, To: "b"}).
+			AddEdge(edge.Edge{From: StartNode, To: "a"}).
+			AddEdge(edge.Edge{From: "a", To: "a2"}).
+			AddEdge(edge.Edge{From: "a2", To: "join"}).
+			AddEdge(edge.Edge{From: "b", To: "join"}).
+			AddEdge(edge.Edge{From: "c", To: "join"}).
+			AddEdge(edge.Edge{From: "join", To: EndNode}).
+			Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return flow, nodes
+	}
+
+	messages := func(s state.State) []string {
+		texts := make([]string, 0, len(s.History))
+		for _, message := range s.History {
+			texts = append(texts, message.Parts[0].(llms.TextContent).Text)
+		}
+		return texts
+	}
+	expected := []string{"a", "b", "c", "a2", "join"}
+
+	t.Run("deterministic merge and one checkpoint per step", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			checkpointer := checkpointer.NewInMemoryCheckpointer()
+			flow, _ := build(checkpointer)
+
+			initState := state.State{}
+			initState.SetThreadID("superstep")
+			finalState, err := flow.Exec(context.Background(), initState, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := messages(finalState); !reflect.DeepEqual(got, expected) {
+				t.Fatalf("expected messages %v, got %v", expected, got)
+			}
+			if finalState.Metadata["last"] != "join" {
+				t.Fatalf("unexpected metadata %+v", finalState.Metadata)
+			}
+
+			// __start__, [a b c]（多个节点，Node 为空）, [a2], [join]
+			all, err := checkpointer.GetAll(context.Background(), "superstep")
+			if err != nil {
+				t.Fatal(err)
+			}
+			nodes := make([]string, 0, len(all))
+			for _, s := range all {
+				nodes = append(nodes, s.GetNode())
+			}
+			if !reflect.DeepEqual(nodes, []string{StartNode, "", "a2", "join"}) {
+				t.Fatalf("unexpected checkpoints %v", nodes)
+			}
+		}
+	})
+
+	t.Run("resume re-runs only failed nodes of the step", func(t *testing.T) {
+		checkpointer := checkpointer.NewInMemoryCheckpointer()
+		flow, nodes := build(checkpointer)
+		nodes["b"].fail.Store(true)
+
+		initState := state.State{}
+		initState.SetThreadID("resume")
+		if _, err := flow.Exec(context.Background(), initState, nil); err == nil {
+			t.Fatal("expected step to fail")
+		}
+
+		lastState, err := checkpointer.GetLastest(context.Background(), "resume")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tasks := lastState.GetPendingTasks(); len(tasks) != 1 || tasks[0].Node != "b" {
+			t.Fatalf("unexpected pending tasks %+v", tasks)
+		}
+
+		nodes["b"].fail.Store(false)
+		finalState, err := flow.Resume(context.Background(), *lastState, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := messages(finalState); !reflect.DeepEqual(got, expected) {
+			t.Fatalf("expected messages %v, got %v", expected, got)
+		}
+		for name, runs := range map[string]int32{"a": 1, "b": 2, "c": 1, "join": 1} {
+			if nodes[name].runs.Load() != runs {
+				t.Fatalf("expected %s to run %d times, got %d", name, runs, nodes[name].runs.Load())
+			}
+		}
+	})
+}
+
+type tokenNode struct{}
+
+func (n *tokenNode) Name() string {
+	return "token"
+}
+
+func (n *tokenNode) Run(ctx context.Context, state *state.State, streamFunc flowcontract.StreamFunc) error {
+	if err := streamFunc(ctx, &flowcontract.FlowStreamEvent{Type: flowcontract.EventLLMToken, Chunk: "hello"}); err != nil {
+		return err
+	}
+	return streamFunc(ctx, &flowcontract.FlowStreamEvent{Chunk: "progress"})
+}
+
+func TestFlowStreamEvents(t *testing.T) {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collect := func() (*[]flowcontract.FlowStreamEvent, flowcontract.StreamFunc) {
+		var mu sync.Mutex
+		events := make([]flowcontract.FlowStreamEvent, 0)
+		return &events, func(ctx context.Context, event *flowcontract.FlowStreamEvent) error {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, *event)
+			return nil
+		}
+	}
+
+	t.Run("typed events carry node, thread and run", func(t *testing.T) {
+		token := &tokenNode{}
+		flow, err := NewFlowBuilder(logger).
+			SetName("events").
+			SetCheckpointer(checkpointer.NewInMemoryCheckpointer()).
+			AddNode(token).
+			AddEdge(edge.Edge{From: StartNode, To: token.Name()}).
+			AddEdge(edge.Edge{From: token.Name(), To: EndNode}).
+			Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		initState := state.State{}
+		initState.SetThreadID("events")
+
+		events, streamFunc := collect()
+		if _, err := flow.Exec(context.Background(), initState, streamFunc, WithStreamMode(flowcontract.StreamModeDebug)); err != nil {
+			t.Fatal(err)
+		}
+
+		types := make([]flowcontract.EventType, 0)
+		for _, event := range *events {
+			if event.ThreadID != "events" || event.RunID == "" || event.RunID != (*events)[0].RunID {
+				t.Fatalf("unexpected event ids %+v", event)
+			}
+			types = append(types, event.Type)
+		}
+
+		expected := []flowcontract.EventType{
+			flowcontract.EventRunStart,
+			flowcontract.EventRoute,
+			flowcontract.EventCheckpointSaved,
+			flowcontract.EventNodeStart,
+			flowcontract.EventLLMToken,
+			flowcontract.EventCustom,
+			flowcontract.EventNodeEnd,
+			flowcontract.EventRoute,
+			flowcontract.EventCheckpointSaved,
+			flowcontract.EventRunEnd,
+		}
+		if !reflect.DeepEqual(types, expected) {
+			t.Fatalf("expected events %v, got %v", expected, types)
+		}
+
+		for _, event := range (*events)[3:8] {
+			if event.Node != token.Name() {
+				t.Fatalf("expected node %s, got %+v", token.Name(), event)
+			}
+		}
+		if route := (*events)[7]; !reflect.DeepEqual(route.NextNodes, []string{EndNode}) {
+			t.Fatalf("unexpected route event %+v", route)
+		}
+		if saved := (*events)[8]; saved.CheckpointID == "" || saved.Node != token.Name() {
+			t.Fatalf("unexpected checkpoint event %+v", saved)
+		}
+		if end := (*events)[9]; end.FullState == nil || end.Err != nil {
+			t.Fatalf("unexpected run end event %+v", end)
+		}
+	})
+
+	t.Run("node failure emits error and run end", func(t *testing.T) {
+		flaky := &flakyNode{}
+		flaky.fail.Store(true)
+		flow, err := NewFlowBuilder(logger).
+			SetName("events").
+			SetCheckpointer(checkpointer.NewInMemoryCheckpointer()).
+			SetExecutionMode(ExecutionModeSuperstep).
+			AddNode(flaky).
+			AddEdge(edge.Edge{From: StartNode, To: flaky.Name()}).
+			AddEdge(edge.Edge{From: flaky.Name(), To: EndNode}).
+			Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		initState := state.State{Metadata: map[string]interface{}{}}
+		events, streamFunc := collect()
+		if _, err := flow.Exec(context.Background(), initState, streamFunc); err == nil {
+			t.Fatal("expected flow to fail")
+		}
+
+		var failed, ended bool
+		for _, event := range *events {
+			switch event.Type {
+			case flowcontract.EventError:
+				failed = event.Node == flaky.Name() && event.Err != nil
+			case flowcontract.EventRunEnd:
+				ended = event.Err != nil
+			}
+		}
+		if !failed || !ended {
+			t.Fatalf("expected error and run end events, got %+v", *events)
+		}
+	})
+}
+
+func TestFlowStreamModes(t *testing.T) {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := &tokenNode{}
+	sample1 := &sample1Node{}
+	flow, err := NewFlowBuilder(logger).
+		SetName("modes").
+		SetCheckpointer(checkpointer.NewInMemoryCheckpointer()).
+		AddNode(token).
+		AddNode(sample1).
+		AddEdge(edge.Edge{From: StartNode, To: token.Name()}).
+		AddEdge(edge.Edge{From: token.Name(), To: sample1.Name()}).
+		AddEdge(edge.Edge{From: sample1.Name(), To: EndNode}).
+		Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(t *testing.T, opts ...ExecOption) []flowcontract.FlowStreamEvent {
+		events := make([]flowcontract.FlowStreamEvent, 0)
+		initState := state.State{Metadata: map[string]interface{}{"keep": 1}}
+		_, err := flow.Exec(context.Background(), initState, func(ctx context.Context, event *flowcontract.FlowStreamEvent) error {
+			if event.Type != flowcontract.EventRunStart && event.Type != flowcontract.EventRunEnd {
+				events = append(events, *event)
+			}
+			return nil
+		}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return events
+	}
+
+	t.Run("messages only delivers tokens", func(t *testing.T) {
+		events := run(t, WithStreamMode(flowcontract.StreamModeMessages))
+		if len(events) != 1 || events[0].Type != flowcontract.EventLLMToken || events[0].Chunk != "hello" {
+			t.Fatalf("expected a single token event, got %+v", events)
+		}
+	})
+
+	t.Run("updates deliver node deltas", func(t *testing.T) {
+		events := run(t, WithStreamMode(flowcontract.StreamModeUpdates))
+		var update *state.State
+		for _, event := range events {
+			if event.Type == flowcontract.EventLLMToken {
+				t.Fatalf("unexpected token event %+v", event)
+			}
+			if event.Type == flowcontract.EventNodeEnd && event.Node == sample1.Name() {
+				if event.FullState != nil {
+					t.Fatalf("expected no full state in updates mode")
+				}
+				update = event.Update
+			}
+		}
+		if update == nil || !reflect.DeepEqual(update.Metadata, map[string]interface{}{"sample1": "sample1"}) {
+			t.Fatalf("unexpected update %+v", update)
+		}
+	})
+
+	t.Run("default delivers values and tokens", func(t *testing.T) {
+		types := make([]flowcontract.EventType, 0)
+		for _, event := range run(t) {
+			types = append(types, event.Type)
+			if event.Type == flowcontract.EventNodeEnd && (event.FullState == nil || event.Update != nil) {
+				t.Fatalf("expected full state only, got %+v", event)
+			}
+		}
+		expected := []flowcontract.EventType{
+			flowcontract.EventLLMToken,
+			flowcontract.EventCustom,
+			flowcontract.EventNodeEnd,
+			flowcontract.EventNodeEnd,
+		}
+		if !reflect.DeepEqual(types, expected) {
+			t.Fatalf("expected events %v, got %v", expected, types)
+		}
+	})
+}
+
+func TestFlowStream(t *testing.T) {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := &tokenNode{}
+	sample1 := &sample1Node{}
+	flow, err := NewFlowBuilder(logger).
+		SetName("stream").
+		SetCheckpointer(checkpointer.NewInMemoryCheckpointer()).
+		SetThreadLocking(ThreadLockingFail).
+		AddNode(token).
+		AddNode(sample1).
+		AddEdge(edge.Edge{From: StartNode, To: token.Name()}).
+		AddEdge(edge.Edge{From: token.Name(), To: sample1.Name()}).
+		AddEdge(edge.Edge{From: sample1.Name(), To: EndNode}).
+		Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initState := state.State{}
+	initState.SetThreadID("stream")
+
+	t.Run("iterates all events", func(t *testing.T) {
+		var last *flowcontract.FlowStreamEvent
+		count := 0
+		for event, err := range flow.Stream(context.Background(), initState) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			count++
+			last = event
+		}
+		if count < 4 || last.Type != flowcontract.EventRunEnd || last.FullState.Metadata["sample1"] != "sample1" {
+			t.Fatalf("unexpected last event %+v after %d events", last, count)
+		}
+	})
+
+	t.Run("stopping early cancels the run", func(t *testing.T) {
+		for event, err := range flow.Stream(context.Background(), initState) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if event.Type == flowcontract.EventLLMToken {
+				break
+			}
+		}
+
+		// 迭代器返回时执行已经退出并释放了线程锁
+		if _, err := flow.Exec(context.Background(), initState, nil); err != nil {
+			t.Fatalf("expected thread to be released, got %v", err)
+		}
+	})
+
+	t.Run("callback errors fail the run", func(t *testing.T) {
+		errStop := errors.New("client gone")
+		_, err := flow.Exec(context.Background(), initState, func(ctx context.Context, event *flowcontract.FlowStreamEvent) error {
+			if event.Type == flowcontract.EventNodeEnd {
+				return errStop
+			}
+			return nil
+		})
+		if !errors.Is(err, errStop) {
+			t.Fatalf("expected callback error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package flow
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/futurxlab/golanggraph/state"
+)
+
+// pendingTracker 记录一次执行中尚未完成的节点，执行失败时用于生成可恢复的检查点
+type pendingTracker struct {
+	sync.Mutex
+	nextID   int
+	tasks    map[int]workItem
+	failedID int
+	failed   chan struct{}
+	failOnce sync.Once
+}
+
+func newPendingTracker() *pendingTracker {
+	return &pendingTracker{
+		tasks:    make(map[int]workItem),
+		failedID: -1,
+		failed:   make(chan struct{}),
+	}
+}
+
+// add 为节点分配编号并记录为未完成
+func (t *pendingTracker) add(work workItem) workItem {
+	t.Lock()
+	defer t.Unlock()
+	work.id = t.nextID
+	t.nextID++
+
+	// 保存副本，节点执行时对状态的修改不应出现在待恢复的输入中
+	stored := work
+	stored.state = *work.state.Clone()
+	t.tasks[work.id] = stored
+	return work
+}
+
+// done 节点执行成功，不再需要恢复
+func (t *pendingTracker) done(id int) {
+	t.Lock()
+	defer t.Unlock()
+	delete(t.tasks, id)
+}
+
+// fail 标记执行失败，之后出队的节点不再执行，只记录为待恢复
+func (t *pendingTracker) fail(id int) {
+	t.failOnce.Do(func() {
+		t.Lock()
+		t.failedID = id
+		t.Unlock()
+		close(t.failed)
+	})
+}
+
+func (t *pendingTracker) isFailed() bool {
+	select {
+	case <-t.failed:
+		return true
+	default:
+		return false
+	}
+}
+
+// pendingTasks 返回所有未完成的节点，失败的节点排在最前面，其余按入队顺序排列
+func (t *pendingTracker) pendingTasks() []state.PendingTask {
+	t.Lock()
+	defer t.Unlock()
+
+	ids := make([]int, 0, len(t.tasks))
+	for id := range t.tasks {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if ids[i] == t.failedID || ids[j] == t.failedID {
+			return ids[i] == t.failedID
+		}
+		return ids[i] < ids[j]
+	})
+
+	tasks := make([]state.PendingTask, 0, len(ids))
+	for _, id := range ids {
+		work := t.tasks[id]
+		tasks = append(tasks, state.PendingTask{Node: work.node, State: work.state.Clone()})
+	}
+	return tasks
+}
@@ -0,0 +1,106 @@
+package flow
+
+import (
+	"sync"
+)
+
+// scheduler 一次执行的就绪队列。入队永远不会阻塞；
+// 依赖未就绪或正在执行的节点被暂存，不占用工作线程，其他节点完成时重新放回就绪队列。
+// 就绪队列为空且没有正在处理的节点时调度结束，所有工作线程退出。
+type scheduler struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	ready  []workItem
+	parked []workItem
+	// active 已出队但尚未处理完成的节点数量
+	active int
+	// generation 每次唤醒时递增，用于发现检查条件与暂存之间发生的唤醒
+	generation uint64
+	closed     bool
+}
+
+func newScheduler() *scheduler {
+	s := &scheduler{}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// push 把节点放入就绪队列
+func (s *scheduler) push(work workItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ready = append(s.ready, work)
+	s.cond.Signal()
+}
+
+// pop 取出一个就绪的节点，调度结束时返回 false
+func (s *scheduler) pop() (workItem, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.ready) == 0 && !s.closed {
+		s.cond.Wait()
+	}
+	if len(s.ready) == 0 {
+		return workItem{}, false
+	}
+
+	work := s.ready[0]
+	s.ready[0] = workItem{}
+	s.ready = s.ready[1:]
+	s.active++
+	return work, true
+}
+
+// done 出队的节点处理完成，没有剩余工作时结束调度
+func (s *scheduler) done() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.active--
+	if s.active == 0 && len(s.ready) == 0 {
+		s.closed = true
+		s.cond.Broadcast()
+	}
+}
+
+// version 返回当前的唤醒版本，在检查节点是否可以执行之前调用
+func (s *scheduler) version() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.generation
+}
+
+// park 暂存暂时无法执行的节点，检查之后已经发生过唤醒时直接放回就绪队列
+func (s *scheduler) park(work workItem, version uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.generation != version {
+		s.ready = append(s.ready, work)
+		s.cond.Signal()
+		return
+	}
+	s.parked = append(s.parked, work)
+}
+
+// wake 节点完成或释放时把所有暂存的节点放回就绪队列重新检查
+func (s *scheduler) wake() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	if len(s.parked) == 0 {
+		return
+	}
+	s.ready = append(s.ready, s.parked...)
+	s.parked = nil
+	s.cond.Broadcast()
+}
+
+// stalled 返回调度结束时仍然暂存的节点，它们的依赖永远不会完成
+func (s *scheduler) stalled() []workItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]workItem{}, s.parked...)
+}
@@ -0,0 +1,98 @@
+package flow
+
+import (
+	"context"
+	"iter"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/state"
+)
+
+// streamItem 等待调用方处理的事件，处理完成后关闭 done
+type streamItem struct {
+	event *flowcontract.FlowStreamEvent
+	done  chan struct{}
+}
+
+// Stream 从起始节点开始执行，以迭代器的方式返回流式事件。
+// 调用方处理完一个事件后才会继续执行（背压），提前停止迭代会取消执行并等待其退出。
+// 执行失败时最后返回一次错误，成功时最后一个事件为 EventRunEnd，其 FullState 为最终状态。
+func (f *Flow) Stream(ctx context.Context, initState state.State, opts ...ExecOption) iter.Seq2[*flowcontract.FlowStreamEvent, error] {
+	return f.stream(ctx, func(ctx context.Context, streamFunc flowcontract.StreamFunc) error {
+		_, err := f.Exec(ctx, initState, streamFunc, opts...)
+		return err
+	})
+}
+
+// ResumeStream 与 Resume 相同，以迭代器的方式返回流式事件
+func (f *Flow) ResumeStream(ctx context.Context, lastState state.State, opts ...ExecOption) iter.Seq2[*flowcontract.FlowStreamEvent, error] {
+	return f.stream(ctx, func(ctx context.Context, streamFunc flowcontract.StreamFunc) error {
+		_, err := f.Resume(ctx, lastState, streamFunc, opts...)
+		return err
+	})
+}
+
+// ResumeThreadStream 与 ResumeThread 相同，以迭代器的方式返回流式事件
+func (f *Flow) ResumeThreadStream(ctx context.Context, threadID string, opts ...ExecOption) iter.Seq2[*flowcontract.FlowStreamEvent, error] {
+	return f.stream(ctx, func(ctx context.Context, streamFunc flowcontract.StreamFunc) error {
+		_, err := f.ResumeThread(ctx, threadID, streamFunc, opts...)
+		return err
+	})
+}
+
+// ContinueStream 与 Continue 相同，以迭代器的方式返回流式事件
+func (f *Flow) ContinueStream(ctx context.Context, threadID string, update func(current *state.State) error, opts ...ExecOption) iter.Seq2[*flowcontract.FlowStreamEvent, error] {
+	return f.stream(ctx, func(ctx context.Context, streamFunc flowcontract.StreamFunc) error {
+		_, err := f.Continue(ctx, threadID, update, streamFunc, opts...)
+		return err
+	})
+}
+
+func (f *Flow) stream(ctx context.Context, run func(ctx context.Context, streamFunc flowcontract.StreamFunc) error) iter.Seq2[*flowcontract.FlowStreamEvent, error] {
+	return func(yield func(*flowcontract.FlowStreamEvent, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		items := make(chan streamItem)
+		result := make(chan error, 1)
+
+		// 部分事件（例如失败后保存检查点）使用不可取消的 ctx 发送，这里统一以执行的 ctx 判断是否停止
+		streamFunc := func(_ context.Context, event *flowcontract.FlowStreamEvent) error {
+			item := streamItem{event: event, done: make(chan struct{})}
+			select {
+			case items <- item:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			select {
+			case <-item.done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		go func() {
+			result <- run(ctx, streamFunc)
+		}()
+
+		for {
+			select {
+			case item := <-items:
+				more := yield(item.event, nil)
+				close(item.done)
+				if !more {
+					// 调用方停止迭代，取消执行并等待退出，保证线程锁等资源已经释放
+					cancel()
+					<-result
+					return
+				}
+			case err := <-result:
+				if err != nil {
+					yield(nil, err)
+				}
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package flow
+
+import (
+	"reflect"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/state"
+)
+
+// execOptions 单次 Exec/Resume 的选项
+type execOptions struct {
+	streamModes map[flowcontract.StreamMode]bool
+}
+
+type ExecOption func(*execOptions)
+
+// WithStreamMode 选择流式输出的内容，默认为 StreamModeValues 和 StreamModeMessages
+func WithStreamMode(modes ...flowcontract.StreamMode) ExecOption {
+	return func(o *execOptions) {
+		for _, mode := range modes {
+			o.streamModes[mode] = true
+		}
+	}
+}
+
+func newExecOptions(opts ...ExecOption) *execOptions {
+	options := &execOptions{
+		streamModes: make(map[flowcontract.StreamMode]bool),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if len(options.streamModes) == 0 {
+		options.streamModes[flowcontract.StreamModeValues] = true
+		options.streamModes[flowcontract.StreamModeMessages] = true
+	}
+
+	return options
+}
+
+// FilterEvent 按输出模式过滤事件，返回 nil 表示该事件不属于任何选择的模式，未选择模式时使用默认模式。
+// 以 StreamModeDebug 执行后可以用它为不同的调用方分别过滤
+func FilterEvent(event *flowcontract.FlowStreamEvent, modes ...flowcontract.StreamMode) *flowcontract.FlowStreamEvent {
+	return filterEvent(newExecOptions(WithStreamMode(modes...)).streamModes, event)
+}
+
+// filterEvent 按输出模式过滤事件，返回 nil 表示不输出。
+// 执行开始和结束事件总是输出，节点开始、检查点和路由事件只在 debug 模式下输出
+func filterEvent(modes map[flowcontract.StreamMode]bool, event *flowcontract.FlowStreamEvent) *flowcontract.FlowStreamEvent {
+	if modes[flowcontract.StreamModeDebug] {
+		return event
+	}
+
+	values, updates := modes[flowcontract.StreamModeValues], modes[flowcontract.StreamModeUpdates]
+
+	switch event.Type {
+	case flowcontract.EventRunStart, flowcontract.EventRunEnd:
+		return event
+	case flowcontract.EventLLMToken:
+		if modes[flowcontract.StreamModeMessages] {
+			return event
+		}
+	case flowcontract.EventNodeEnd:
+		if !values && !updates {
+			return nil
+		}
+		filtered := *event
+		if !values {
+			filtered.FullState = nil
+		}
+		if !updates {
+			filtered.Update = nil
+		}
+		return &filtered
+	case flowcontract.EventToolCallStart, flowcontract.EventToolCallResult, flowcontract.EventError, flowcontract.EventCustom:
+		if values || updates {
+			return event
+		}
+	}
+
+	return nil
+}
+
+// stateDelta 计算节点输出相对输入的修改，被删除的 Metadata key 值为 nil
+func stateDelta(base, output *state.State) *state.State {
+	delta, removed := diffState(base, output)
+	for _, k := range removed {
+		delta.Metadata[k] = nil
+	}
+	return delta
+}
+
+// diffState 计算节点输出相对输入的修改，被删除的 Metadata key 单独返回。
+// 历史消息是追加关系时只包含新增的消息，节点缩短了历史消息时为完整的历史消息
+func diffState(base, output *state.State) (*state.State, []string) {
+	delta := &state.State{
+		Metadata: make(map[string]interface{}),
+	}
+
+	if len(output.History) >= len(base.History) {
+		delta.History = append(delta.History, output.History[len(base.History):]...)
+	} else {
+		delta.History = append(delta.History, output.History...)
+	}
+
+	for k, v := range output.Metadata {
+		if old, ok := base.Metadata[k]; ok && reflect.DeepEqual(old, v) {
+			continue
+		}
+		delta.Metadata[k] = v
+	}
+	removed := make([]string, 0)
+	for k := range base.Metadata {
+		if _, ok := output.Metadata[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+
+	delta.SetNode(output.GetNode())
+
+	return delta, removed
+}
@@ -0,0 +1,337 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"sync"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
+)
+
+// ExecutionMode 节点的调度方式
+type ExecutionMode int
+
+const (
+	// ExecutionModeConcurrent 节点完成后立即调度下游节点，默认模式
+	ExecutionModeConcurrent ExecutionMode = iota
+	// ExecutionModeSuperstep 按超步执行：同一步触发的节点基于同一个状态并行执行，
+	// 全部完成后按节点名顺序合并输出，保存一个检查点，再调度下一步。
+	// 合并顺序和检查点顺序与 goroutine 调度无关，适用于需要回放和确定性测试的场景。
+	// 等待依赖节点的进度不会保存到检查点，Resume 后重新计算。
+	ExecutionModeSuperstep
+)
+
+// stepResult 节点在一个超步中的输出
+type stepResult struct {
+	node   string
+	output *state.State
+	err    error
+}
+
+// superstepExecutor 保存一次超步执行的运行状态
+type superstepExecutor struct {
+	flow   *Flow
+	events *eventEmitter
+	writer checkpointWriter
+
+	// completed 每个节点完成的次数，consumed 等待中的节点已经消费的依赖节点完成次数
+	completed map[string]int
+	consumed  map[string]map[string]int
+	// waiting 已被触发、但依赖节点尚未全部完成的节点
+	waiting []string
+}
+
+func newSuperstepExecutor(ctx context.Context, f *Flow, events *eventEmitter) *superstepExecutor {
+	return &superstepExecutor{
+		flow:      f,
+		events:    events,
+		writer:    newCheckpointWriter(ctx, f.durability, f.checkpointer, events.checkpointSaved),
+		completed: make(map[string]int),
+		consumed:  make(map[string]map[string]int),
+	}
+}
+
+// run 以 tasks 中的节点作为第一步开始执行，所有任务共享同一个输入状态；
+// writes 为上次执行失败的那一步中已经成功的节点输出，直接参与合并而不再执行
+func (e *superstepExecutor) run(ctx context.Context, tasks []workItem, writes []state.PendingWrite) (state.State, error) {
+	current := tasks[0].state.Clone()
+	active := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		if task.node != EndNode {
+			active = append(active, task.node)
+		}
+	}
+
+	err := e.loop(ctx, current, active, writes)
+
+	// 等待缓冲的检查点写入完成，写入失败时同样视为执行失败
+	var final *state.State
+	if err == nil {
+		final = current
+	}
+	if closeErr := e.writer.Close(ctx, final); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return state.State{}, xerror.Wrap(err)
+	}
+
+	e.flow.logger.Infof(ctx, "flow finished")
+
+	return *current, nil
+}
+
+func (e *superstepExecutor) loop(ctx context.Context, current *state.State, active []string, writes []state.PendingWrite) error {
+	for step := 0; len(active) > 0; step++ {
+		if err := ctx.Err(); err != nil {
+			return e.fail(ctx, current, active, nil, err)
+		}
+
+		e.flow.logger.Infof(ctx, "executing superstep %d %v", step, active)
+
+		results := e.runStep(ctx, current, active, writes)
+		writes = nil
+
+		// 有节点失败时，保存这一步失败的节点和已经成功的输出，Resume 时只重新执行失败的节点
+		var firstErr error
+		failed := make([]string, 0)
+		succeeded := make([]stepResult, 0, len(results))
+		for _, result := range results {
+			if result.err != nil {
+				if firstErr == nil {
+					firstErr = result.err
+				}
+				failed = append(failed, result.node)
+				continue
+			}
+			succeeded = append(succeeded, result)
+		}
+		if firstErr != nil {
+			return e.fail(ctx, current, failed, succeeded, firstErr)
+		}
+
+		next, err := e.merge(ctx, current, results)
+		if err != nil {
+			return err
+		}
+
+		// 多个节点的步骤没有单一的节点名，Node 留空，避免保存不存在的节点名；
+		// 这一步执行的节点可以从上一个检查点的 NextNodes 得到
+		current.SetNode("")
+		if len(active) == 1 {
+			current.SetNode(active[0])
+		}
+		current.SetNextNodes(next)
+		if err := e.writer.Save(ctx, current.GetThreadID(), current); err != nil {
+			return xerror.Wrap(err)
+		}
+
+		active = next
+	}
+
+	if len(e.waiting) > 0 {
+		return xerror.New(fmt.Sprintf("dependencies of nodes %v never completed", e.waiting))
+	}
+
+	return nil
+}
+
+// runStep 基于同一个输入状态并行执行这一步的所有节点，结果按节点名排序
+func (e *superstepExecutor) runStep(ctx context.Context, current *state.State, active []string, writes []state.PendingWrite) []stepResult {
+	results := make([]stepResult, len(active), len(active)+len(writes))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, max(FlowWorkerCount, 1))
+	for i, node := range active {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			output, err := e.runNode(ctx, node, current.Clone())
+			results[i] = stepResult{node: node, output: output, err: err}
+		}()
+	}
+	wg.Wait()
+
+	for _, write := range writes {
+		results = append(results, stepResult{node: write.Node, output: write.State.Clone()})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].node < results[j].node
+	})
+
+	return results
+}
+
+// runNode 执行单个节点，panic 转换为 NodePanicError
+func (e *superstepExecutor) runNode(ctx context.Context, node string, input *state.State) (output *state.State, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e.flow.logger.Errorf(ctx, "node %s panicked %+v", node, r)
+			err = xerror.Wrap(&NodePanicError{Node: node, Value: r, Stack: debug.Stack()})
+			_ = e.events.emit(ctx, &flowcontract.FlowStreamEvent{Type: flowcontract.EventError, Node: node, Err: err})
+		}
+	}()
+
+	if node == StartNode {
+		return input, nil
+	}
+
+	entry, ok := e.flow.nodes[node]
+	if !ok || entry.node == nil {
+		return nil, xerror.New(fmt.Sprintf("node %s not found", node))
+	}
+
+	e.flow.logger.Infof(ctx, "executing node %s", node)
+	if err := e.events.runNode(ctx, node, entry.node, input); err != nil {
+		return nil, xerror.Wrap(err)
+	}
+
+	return input, nil
+}
+
+// merge 按节点名顺序把输出合并到 current，并返回下一步要执行的节点
+func (e *superstepExecutor) merge(ctx context.Context, current *state.State, results []stepResult) ([]string, error) {
+	base := current.Clone()
+
+	triggered := make([]string, 0)
+	for _, result := range results {
+		applyWrites(current, base, result.output)
+		e.completed[result.node]++
+
+		routed := make([]string, 0, len(e.flow.graph[result.node]))
+		for _, edge := range e.flow.graph[result.node] {
+			nextNode := edge.To
+
+			if len(edge.ConditionalTo) > 0 {
+				condition, err := edge.ConditionFunc(ctx, *result.output)
+				if err != nil {
+					return nil, xerror.Wrap(err)
+				}
+
+				if condition != "" {
+					nextNode = condition
+				}
+			}
+
+			if nextNode == "" {
+				return nil, xerror.New(fmt.Sprintf("no next node found for edge %s", edge.To))
+			}
+
+			routed = append(routed, nextNode)
+		}
+		if err := e.events.route(ctx, result.node, routed); err != nil {
+			return nil, err
+		}
+		triggered = append(triggered, routed...)
+	}
+
+	next := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, node := range triggered {
+		if node == EndNode || seen[node] {
+			continue
+		}
+		seen[node] = true
+
+		entry, ok := e.flow.nodes[node]
+		if ok && len(entry.dependencies) > 0 {
+			if !slices.Contains(e.waiting, node) {
+				e.waiting = append(e.waiting, node)
+			}
+			continue
+		}
+		next = append(next, node)
+	}
+
+	// 依赖节点全部完成的等待节点加入下一步
+	waiting := make([]string, 0, len(e.waiting))
+	for _, node := range e.waiting {
+		if e.takeDependencies(node) {
+			next = append(next, node)
+			continue
+		}
+		waiting = append(waiting, node)
+	}
+	e.waiting = waiting
+
+	sort.Strings(next)
+
+	return next, nil
+}
+
+// takeDependencies 依赖节点在上次执行之后都完成过时消费一次完成记录
+func (e *superstepExecutor) takeDependencies(node string) bool {
+	consumed, ok := e.consumed[node]
+	if !ok {
+		consumed = make(map[string]int)
+		e.consumed[node] = consumed
+	}
+
+	dependencies := e.flow.nodes[node].dependencies
+	for _, dependency := range dependencies {
+		if e.completed[dependency] <= consumed[dependency] {
+			return false
+		}
+	}
+	for _, dependency := range dependencies {
+		consumed[dependency]++
+	}
+	return true
+}
+
+// fail 以这一步的输入状态保存检查点，记录失败的节点和已经成功的节点输出
+func (e *superstepExecutor) fail(ctx context.Context, current *state.State, failed []string, succeeded []stepResult, err error) error {
+	pending := current.Clone()
+	tasks := make([]state.PendingTask, 0, len(failed))
+	for _, node := range failed {
+		tasks = append(tasks, state.PendingTask{Node: node, State: current.Clone()})
+	}
+	writes := make([]state.PendingWrite, 0, len(succeeded))
+	for _, result := range succeeded {
+		writes = append(writes, state.PendingWrite{Node: result.node, State: result.output})
+	}
+
+	if len(tasks) > 0 {
+		pending.SetNode(tasks[0].Node)
+	}
+	pending.SetNextNodes(nil)
+	pending.SetPendingTasks(tasks)
+	pending.SetPendingWrites(writes)
+
+	// 执行可能因为 ctx 结束而失败，此时仍需要保存
+	if saveErr := e.writer.Save(context.WithoutCancel(ctx), pending.GetThreadID(), pending); saveErr != nil {
+		e.flow.logger.Errorf(ctx, "failed to save pending writes: %s", saveErr)
+	}
+
+	return err
+}
+
+// applyWrites 把节点相对 base 的修改应用到 current：追加新增的消息，覆盖变化的 Metadata，删除被移除的 key。
+// 节点缩短了历史消息（例如摘要）时以节点的历史消息为准。
+func applyWrites(current, base, output *state.State) {
+	delta, removed := diffState(base, output)
+
+	if len(output.History) >= len(base.History) {
+		current.History = append(current.History, delta.History...)
+	} else {
+		current.History = delta.History
+	}
+
+	if current.Metadata == nil && len(delta.Metadata) > 0 {
+		current.Metadata = make(map[string]interface{})
+	}
+	for k, v := range delta.Metadata {
+		current.Metadata[k] = v
+	}
+	for _, k := range removed {
+		delete(current.Metadata, k)
+	}
+}
@@ -0,0 +1,98 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
+)
+
+// ThreadLocking 同一线程被并发执行时的处理方式，需要 Checkpointer 实现 flowcontract.ThreadLocker
+type ThreadLocking int
+
+const (
+	// ThreadLockingNone 不加锁
+	ThreadLockingNone ThreadLocking = iota
+	// ThreadLockingFail 线程正在执行时立即返回 flowcontract.ErrThreadBusy
+	ThreadLockingFail
+	// ThreadLockingWait 线程正在执行时排队等待，直到上一个执行结束或 ctx 结束
+	ThreadLockingWait
+)
+
+// threadLockKey 执行持有的线程锁在 ctx 中的 key
+type threadLockKey struct{}
+
+// lockThread 按配置获取线程锁，返回的 ctx 在锁丢失时被取消，并携带线程锁使检查点在锁丢失后不再写入，release 释放锁
+func (f *Flow) lockThread(ctx context.Context, threadID string) (context.Context, func(), error) {
+	if f.threadLocking == ThreadLockingNone {
+		return ctx, func() {}, nil
+	}
+
+	locker, ok := f.checkpointer.(flowcontract.ThreadLocker)
+	if !ok {
+		return nil, nil, xerror.New("checkpointer does not support thread locking")
+	}
+
+	lock, err := locker.LockThread(ctx, threadID, f.threadLocking == ThreadLockingWait)
+	if err != nil {
+		return nil, nil, xerror.Wrap(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, threadLockKey{}, lock))
+	go func() {
+		select {
+		case <-lock.Lost():
+			f.logger.Errorf(ctx, "thread lock lost %s, canceling execution", threadID)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	release := func() {
+		cancel()
+		if err := lock.Unlock(context.WithoutCancel(ctx)); err != nil {
+			f.logger.Errorf(ctx, "failed to unlock thread %s: %s", threadID, err)
+		}
+	}
+
+	return ctx, release, nil
+}
+
+// UpdateThread 按配置获取线程锁后执行 fn，用于在执行之外读取并修改线程的检查点（例如创建线程、手动更新状态），
+// 修改不会和该线程上的执行交错，也不会被执行下一次保存的检查点覆盖。fn 收到的 ctx 在锁丢失时被取消
+func (f *Flow) UpdateThread(ctx context.Context, threadID string, fn func(ctx context.Context) error) error {
+	ctx, release, err := f.lockThread(ctx, threadID)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return fn(ctx)
+}
+
+// lockedCheckpointer 在锁丢失后拒绝写入检查点。
+// 执行失败和结束时的检查点使用不会被取消的 ctx 写入，需要单独检查锁，避免覆盖新的持有者写入的检查点
+type lockedCheckpointer struct {
+	flowcontract.Checkpointer
+	lock flowcontract.ThreadLock
+}
+
+// guardCheckpointer ctx 中携带线程锁时返回在锁丢失后拒绝写入的 Checkpointer
+func guardCheckpointer(ctx context.Context, checkpointer flowcontract.Checkpointer) flowcontract.Checkpointer {
+	lock, ok := ctx.Value(threadLockKey{}).(flowcontract.ThreadLock)
+	if !ok {
+		return checkpointer
+	}
+	return &lockedCheckpointer{Checkpointer: checkpointer, lock: lock}
+}
+
+func (c *lockedCheckpointer) Save(ctx context.Context, namespace string, current *state.State) (string, error) {
+	select {
+	case <-c.lock.Lost():
+		return "", xerror.Wrap(fmt.Errorf("%w: %s", flowcontract.ErrThreadLockLost, namespace))
+	default:
+	}
+	return c.Checkpointer.Save(ctx, namespace, current)
+}
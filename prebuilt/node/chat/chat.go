@@ -91,8 +91,9 @@ func (c *ChatNode) Run(ctx context.Context, currentState *state.State, streamFun
 		llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
 			if streamFunc != nil {
 				return streamFunc(ctx, &flowcontract.FlowStreamEvent{
-					FullState: currentState,
-					Chunk:     string(chunk),
+					Type:  flowcontract.EventLLMToken,
+					Node:  c.Name(),
+					Chunk: string(chunk),
 				})
 			}
 			return nil
@@ -28,6 +28,9 @@ const (
 	defaultMCPResult = "no result"
 )
 
+// MCPToolEvent 旧版本中以 JSON 形式放在 FlowStreamEvent.Chunk 中的工具调用事件
+//
+// Deprecated: 工具调用通过 flowcontract.EventToolCallStart 和 flowcontract.EventToolCallResult 事件发送
 type MCPToolEvent struct {
 	ToolName   string `json:"tool_name"`
 	ToolArgs   string `json:"tool_args"`
@@ -272,69 +275,31 @@ func (m *MCP) runParallel(
 							Content:    defaultMCPResult,
 						})
 					}
+					mu.Lock()
 					messages = append(messages, message)
+					mu.Unlock()
 				}()
 
-				toolEvent := MCPToolEvent{
-					ToolName: toolName,
-					ToolArgs: toolCallPart.FunctionCall.Arguments,
-					Status:   "processing",
-				}
-
 				m.logger.Infof(ctx, "calling mcp tool %s %s %s", mcpName, toolName, toolCallPart.FunctionCall.Arguments)
 
-				_ = streamFunc(ctx, &flowcontract.FlowStreamEvent{
-					FullState: currentState,
-					Chunk:     toolEvent.String(),
-				})
+				m.emitToolCall(ctx, streamFunc, flowcontract.EventToolCallStart, toolCallPart, "", nil)
 
 				// call mcp tool
-				request := mcp.CallToolRequest{}
-				request.Params.Name = toolName
-				arguments := make(map[string]interface{})
-				err := json.Unmarshal([]byte(toolCallPart.FunctionCall.Arguments), &arguments)
-				if err != nil {
-					mu.Lock()
-					if firstErr == nil {
-						firstErr = xerror.Wrap(err)
-					}
-					mu.Unlock()
-					return
-				}
-				request.Params.Arguments = arguments
-
-				response, err := mcpClient.CallTool(ctx, request)
+				result, err := m.callTool(ctx, mcpClient, toolName, toolCallPart.FunctionCall.Arguments)
+				m.emitToolCall(ctx, streamFunc, flowcontract.EventToolCallResult, toolCallPart, result, err)
 				if err != nil {
 					mu.Lock()
 					if firstErr == nil {
-						firstErr = xerror.Wrap(err)
+						firstErr = err
 					}
 					mu.Unlock()
 					return
 				}
 
-				jsonContent, err := json.Marshal(response.Content)
-				if err != nil {
-					mu.Lock()
-					if firstErr == nil {
-						firstErr = xerror.Wrap(err)
-					}
-					mu.Unlock()
-					return
-				}
-
-				toolEvent.ToolResult = string(jsonContent)
-				toolEvent.Status = "completed"
-
-				_ = streamFunc(ctx, &flowcontract.FlowStreamEvent{
-					FullState: currentState,
-					Chunk:     toolEvent.String(),
-				})
-
 				mu.Lock()
 				message.Parts = append(message.Parts, llms.ToolCallResponse{
 					ToolCallID: toolCallPart.ID,
-					Content:    string(jsonContent),
+					Content:    result,
 				})
 				mu.Unlock()
 			}(toolCallPart)
@@ -379,16 +344,7 @@ func (m *MCP) runSerial(
 				continue
 			}
 
-			toolEvent := MCPToolEvent{
-				ToolName: toolName,
-				ToolArgs: toolCallPart.FunctionCall.Arguments,
-				Status:   "processing",
-			}
-
-			_ = streamFunc(ctx, &flowcontract.FlowStreamEvent{
-				FullState: currentState,
-				Chunk:     toolEvent.String(),
-			})
+			m.emitToolCall(ctx, streamFunc, flowcontract.EventToolCallStart, toolCallPart, "", nil)
 
 			message := llms.MessageContent{
 				Role:  llms.ChatMessageTypeTool,
@@ -396,38 +352,17 @@ func (m *MCP) runSerial(
 			}
 
 			// call mcp tool
-			request := mcp.CallToolRequest{}
-			request.Params.Name = toolName
-			arguments := make(map[string]interface{})
-			err := json.Unmarshal([]byte(toolCallPart.FunctionCall.Arguments), &arguments)
+			result, err := m.callTool(ctx, mcpClient, toolName, toolCallPart.FunctionCall.Arguments)
+			m.emitToolCall(ctx, streamFunc, flowcontract.EventToolCallResult, toolCallPart, result, err)
 			if err != nil {
-				return xerror.Wrap(err)
+				return err
 			}
-			request.Params.Arguments = arguments
-
-			response, err := mcpClient.CallTool(ctx, request)
-			if err != nil {
-				return xerror.Wrap(err)
-			}
-
-			jsonContent, err := json.Marshal(response.Content)
-			if err != nil {
-				return xerror.Wrap(err)
-			}
-
-			toolEvent.ToolResult = string(jsonContent)
-			toolEvent.Status = "completed"
-
-			_ = streamFunc(ctx, &flowcontract.FlowStreamEvent{
-				FullState: currentState,
-				Chunk:     toolEvent.String(),
-			})
 
 			message.Parts = append(
 				message.Parts,
 				llms.ToolCallResponse{
 					ToolCallID: toolCallPart.ID,
-					Content:    string(jsonContent),
+					Content:    result,
 				},
 			)
 
@@ -440,6 +375,57 @@ func (m *MCP) runSerial(
 	return nil
 }
 
+// callTool 调用 MCP 工具，返回 JSON 编码的调用结果
+func (m *MCP) callTool(ctx context.Context, mcpClient client.MCPClient, toolName string, args string) (string, error) {
+	request := mcp.CallToolRequest{}
+	request.Params.Name = toolName
+	arguments := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(args), &arguments); err != nil {
+		return "", xerror.Wrap(err)
+	}
+	request.Params.Arguments = arguments
+
+	response, err := mcpClient.CallTool(ctx, request)
+	if err != nil {
+		return "", xerror.Wrap(err)
+	}
+
+	jsonContent, err := json.Marshal(response.Content)
+	if err != nil {
+		return "", xerror.Wrap(err)
+	}
+
+	return string(jsonContent), nil
+}
+
+// emitToolCall 发送工具调用开始或结束事件
+func (m *MCP) emitToolCall(
+	ctx context.Context,
+	streamFunc flowcontract.StreamFunc,
+	eventType flowcontract.EventType,
+	toolCallPart llms.ToolCall,
+	result string,
+	err error,
+) {
+	if streamFunc == nil {
+		return
+	}
+
+	if streamErr := streamFunc(ctx, &flowcontract.FlowStreamEvent{
+		Type: eventType,
+		Node: m.Name(),
+		ToolCall: &flowcontract.ToolCallEvent{
+			ID:        toolCallPart.ID,
+			Name:      toolCallPart.FunctionCall.Name,
+			Arguments: toolCallPart.FunctionCall.Arguments,
+			Result:    result,
+		},
+		Err: err,
+	}); streamErr != nil {
+		m.logger.Warnf(ctx, "streaming tool call event failed %s", streamErr)
+	}
+}
+
 func NewMCPTools(opts ...Option) (*MCP, error) {
 	defaultLogger, err := logger.NewLogger()
 	if err != nil {
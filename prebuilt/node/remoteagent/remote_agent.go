@@ -0,0 +1,303 @@
+package remoteagent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/logger"
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
+
+	"github.com/a2aproject/a2a-go/a2a"
+	"github.com/a2aproject/a2a-go/a2aclient"
+	"github.com/a2aproject/a2a-go/a2aclient/agentcard"
+	"github.com/tmc/langchaingo/llms"
+)
+
+const (
+	NodeName = "RemoteAgentNode"
+
+	// ContextIDKeyPrefix 加上节点名称作为 Metadata 的 key，保存远程 Agent 的 contextId，同一线程的多次调用在同一个 context 中继续
+	ContextIDKeyPrefix = "a2a_context_id:"
+	// TaskIDKeyPrefix 加上节点名称作为 Metadata 的 key，远程任务等待输入时保存任务 ID，下一次调用继续该任务
+	TaskIDKeyPrefix = "a2a_task_id:"
+)
+
+type Options struct {
+	Name   string
+	Logger logger.ILogger
+	// URL 远程 Agent 的地址，从 URL 下的 /.well-known/agent-card.json 获取 Agent Card
+	URL string
+	// Card 直接指定 Agent Card，设置后不再请求 URL
+	Card *a2a.AgentCard
+	// HTTPClient 请求远程 Agent 使用的 http.Client，默认没有超时，由 ctx 控制
+	HTTPClient *http.Client
+	// Streaming 为 true 时使用 message/stream，远程 Agent 输出的 artifact 以 LLM token 事件转发
+	Streaming bool
+}
+
+type Option func(*Options)
+
+func WithName(name string) Option {
+	return func(o *Options) {
+		o.Name = name
+	}
+}
+
+func WithLogger(logger logger.ILogger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+func WithURL(url string) Option {
+	return func(o *Options) {
+		o.URL = url
+	}
+}
+
+func WithAgentCard(card *a2a.AgentCard) Option {
+	return func(o *Options) {
+		o.Card = card
+	}
+}
+
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *Options) {
+		o.HTTPClient = client
+	}
+}
+
+func WithStreaming(enable bool) Option {
+	return func(o *Options) {
+		o.Streaming = enable
+	}
+}
+
+// RemoteAgentNode 通过 A2A 协议把最后一条用户消息发给远程 Agent，并把远程 Agent 的回复作为 AI 消息追加到 History。
+// 远程任务失败、被拒绝或取消时返回错误
+type RemoteAgentNode struct {
+	options *Options
+
+	mu     sync.Mutex
+	client *a2aclient.Client
+}
+
+func NewRemoteAgentNode(opts ...Option) (*RemoteAgentNode, error) {
+	defaultLogger, err := logger.NewLogger()
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+
+	options := &Options{
+		Name:       NodeName,
+		Logger:     defaultLogger,
+		HTTPClient: &http.Client{},
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if options.URL == "" && options.Card == nil {
+		return nil, xerror.New("remote agent url or agent card is required")
+	}
+
+	return &RemoteAgentNode{options: options}, nil
+}
+
+func (n *RemoteAgentNode) Name() string {
+	return n.options.Name
+}
+
+func (n *RemoteAgentNode) Run(ctx context.Context, currentState *state.State, streamFunc flowcontract.StreamFunc) error {
+	client, err := n.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	message := n.message(currentState)
+	params := &a2a.MessageSendParams{Message: message}
+
+	var result *taskResult
+	if n.options.Streaming {
+		result, err = n.stream(ctx, client, params, streamFunc)
+	} else {
+		result, err = n.send(ctx, client, params)
+	}
+	if err != nil {
+		return err
+	}
+
+	if currentState.Metadata == nil {
+		currentState.Metadata = make(map[string]interface{})
+	}
+	if result.contextID != "" {
+		currentState.Metadata[ContextIDKeyPrefix+n.Name()] = result.contextID
+	}
+	delete(currentState.Metadata, TaskIDKeyPrefix+n.Name())
+
+	switch result.state {
+	case a2a.TaskStateFailed, a2a.TaskStateRejected, a2a.TaskStateCanceled, a2a.TaskStateAuthRequired:
+		return xerror.New(fmt.Sprintf("remote agent task %s %s: %s", result.taskID, result.state, result.statusText))
+	case a2a.TaskStateInputRequired:
+		currentState.Metadata[TaskIDKeyPrefix+n.Name()] = string(result.taskID)
+	}
+
+	currentState.History = append(currentState.History, llms.TextParts(llms.ChatMessageTypeAI, result.reply()))
+	return nil
+}
+
+// getClient 第一次调用时获取 Agent Card 并创建客户端
+func (n *RemoteAgentNode) getClient(ctx context.Context) (*a2aclient.Client, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.client != nil {
+		return n.client, nil
+	}
+
+	card := n.options.Card
+	if card == nil {
+		resolved, err := agentcard.NewResolver(n.options.HTTPClient).Resolve(ctx, n.options.URL)
+		if err != nil {
+			return nil, xerror.Wrap(err)
+		}
+		card = resolved
+	}
+
+	client, err := a2aclient.NewFromCard(ctx, card, a2aclient.WithJSONRPCTransport(n.options.HTTPClient))
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+	n.client = client
+
+	return client, nil
+}
+
+// message 以最后一条用户消息构造请求，带上之前保存的 contextId 和等待输入的任务 ID
+func (n *RemoteAgentNode) message(currentState *state.State) *a2a.Message {
+	message := a2a.NewMessage(a2a.MessageRoleUser)
+	for i := len(currentState.History) - 1; i >= 0; i-- {
+		if currentState.History[i].Role != llms.ChatMessageTypeHuman {
+			continue
+		}
+		for _, part := range currentState.History[i].Parts {
+			switch p := part.(type) {
+			case llms.TextContent:
+				message.Parts = append(message.Parts, a2a.TextPart{Text: p.Text})
+			case llms.ImageURLContent:
+				message.Parts = append(message.Parts, a2a.FilePart{File: a2a.FileURI{URI: p.URL}})
+			}
+		}
+		break
+	}
+
+	if contextID, ok := currentState.Metadata[ContextIDKeyPrefix+n.Name()].(string); ok {
+		message.ContextID = contextID
+	}
+	if taskID, ok := currentState.Metadata[TaskIDKeyPrefix+n.Name()].(string); ok {
+		message.TaskID = a2a.TaskID(taskID)
+	}
+	return message
+}
+
+func (n *RemoteAgentNode) send(ctx context.Context, client *a2aclient.Client, params *a2a.MessageSendParams) (*taskResult, error) {
+	response, err := client.SendMessage(ctx, params)
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+
+	result := &taskResult{}
+	switch r := response.(type) {
+	case *a2a.Task:
+		result.applyTask(r)
+	case *a2a.Message:
+		result.applyMessage(r)
+	}
+	return result, nil
+}
+
+// stream 使用 message/stream 执行，artifact 中的文本以 LLM token 事件转发
+func (n *RemoteAgentNode) stream(ctx context.Context, client *a2aclient.Client, params *a2a.MessageSendParams, streamFunc flowcontract.StreamFunc) (*taskResult, error) {
+	result := &taskResult{}
+	for event, err := range client.SendStreamingMessage(ctx, params) {
+		if err != nil {
+			return nil, xerror.Wrap(err)
+		}
+
+		switch e := event.(type) {
+		case *a2a.Task:
+			result.applyTask(e)
+		case *a2a.Message:
+			result.applyMessage(e)
+		case *a2a.TaskStatusUpdateEvent:
+			result.taskID, result.contextID = e.TaskID, e.ContextID
+			result.state = e.Status.State
+			result.statusText = partsText(messageParts(e.Status.Message))
+		case *a2a.TaskArtifactUpdateEvent:
+			result.taskID, result.contextID = e.TaskID, e.ContextID
+			chunk := partsText(e.Artifact.Parts)
+			result.artifacts.WriteString(chunk)
+			if streamFunc != nil && chunk != "" {
+				if err := streamFunc(ctx, &flowcontract.FlowStreamEvent{Type: flowcontract.EventLLMToken, Chunk: chunk}); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// taskResult 远程调用的结果，回复优先使用 artifact 中的文本，没有 artifact 时使用状态消息
+type taskResult struct {
+	taskID     a2a.TaskID
+	contextID  string
+	state      a2a.TaskState
+	statusText string
+	artifacts  strings.Builder
+}
+
+func (r *taskResult) applyTask(task *a2a.Task) {
+	r.taskID, r.contextID = task.ID, task.ContextID
+	r.state = task.Status.State
+	r.statusText = partsText(messageParts(task.Status.Message))
+	r.artifacts.Reset()
+	for _, artifact := range task.Artifacts {
+		r.artifacts.WriteString(partsText(artifact.Parts))
+	}
+}
+
+func (r *taskResult) applyMessage(message *a2a.Message) {
+	r.taskID, r.contextID = message.TaskID, message.ContextID
+	r.state = a2a.TaskStateCompleted
+	r.statusText = partsText(message.Parts)
+}
+
+func (r *taskResult) reply() string {
+	if r.artifacts.Len() > 0 {
+		return r.artifacts.String()
+	}
+	return r.statusText
+}
+
+func messageParts(message *a2a.Message) a2a.ContentParts {
+	if message == nil {
+		return nil
+	}
+	return message.Parts
+}
+
+// partsText 返回所有文本 part 的拼接
+func partsText(parts a2a.ContentParts) string {
+	var builder strings.Builder
+	for _, part := range parts {
+		if text, ok := part.(a2a.TextPart); ok {
+			builder.WriteString(text.Text)
+		}
+	}
+	return builder.String()
+}
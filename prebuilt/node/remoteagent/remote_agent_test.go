@@ -0,0 +1,128 @@
+package remoteagent
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/futurxlab/golanggraph/checkpointer"
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/edge"
+	"github.com/futurxlab/golanggraph/flow"
+	"github.com/futurxlab/golanggraph/logger"
+	"github.com/futurxlab/golanggraph/server/a2aserver"
+	"github.com/futurxlab/golanggraph/state"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// countNode 以两个 token 回复线程中的消息数；消息为 fail 时返回错误
+type countNode struct{}
+
+func (n *countNode) Name() string {
+	return "count"
+}
+
+func (n *countNode) Run(ctx context.Context, currentState *state.State, streamFunc flowcontract.StreamFunc) error {
+	last := currentState.History[len(currentState.History)-1].Parts[0].(llms.TextContent).Text
+	if last == "fail" {
+		return errors.New("count failed")
+	}
+
+	reply := []string{"messages: ", string(rune('0' + len(currentState.History)))}
+	for _, chunk := range reply {
+		if err := streamFunc(ctx, &flowcontract.FlowStreamEvent{Type: flowcontract.EventLLMToken, Chunk: chunk}); err != nil {
+			return err
+		}
+	}
+	currentState.History = append(currentState.History, llms.TextParts(llms.ChatMessageTypeAI, reply[0]+reply[1]))
+	return nil
+}
+
+func newFlow(t *testing.T, name string, node flowcontract.Node) *flow.Flow {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := flow.NewFlowBuilder(logger).
+		SetName(name).
+		SetCheckpointer(checkpointer.NewInMemoryCheckpointer()).
+		AddNode(node).
+		AddEdge(edge.Edge{From: flow.StartNode, To: node.Name()}).
+		AddEdge(edge.Edge{From: node.Name(), To: flow.EndNode}).
+		Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestRemoteAgentNode(t *testing.T) {
+	remote, err := a2aserver.NewServer(newFlow(t, "counter", &countNode{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(remote)
+	defer httpServer.Close()
+
+	for _, streaming := range []bool{false, true} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		defer cancel()
+
+		node, err := NewRemoteAgentNode(WithURL(httpServer.URL), WithStreaming(streaming))
+		if err != nil {
+			t.Fatal(err)
+		}
+		local := newFlow(t, "local", node)
+
+		var tokens []string
+		streamFunc := func(ctx context.Context, event *flowcontract.FlowStreamEvent) error {
+			if event.Type == flowcontract.EventLLMToken {
+				tokens = append(tokens, event.Chunk)
+			}
+			return nil
+		}
+
+		input := state.State{History: []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "hi")}}
+		output, err := local.Exec(ctx, input, streamFunc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reply := output.History[len(output.History)-1]; reply.Role != llms.ChatMessageTypeAI || reply.Parts[0].(llms.TextContent).Text != "messages: 1" {
+			t.Fatalf("unexpected reply %+v", reply)
+		}
+		if _, ok := output.Metadata[ContextIDKeyPrefix+NodeName].(string); !ok {
+			t.Fatalf("expected context id in metadata, got %v", output.Metadata)
+		}
+		if streaming && (len(tokens) != 2 || tokens[0] != "messages: " || tokens[1] != "1") {
+			t.Fatalf("unexpected tokens %v", tokens)
+		}
+		if !streaming && len(tokens) != 0 {
+			t.Fatalf("unexpected tokens %v", tokens)
+		}
+
+		// 同一个 contextId 在远程线程上继续
+		output.History = append(output.History, llms.TextParts(llms.ChatMessageTypeHuman, "again"))
+		output, err = local.Exec(ctx, output, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text := output.History[len(output.History)-1].Parts[0].(llms.TextContent).Text; text != "messages: 3" {
+			t.Fatalf("expected remote thread to continue, got %q", text)
+		}
+
+		input = state.State{History: []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "fail")}}
+		if _, err := local.Exec(ctx, input, nil); err == nil {
+			t.Fatal("expected remote failure to fail the run")
+		}
+	}
+}
+
+func TestNewRemoteAgentNode(t *testing.T) {
+	if _, err := NewRemoteAgentNode(); err == nil {
+		t.Fatal("expected error without url or agent card")
+	}
+}
@@ -147,11 +147,16 @@ func (m *Tools) Run(ctx context.Context, currentState *state.State, streamFunc f
 				Metadata: currentState.Metadata,
 			}
 
+			m.emitToolCalls(ctx, streamFunc, flowcontract.EventToolCallStart, message, nil, nil)
+
 			if err := tool.Run(ctx, state, streamFunc); err != nil {
 				m.logger.Errorf(ctx, "tool run failed %s", err)
+				m.emitToolCalls(ctx, streamFunc, flowcontract.EventToolCallResult, message, nil, err)
 				return
 			}
 
+			m.emitToolCalls(ctx, streamFunc, flowcontract.EventToolCallResult, message, state.History, nil)
+
 			mutex.Lock()
 			currentState.Merge(state)
 			mutex.Unlock()
@@ -169,6 +174,50 @@ func (m *Tools) Run(ctx context.Context, currentState *state.State, streamFunc f
 	return nil
 }
 
+// emitToolCalls 为 message 中的每个工具调用发送事件，结果从 history 中按工具调用 ID 查找
+func (m *Tools) emitToolCalls(
+	ctx context.Context,
+	streamFunc flowcontract.StreamFunc,
+	eventType flowcontract.EventType,
+	message llms.MessageContent,
+	history []llms.MessageContent,
+	err error,
+) {
+	if streamFunc == nil {
+		return
+	}
+
+	results := make(map[string]string)
+	for _, content := range history {
+		for _, part := range content.Parts {
+			if response, ok := part.(llms.ToolCallResponse); ok {
+				results[response.ToolCallID] = response.Content
+			}
+		}
+	}
+
+	for _, part := range message.Parts {
+		toolCall, ok := part.(llms.ToolCall)
+		if !ok || toolCall.FunctionCall == nil {
+			continue
+		}
+
+		if streamErr := streamFunc(ctx, &flowcontract.FlowStreamEvent{
+			Type: eventType,
+			Node: m.Name(),
+			ToolCall: &flowcontract.ToolCallEvent{
+				ID:        toolCall.ID,
+				Name:      toolCall.FunctionCall.Name,
+				Arguments: toolCall.FunctionCall.Arguments,
+				Result:    results[toolCall.ID],
+			},
+			Err: err,
+		}); streamErr != nil {
+			m.logger.Warnf(ctx, "streaming tool call event failed %s", streamErr)
+		}
+	}
+}
+
 func NewTools(opts ...Option) (*Tools, error) {
 	defaultLogger, err := logger.NewLogger()
 	if err != nil {
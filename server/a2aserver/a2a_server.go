@@ -0,0 +1,169 @@
+package a2aserver
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/futurxlab/golanggraph/flow"
+	"github.com/futurxlab/golanggraph/logger"
+	"github.com/futurxlab/golanggraph/xerror"
+
+	"github.com/a2aproject/a2a-go/a2a"
+	"github.com/a2aproject/a2a-go/a2asrv"
+)
+
+const (
+	DefaultVersion = "1.0.0"
+
+	// ProtocolVersion 实现的 A2A 协议版本
+	ProtocolVersion = "0.3.0"
+)
+
+type Options struct {
+	Logger logger.ILogger
+	// Description Agent Card 中的描述，调用方依据它决定何时调用
+	Description string
+	// Version Agent 的版本，默认为 DefaultVersion
+	Version string
+	// URL JSON-RPC 接口的地址，为空时使用请求 Agent Card 的 Host 加根路径
+	URL string
+	// Skills Agent Card 中的技能，为空时以 Flow 为唯一的技能
+	Skills []a2a.AgentSkill
+	// HandlerOptions 创建 a2asrv.RequestHandler 的额外选项，例如 TaskStore
+	HandlerOptions []a2asrv.RequestHandlerOption
+}
+
+type Option func(*Options)
+
+func WithLogger(logger logger.ILogger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+func WithDescription(description string) Option {
+	return func(o *Options) {
+		o.Description = description
+	}
+}
+
+func WithVersion(version string) Option {
+	return func(o *Options) {
+		o.Version = version
+	}
+}
+
+func WithURL(url string) Option {
+	return func(o *Options) {
+		o.URL = url
+	}
+}
+
+func WithSkills(skills ...a2a.AgentSkill) Option {
+	return func(o *Options) {
+		o.Skills = append(o.Skills, skills...)
+	}
+}
+
+func WithHandlerOptions(opts ...a2asrv.RequestHandlerOption) Option {
+	return func(o *Options) {
+		o.HandlerOptions = append(o.HandlerOptions, opts...)
+	}
+}
+
+// Server 以 A2A 协议发布 Flow：a2asrv.WellKnownAgentCardPath 返回 Agent Card，其余请求作为 JSON-RPC 处理。
+// 每个 message/send 或 message/stream 以消息内容作为用户消息执行一次 Flow，A2A 的 contextId 作为线程 ID，
+// 同一 context 的任务在线程最新的状态上继续
+type Server struct {
+	options *Options
+	card    a2a.AgentCard
+	jsonrpc http.Handler
+}
+
+func NewServer(f *flow.Flow, opts ...Option) (*Server, error) {
+	defaultLogger, err := logger.NewLogger()
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+
+	options := &Options{
+		Logger:      defaultLogger,
+		Description: "Run the " + f.Name() + " agent",
+		Version:     DefaultVersion,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	skills := options.Skills
+	if len(skills) == 0 {
+		skills = []a2a.AgentSkill{{
+			ID:          f.Name(),
+			Name:        f.Name(),
+			Description: options.Description,
+			Tags:        []string{},
+		}}
+	}
+
+	executor := &flowExecutor{flow: f, logger: options.Logger}
+
+	return &Server{
+		options: options,
+		card: a2a.AgentCard{
+			Name:               f.Name(),
+			Description:        options.Description,
+			Version:            options.Version,
+			URL:                options.URL,
+			ProtocolVersion:    ProtocolVersion,
+			PreferredTransport: a2a.TransportProtocolJSONRPC,
+			Capabilities:       a2a.AgentCapabilities{Streaming: true},
+			DefaultInputModes:  []string{"text/plain"},
+			DefaultOutputModes: []string{"text/plain"},
+			Skills:             skills,
+		},
+		jsonrpc: a2asrv.NewJSONRPCHandler(a2asrv.NewHandler(executor, options.HandlerOptions...)),
+	}, nil
+}
+
+// Card 返回 Agent Card，没有配置 URL 时 URL 为空
+func (s *Server) Card() *a2a.AgentCard {
+	card := s.card
+	return &card
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == a2asrv.WellKnownAgentCardPath {
+		s.serveCard(w, r)
+		return
+	}
+	s.jsonrpc.ServeHTTP(w, r)
+}
+
+// serveCard 返回 Agent Card，Agent Card 是公开的，允许任意来源跨域读取
+func (s *Server) serveCard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case http.MethodGet:
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	card := s.card
+	if card.URL == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		card.URL = scheme + "://" + r.Host + "/"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&card); err != nil {
+		s.options.Logger.Warnf(r.Context(), "write agent card failed %s", err)
+	}
+}
@@ -0,0 +1,245 @@
+package a2aserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/futurxlab/golanggraph/checkpointer"
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/edge"
+	"github.com/futurxlab/golanggraph/flow"
+	"github.com/futurxlab/golanggraph/logger"
+	"github.com/futurxlab/golanggraph/state"
+
+	"github.com/a2aproject/a2a-go/a2a"
+	"github.com/a2aproject/a2a-go/a2aclient"
+	"github.com/a2aproject/a2a-go/a2aclient/agentcard"
+	"github.com/a2aproject/a2a-go/a2asrv"
+	"github.com/a2aproject/a2a-go/a2asrv/eventqueue"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// echoNode 回复最后一条用户消息，以两个 token 事件输出；消息为 fail 时返回错误
+type echoNode struct{}
+
+func (n *echoNode) Name() string {
+	return "echo"
+}
+
+func (n *echoNode) Run(ctx context.Context, currentState *state.State, streamFunc flowcontract.StreamFunc) error {
+	text := currentState.History[len(currentState.History)-1].Parts[0].(llms.TextContent).Text
+	if text == "fail" {
+		return errors.New("echo failed")
+	}
+
+	for _, chunk := range []string{"echo: ", text} {
+		if err := streamFunc(ctx, &flowcontract.FlowStreamEvent{Type: flowcontract.EventLLMToken, Chunk: chunk}); err != nil {
+			return err
+		}
+	}
+	currentState.History = append(currentState.History, llms.TextParts(llms.ChatMessageTypeAI, "echo: "+text))
+	return nil
+}
+
+func newTestClient(t *testing.T, ctx context.Context) (*a2aclient.Client, flowcontract.Checkpointer) {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	memory := checkpointer.NewInMemoryCheckpointer()
+	node := &echoNode{}
+	f, err := flow.NewFlowBuilder(logger).
+		SetName("echo-agent").
+		SetCheckpointer(memory).
+		AddNode(node).
+		AddEdge(edge.Edge{From: flow.StartNode, To: node.Name()}).
+		AddEdge(edge.Edge{From: node.Name(), To: flow.EndNode}).
+		Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewServer(f, WithLogger(logger), WithDescription("Echoes messages"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(s)
+	t.Cleanup(httpServer.Close)
+
+	card, err := agentcard.NewResolver(http.DefaultClient).Resolve(ctx, httpServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.Name != "echo-agent" || card.URL != httpServer.URL+"/" || !card.Capabilities.Streaming || len(card.Skills) != 1 {
+		t.Fatalf("unexpected agent card %+v", card)
+	}
+
+	client, err := a2aclient.NewFromCard(ctx, card)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, memory
+}
+
+func taskText(task *a2a.Task) (string, string) {
+	artifact := ""
+	for _, a := range task.Artifacts {
+		for _, part := range a.Parts {
+			artifact += part.(a2a.TextPart).Text
+		}
+	}
+	status := ""
+	if task.Status.Message != nil {
+		status = task.Status.Message.Parts[0].(a2a.TextPart).Text
+	}
+	return artifact, status
+}
+
+func TestSendMessage(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	client, memory := newTestClient(t, ctx)
+
+	result, err := client.SendMessage(ctx, &a2a.MessageSendParams{Message: a2a.NewMessage(a2a.MessageRoleUser, a2a.TextPart{Text: "hi"})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	task, ok := result.(*a2a.Task)
+	if !ok || task.Status.State != a2a.TaskStateCompleted {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if artifact, status := taskText(task); artifact != "echo: hi" || status != "echo: hi" {
+		t.Fatalf("unexpected task output %q %q", artifact, status)
+	}
+
+	// 同一个 context 在线程上继续
+	message := a2a.NewMessage(a2a.MessageRoleUser, a2a.TextPart{Text: "again"})
+	message.ContextID = task.ContextID
+	if _, err := client.SendMessage(ctx, &a2a.MessageSendParams{Message: message}); err != nil {
+		t.Fatal(err)
+	}
+	latest, err := memory.GetLastest(ctx, task.ContextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(latest.History) != 4 {
+		t.Fatalf("expected 4 messages in thread, got %d", len(latest.History))
+	}
+
+	result, err = client.SendMessage(ctx, &a2a.MessageSendParams{Message: a2a.NewMessage(a2a.MessageRoleUser, a2a.TextPart{Text: "fail"})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task := result.(*a2a.Task); task.Status.State != a2a.TaskStateFailed {
+		t.Fatalf("expected failed task, got %s", task.Status.State)
+	}
+}
+
+func TestSendStreamingMessage(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	client, _ := newTestClient(t, ctx)
+
+	var states []a2a.TaskState
+	var chunks []string
+	for event, err := range client.SendStreamingMessage(ctx, &a2a.MessageSendParams{Message: a2a.NewMessage(a2a.MessageRoleUser, a2a.TextPart{Text: "hi"})}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch e := event.(type) {
+		case *a2a.Task:
+			states = append(states, e.Status.State)
+		case *a2a.TaskStatusUpdateEvent:
+			states = append(states, e.Status.State)
+		case *a2a.TaskArtifactUpdateEvent:
+			chunks = append(chunks, e.Artifact.Parts[0].(a2a.TextPart).Text)
+		}
+	}
+
+	if len(states) < 2 || states[len(states)-2] != a2a.TaskStateWorking || states[len(states)-1] != a2a.TaskStateCompleted {
+		t.Fatalf("unexpected status updates %v", states)
+	}
+	if len(chunks) != 2 || chunks[0] != "echo: " || chunks[1] != "hi" {
+		t.Fatalf("unexpected artifact chunks %v", chunks)
+	}
+}
+
+// blockingNode 阻塞直到执行被取消
+type blockingNode struct {
+	started chan struct{}
+}
+
+func (n *blockingNode) Name() string {
+	return "blocking"
+}
+
+func (n *blockingNode) Run(ctx context.Context, currentState *state.State, streamFunc flowcontract.StreamFunc) error {
+	close(n.started)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestCancel(t *testing.T) {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := &blockingNode{started: make(chan struct{})}
+	f, err := flow.NewFlowBuilder(logger).
+		SetName("blocking-agent").
+		SetCheckpointer(checkpointer.NewInMemoryCheckpointer()).
+		AddNode(node).
+		AddEdge(edge.Edge{From: flow.StartNode, To: node.Name()}).
+		AddEdge(edge.Edge{From: node.Name(), To: flow.EndNode}).
+		Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	executor := &flowExecutor{flow: f, logger: logger}
+	queue := eventqueue.NewInMemoryQueue(16)
+	reqCtx := &a2asrv.RequestContext{
+		Message:   a2a.NewMessage(a2a.MessageRoleUser, a2a.TextPart{Text: "hi"}),
+		TaskID:    a2a.NewTaskID(),
+		ContextID: a2a.NewContextID(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- executor.Execute(ctx, reqCtx, queue)
+	}()
+
+	<-node.started
+	if err := executor.Cancel(context.Background(), reqCtx, queue); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// 取消状态之后不再有其他最终状态
+	var states []a2a.TaskState
+	for {
+		readCtx, readCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		event, err := queue.Read(readCtx)
+		readCancel()
+		if err != nil {
+			break
+		}
+		if update, ok := event.(*a2a.TaskStatusUpdateEvent); ok && update.Final {
+			states = append(states, update.Status.State)
+		}
+	}
+	if len(states) != 1 || states[0] != a2a.TaskStateCanceled {
+		t.Fatalf("expected only the canceled final status, got %v", states)
+	}
+}
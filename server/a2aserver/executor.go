@@ -0,0 +1,155 @@
+package a2aserver
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/flow"
+	"github.com/futurxlab/golanggraph/logger"
+	"github.com/futurxlab/golanggraph/server/internal/runutil"
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
+
+	"github.com/a2aproject/a2a-go/a2a"
+	"github.com/a2aproject/a2a-go/a2asrv"
+	"github.com/a2aproject/a2a-go/a2asrv/eventqueue"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// flowExecutor 实现 a2asrv.AgentExecutor。LLM token 作为同一个 artifact 的追加内容输出，
+// 节点没有输出 token 时在结束时以回复作为 artifact；结束状态的消息为执行新增的最后一条 AI 消息
+type flowExecutor struct {
+	flow   *flow.Flow
+	logger logger.ILogger
+}
+
+var _ a2asrv.AgentExecutor = (*flowExecutor)(nil)
+
+func (e *flowExecutor) Execute(ctx context.Context, reqCtx *a2asrv.RequestContext, queue eventqueue.Queue) error {
+	if reqCtx.StoredTask == nil {
+		if err := queue.Write(ctx, a2a.NewStatusUpdateEvent(reqCtx, a2a.TaskStateSubmitted, nil)); err != nil {
+			return xerror.Wrap(err)
+		}
+	}
+
+	message, err := toMessageContent(reqCtx.Message)
+	if err != nil {
+		return e.fail(ctx, reqCtx, queue, err)
+	}
+	// 在 contextId 对应线程最新的状态上追加请求消息，inputLen 为执行前 History 的长度
+	inputLen := 0
+	update := func(current *state.State) error {
+		current.History = append(current.History, message)
+		inputLen = len(current.History)
+		return nil
+	}
+
+	if err := queue.Write(ctx, a2a.NewStatusUpdateEvent(reqCtx, a2a.TaskStateWorking, nil)); err != nil {
+		return xerror.Wrap(err)
+	}
+
+	var artifactID a2a.ArtifactID
+	writeArtifact := func(text string) error {
+		var event *a2a.TaskArtifactUpdateEvent
+		if artifactID == "" {
+			event = a2a.NewArtifactEvent(reqCtx, a2a.TextPart{Text: text})
+			artifactID = event.Artifact.ID
+		} else {
+			event = a2a.NewArtifactUpdateEvent(reqCtx, artifactID, a2a.TextPart{Text: text})
+		}
+		return queue.Write(ctx, event)
+	}
+
+	reply := ""
+	for event, err := range e.flow.ContinueStream(ctx, reqCtx.ContextID, update, flow.WithStreamMode(flowcontract.StreamModeMessages)) {
+		if err != nil {
+			// 被取消时 Cancel 已经写入最终的取消状态，不能再写入失败状态
+			if ctx.Err() != nil {
+				e.logger.Infof(ctx, "a2a task %s canceled", reqCtx.TaskID)
+				return nil
+			}
+			return e.fail(ctx, reqCtx, queue, err)
+		}
+
+		switch event.Type {
+		case flowcontract.EventLLMToken:
+			if event.Chunk == "" {
+				continue
+			}
+			if err := writeArtifact(event.Chunk); err != nil {
+				return xerror.Wrap(err)
+			}
+		case flowcontract.EventRunEnd:
+			if event.FullState == nil {
+				continue
+			}
+			reply = runutil.Reply(event.FullState.History, inputLen)
+			if artifactID == "" && reply != "" {
+				if err := writeArtifact(reply); err != nil {
+					return xerror.Wrap(err)
+				}
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	completed := a2a.NewStatusUpdateEvent(reqCtx, a2a.TaskStateCompleted, a2a.NewMessageForTask(a2a.MessageRoleAgent, reqCtx, a2a.TextPart{Text: reply}))
+	completed.Final = true
+	if err := queue.Write(ctx, completed); err != nil {
+		return xerror.Wrap(err)
+	}
+	return nil
+}
+
+// Cancel 写入取消状态，a2asrv 处理后取消正在进行的执行
+func (e *flowExecutor) Cancel(ctx context.Context, reqCtx *a2asrv.RequestContext, queue eventqueue.Queue) error {
+	canceled := a2a.NewStatusUpdateEvent(reqCtx, a2a.TaskStateCanceled, nil)
+	canceled.Final = true
+	if err := queue.Write(ctx, canceled); err != nil {
+		return xerror.Wrap(err)
+	}
+	return nil
+}
+
+// fail 把执行失败作为任务的失败状态返回给调用方
+func (e *flowExecutor) fail(ctx context.Context, reqCtx *a2asrv.RequestContext, queue eventqueue.Queue, err error) error {
+	e.logger.Errorf(ctx, "a2a task %s failed %s", reqCtx.TaskID, err)
+
+	failed := a2a.NewStatusUpdateEvent(reqCtx, a2a.TaskStateFailed, a2a.NewMessageForTask(a2a.MessageRoleAgent, reqCtx, a2a.TextPart{Text: runutil.ErrorMessage(err)}))
+	failed.Final = true
+	if err := queue.Write(ctx, failed); err != nil {
+		return xerror.Wrap(err)
+	}
+	return nil
+}
+
+// toMessageContent 把 A2A 消息转换为用户消息：文本保持不变，结构化数据转为 JSON 文本，
+// 图片 URI 转为图片内容，其余文件被忽略
+func toMessageContent(message *a2a.Message) (llms.MessageContent, error) {
+	content := llms.MessageContent{Role: llms.ChatMessageTypeHuman}
+	if message == nil {
+		return content, nil
+	}
+
+	for _, part := range message.Parts {
+		switch p := part.(type) {
+		case a2a.TextPart:
+			content.Parts = append(content.Parts, llms.TextContent{Text: p.Text})
+		case a2a.DataPart:
+			data, err := json.Marshal(p.Data)
+			if err != nil {
+				return content, xerror.Wrap(err)
+			}
+			content.Parts = append(content.Parts, llms.TextContent{Text: string(data)})
+		case a2a.FilePart:
+			if uri, ok := p.File.(a2a.FileURI); ok && strings.HasPrefix(uri.MimeType, "image/") {
+				content.Parts = append(content.Parts, llms.ImageURLContent{URL: uri.URI})
+			}
+		}
+	}
+	return content, nil
+}
@@ -0,0 +1,225 @@
+package agui
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/server/internal/runutil"
+
+	"github.com/google/uuid"
+)
+
+// EventType AG-UI 事件的类型
+type EventType string
+
+const (
+	EventRunStarted         EventType = "RUN_STARTED"
+	EventRunFinished        EventType = "RUN_FINISHED"
+	EventRunError           EventType = "RUN_ERROR"
+	EventStepStarted        EventType = "STEP_STARTED"
+	EventStepFinished       EventType = "STEP_FINISHED"
+	EventTextMessageStart   EventType = "TEXT_MESSAGE_START"
+	EventTextMessageContent EventType = "TEXT_MESSAGE_CONTENT"
+	EventTextMessageEnd     EventType = "TEXT_MESSAGE_END"
+	EventToolCallStart      EventType = "TOOL_CALL_START"
+	EventToolCallArgs       EventType = "TOOL_CALL_ARGS"
+	EventToolCallEnd        EventType = "TOOL_CALL_END"
+	EventToolCallResult     EventType = "TOOL_CALL_RESULT"
+	EventStateSnapshot      EventType = "STATE_SNAPSHOT"
+	EventStateDelta         EventType = "STATE_DELTA"
+	EventCustom             EventType = "CUSTOM"
+)
+
+const (
+	roleSystem    = "system"
+	roleDeveloper = "developer"
+	roleUser      = "user"
+	roleAssistant = "assistant"
+	roleTool      = "tool"
+)
+
+// Event AG-UI 事件的 JSON 表示，只包含事件类型对应的字段。
+// 文本和工具参数事件的 Delta 为字符串，STATE_DELTA 的 Delta 为 JSON Patch 操作数组
+type Event struct {
+	Type            EventType   `json:"type"`
+	Timestamp       int64       `json:"timestamp,omitempty"`
+	ThreadID        string      `json:"threadId,omitempty"`
+	RunID           string      `json:"runId,omitempty"`
+	StepName        string      `json:"stepName,omitempty"`
+	MessageID       string      `json:"messageId,omitempty"`
+	Role            string      `json:"role,omitempty"`
+	Delta           interface{} `json:"delta,omitempty"`
+	ToolCallID      string      `json:"toolCallId,omitempty"`
+	ToolCallName    string      `json:"toolCallName,omitempty"`
+	ParentMessageID string      `json:"parentMessageId,omitempty"`
+	Content         string      `json:"content,omitempty"`
+	Snapshot        interface{} `json:"snapshot,omitempty"`
+	Message         string      `json:"message,omitempty"`
+	Name            string      `json:"name,omitempty"`
+	Value           interface{} `json:"value,omitempty"`
+}
+
+// PatchOperation RFC 6902 JSON Patch 操作
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// translator 把一次执行的流式事件转换为 AG-UI 事件。
+// 同一节点连续输出的 LLM token 属于同一条文本消息，节点结束、开始工具调用或其他节点输出 token 时结束该消息；
+// 共享状态为 State.Metadata，执行开始时输出快照，之后每个节点的修改以相对于客户端状态的 JSON Patch 输出
+type translator struct {
+	threadID string
+	runID    string
+	// snapshot 客户端当前的共享状态，输出 STATE_DELTA 时同步更新
+	snapshot map[string]interface{}
+
+	messageID   string
+	messageNode string
+}
+
+func newTranslator(threadID, runID string) *translator {
+	return &translator{threadID: threadID, runID: runID, snapshot: make(map[string]interface{})}
+}
+
+// setSnapshot 设置执行开始时的 Metadata，复制一份以免与节点的修改并发
+func (t *translator) setSnapshot(snapshot map[string]interface{}) {
+	t.snapshot = make(map[string]interface{}, len(snapshot))
+	for k, v := range snapshot {
+		t.snapshot[k] = v
+	}
+}
+
+func (t *translator) translate(event *flowcontract.FlowStreamEvent) []*Event {
+	// 客户端没有指定 runId 时使用执行的 RunID
+	if t.runID == "" {
+		t.runID = event.RunID
+	}
+	if t.threadID == "" {
+		t.threadID = event.ThreadID
+	}
+
+	var events []*Event
+	switch event.Type {
+	case flowcontract.EventRunStart:
+		snapshot := make(map[string]interface{}, len(t.snapshot))
+		for k, v := range t.snapshot {
+			snapshot[k] = v
+		}
+		events = append(events,
+			&Event{Type: EventRunStarted, ThreadID: t.threadID, RunID: t.runID},
+			&Event{Type: EventStateSnapshot, Snapshot: snapshot},
+		)
+	case flowcontract.EventNodeStart:
+		events = append(events, &Event{Type: EventStepStarted, StepName: event.Node})
+	case flowcontract.EventLLMToken:
+		if event.Chunk == "" {
+			break
+		}
+		if t.messageID != "" && t.messageNode != event.Node {
+			events = append(events, t.endMessage()...)
+		}
+		if t.messageID == "" {
+			t.messageID, t.messageNode = uuid.New().String(), event.Node
+			events = append(events, &Event{Type: EventTextMessageStart, MessageID: t.messageID, Role: roleAssistant})
+		}
+		events = append(events, &Event{Type: EventTextMessageContent, MessageID: t.messageID, Delta: event.Chunk})
+	case flowcontract.EventToolCallStart:
+		if event.ToolCall == nil {
+			break
+		}
+		events = append(events, t.endMessage()...)
+		events = append(events, &Event{Type: EventToolCallStart, ToolCallID: event.ToolCall.ID, ToolCallName: event.ToolCall.Name})
+		if event.ToolCall.Arguments != "" {
+			events = append(events, &Event{Type: EventToolCallArgs, ToolCallID: event.ToolCall.ID, Delta: event.ToolCall.Arguments})
+		}
+		events = append(events, &Event{Type: EventToolCallEnd, ToolCallID: event.ToolCall.ID})
+	case flowcontract.EventToolCallResult:
+		if event.ToolCall == nil {
+			break
+		}
+		content := event.ToolCall.Result
+		if event.Err != nil && content == "" {
+			content = runutil.ErrorMessage(event.Err)
+		}
+		events = append(events, &Event{
+			Type:       EventToolCallResult,
+			MessageID:  uuid.New().String(),
+			ToolCallID: event.ToolCall.ID,
+			Content:    content,
+			Role:       roleTool,
+		})
+	case flowcontract.EventNodeEnd:
+		if t.messageNode == event.Node {
+			events = append(events, t.endMessage()...)
+		}
+		if event.Update != nil {
+			if patch := t.metadataPatch(event.Update.Metadata); len(patch) > 0 {
+				events = append(events, &Event{Type: EventStateDelta, Delta: patch})
+			}
+		}
+		events = append(events, &Event{Type: EventStepFinished, StepName: event.Node})
+	case flowcontract.EventError:
+		if t.messageNode == event.Node {
+			events = append(events, t.endMessage()...)
+		}
+	case flowcontract.EventCustom:
+		events = append(events, &Event{Type: EventCustom, Name: event.Node, Value: event.Chunk})
+	case flowcontract.EventRunEnd:
+		events = append(events, t.endMessage()...)
+		if event.Err != nil {
+			events = append(events, &Event{Type: EventRunError, Message: runutil.ErrorMessage(event.Err)})
+		} else {
+			events = append(events, &Event{Type: EventRunFinished, ThreadID: t.threadID, RunID: t.runID})
+		}
+	}
+
+	now := time.Now().UnixMilli()
+	for _, e := range events {
+		e.Timestamp = now
+	}
+	return events
+}
+
+// endMessage 结束正在输出的文本消息
+func (t *translator) endMessage() []*Event {
+	if t.messageID == "" {
+		return nil
+	}
+	event := &Event{Type: EventTextMessageEnd, MessageID: t.messageID}
+	t.messageID, t.messageNode = "", ""
+	return []*Event{event}
+}
+
+// metadataPatch 把节点对 Metadata 的修改转换为 JSON Patch 并更新 snapshot，使其与客户端的状态一致。
+// 值为 nil 的 key 表示被删除，只有客户端已有的 key 才输出 remove；已有的 key 使用 replace
+func (t *translator) metadataPatch(update map[string]interface{}) []PatchOperation {
+	keys := make([]string, 0, len(update))
+	for key := range update {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	patch := make([]PatchOperation, 0, len(keys))
+	for _, key := range keys {
+		path := "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+		_, exists := t.snapshot[key]
+		switch {
+		case update[key] == nil && exists:
+			patch = append(patch, PatchOperation{Op: "remove", Path: path})
+			delete(t.snapshot, key)
+		case update[key] == nil:
+			// 客户端没有该 key，不需要删除
+		case exists:
+			patch = append(patch, PatchOperation{Op: "replace", Path: path, Value: update[key]})
+			t.snapshot[key] = update[key]
+		default:
+			patch = append(patch, PatchOperation{Op: "add", Path: path, Value: update[key]})
+			t.snapshot[key] = update[key]
+		}
+	}
+	return patch
+}
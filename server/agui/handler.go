@@ -0,0 +1,176 @@
+package agui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/flow"
+	"github.com/futurxlab/golanggraph/logger"
+	"github.com/futurxlab/golanggraph/server/internal/runutil"
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
+)
+
+// errBadRequest 请求参数错误
+var errBadRequest = errors.New("bad request")
+
+type Options struct {
+	Logger logger.ILogger
+	// StateKeys 请求中的 state 可以写入的 Metadata key，其余 key 被忽略，默认为空，即不接受客户端写入共享状态
+	StateKeys []string
+}
+
+type Option func(*Options)
+
+func WithLogger(logger logger.ILogger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithStateKeys 允许客户端通过 state 写入的 Metadata key，节点内部使用的 key 不应加入
+func WithStateKeys(keys ...string) Option {
+	return func(o *Options) {
+		o.StateKeys = append(o.StateKeys, keys...)
+	}
+}
+
+// Handler 以 AG-UI 协议执行 Flow：POST 请求体为 RunAgentInput，响应以 SSE 输出 AG-UI 事件。
+// threadId 作为线程 ID，在线程最新的状态上继续，线程已有历史时只追加最后一条 assistant 消息之后的新消息；
+// 节点对应 STEP 事件，LLM token 对应文本消息事件，工具调用对应 TOOL_CALL 事件，State.Metadata 作为共享状态
+type Handler struct {
+	flow    *flow.Flow
+	options *Options
+}
+
+func NewHandler(f *flow.Flow, opts ...Option) (*Handler, error) {
+	defaultLogger, err := logger.NewLogger()
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+
+	options := &Options{
+		Logger: defaultLogger,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &Handler{flow: f, options: options}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	request := &RunAgentInput{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		h.writeError(w, r, xerror.Wrap(fmt.Errorf("%w: %s", errBadRequest, err)))
+		return
+	}
+
+	translator := newTranslator(request.ThreadID, request.RunID)
+	update, err := h.update(request, translator)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.stream(w, r, request.ThreadID, update, translator)
+}
+
+// update 返回由 Flow.Continue 在线程最新的状态上写入请求的函数：线程已有历史时只追加新一轮的消息，
+// 请求中的 state 覆盖 Metadata 中 StateKeys 允许的字段，写入后的 Metadata 作为 translator 的初始快照
+func (h *Handler) update(request *RunAgentInput, translator *translator) (func(current *state.State) error, error) {
+	shared := make(map[string]interface{})
+	if len(request.State) > 0 && string(request.State) != "null" {
+		if err := json.Unmarshal(request.State, &shared); err != nil {
+			return nil, xerror.Wrap(fmt.Errorf("%w: state must be an object: %s", errBadRequest, err))
+		}
+	}
+	// 客户端通常回传完整的快照，不允许的 key 直接忽略而不是拒绝请求
+	for k := range shared {
+		if !slices.Contains(h.options.StateKeys, k) {
+			delete(shared, k)
+		}
+	}
+
+	return func(current *state.State) error {
+		messages := request.Messages
+		if len(current.History) > 0 {
+			messages = newTurn(messages)
+		}
+		for i := range messages {
+			message, err := messages[i].toMessageContent()
+			if err != nil {
+				return err
+			}
+			current.History = append(current.History, message)
+		}
+
+		for k, v := range shared {
+			current.Metadata[k] = v
+		}
+		translator.setSnapshot(current.Metadata)
+		return nil
+	}, nil
+}
+
+// stream 以 debug 模式执行并输出转换后的事件，第一个事件到达后才写入响应头，
+// 执行开始前的错误（例如线程被占用）仍以错误状态码返回
+func (h *Handler) stream(w http.ResponseWriter, r *http.Request, threadID string, update func(current *state.State) error, translator *translator) {
+	var writer *sseWriter
+	ended := false
+	for event, err := range h.flow.ContinueStream(r.Context(), threadID, update, flow.WithStreamMode(flowcontract.StreamModeDebug)) {
+		if err != nil {
+			if writer == nil {
+				h.writeError(w, r, xerror.Wrap(err))
+				return
+			}
+			h.options.Logger.Errorf(r.Context(), "agui run failed %s", err)
+			// 执行本身的错误已经以 RUN_ERROR 输出
+			if !ended {
+				if err := writer.write(&Event{Type: EventRunError, Message: runutil.ErrorMessage(err)}); err != nil {
+					h.options.Logger.Warnf(r.Context(), "write agui event failed %s", err)
+				}
+			}
+			return
+		}
+
+		if writer == nil {
+			writer = newSSEWriter(w)
+		}
+		if event.Type == flowcontract.EventRunEnd {
+			ended = true
+		}
+
+		for _, e := range translator.translate(event) {
+			if err := writer.write(e); err != nil {
+				// 停止迭代会取消执行
+				h.options.Logger.Warnf(r.Context(), "write agui event failed %s", err)
+				return
+			}
+		}
+	}
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, errBadRequest):
+		status = http.StatusBadRequest
+	case errors.Is(err, flowcontract.ErrThreadBusy):
+		status = http.StatusConflict
+	default:
+		h.options.Logger.Errorf(r.Context(), "agui run failed %s", err)
+	}
+
+	http.Error(w, runutil.ErrorMessage(err), status)
+}
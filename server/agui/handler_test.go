@@ -0,0 +1,214 @@
+package agui
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/futurxlab/golanggraph/checkpointer"
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/edge"
+	"github.com/futurxlab/golanggraph/flow"
+	"github.com/futurxlab/golanggraph/logger"
+	"github.com/futurxlab/golanggraph/server/internal/runutil"
+	"github.com/futurxlab/golanggraph/state"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// agentNode 输出两个 token，调用一次工具并修改 Metadata；消息为 fail 时返回错误
+type agentNode struct{}
+
+func (n *agentNode) Name() string {
+	return "agent"
+}
+
+func (n *agentNode) Run(ctx context.Context, currentState *state.State, streamFunc flowcontract.StreamFunc) error {
+	last := runutil.TextOf(currentState.History[len(currentState.History)-1])
+	if last == "fail" {
+		return errors.New("agent failed")
+	}
+
+	for _, chunk := range []string{"looking ", "up"} {
+		if err := streamFunc(ctx, &flowcontract.FlowStreamEvent{Type: flowcontract.EventLLMToken, Chunk: chunk}); err != nil {
+			return err
+		}
+	}
+	toolCall := &flowcontract.ToolCallEvent{ID: "call-1", Name: "search", Arguments: `{"q":"go"}`}
+	if err := streamFunc(ctx, &flowcontract.FlowStreamEvent{Type: flowcontract.EventToolCallStart, ToolCall: toolCall}); err != nil {
+		return err
+	}
+	result := *toolCall
+	result.Result = "found"
+	if err := streamFunc(ctx, &flowcontract.FlowStreamEvent{Type: flowcontract.EventToolCallResult, ToolCall: &result}); err != nil {
+		return err
+	}
+
+	currentState.Metadata["turns"] = float64(len(currentState.History))
+	delete(currentState.Metadata, "draft")
+	currentState.History = append(currentState.History, llms.TextParts(llms.ChatMessageTypeAI, "looking up"))
+	return nil
+}
+
+func newTestHandler(t *testing.T) *Handler {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := &agentNode{}
+	f, err := flow.NewFlowBuilder(logger).
+		SetName("agent").
+		SetCheckpointer(checkpointer.NewInMemoryCheckpointer()).
+		AddNode(node).
+		AddEdge(edge.Edge{From: flow.StartNode, To: node.Name()}).
+		AddEdge(edge.Edge{From: node.Name(), To: flow.EndNode}).
+		Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewHandler(f, WithLogger(logger), WithStateKeys("draft"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func run(t *testing.T, h *Handler, body string) (int, []map[string]interface{}) {
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	var events []map[string]interface{}
+	scanner := bufio.NewScanner(recorder.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		event := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	return recorder.Code, events
+}
+
+func eventTypes(events []map[string]interface{}) []string {
+	types := make([]string, 0, len(events))
+	for _, event := range events {
+		types = append(types, event["type"].(string))
+	}
+	return types
+}
+
+func TestHandler(t *testing.T) {
+	h := newTestHandler(t)
+
+	code, events := run(t, h, `{
+		"threadId": "t1",
+		"runId": "r1",
+		"state": {"draft": "x", "a2a_context_id:t1": "forged"},
+		"messages": [{"id": "m1", "role": "user", "content": "hi"}]
+	}`)
+	if code != http.StatusOK {
+		t.Fatalf("unexpected status %d", code)
+	}
+
+	expected := []string{
+		"RUN_STARTED", "STATE_SNAPSHOT", "STEP_STARTED",
+		"TEXT_MESSAGE_START", "TEXT_MESSAGE_CONTENT", "TEXT_MESSAGE_CONTENT", "TEXT_MESSAGE_END",
+		"TOOL_CALL_START", "TOOL_CALL_ARGS", "TOOL_CALL_END", "TOOL_CALL_RESULT",
+		"STATE_DELTA", "STEP_FINISHED", "RUN_FINISHED",
+	}
+	if types := eventTypes(events); !reflect.DeepEqual(types, expected) {
+		t.Fatalf("unexpected events %v", types)
+	}
+
+	if events[0]["threadId"] != "t1" || events[0]["runId"] != "r1" {
+		t.Fatalf("unexpected run started %v", events[0])
+	}
+	// 不在 StateKeys 中的 key 被忽略
+	if !reflect.DeepEqual(events[1]["snapshot"], map[string]interface{}{"draft": "x"}) {
+		t.Fatalf("unexpected snapshot %v", events[1])
+	}
+	if events[4]["delta"] != "looking " || events[4]["messageId"] != events[3]["messageId"] || events[3]["role"] != "assistant" {
+		t.Fatalf("unexpected text message %v %v", events[3], events[4])
+	}
+	if events[7]["toolCallId"] != "call-1" || events[7]["toolCallName"] != "search" || events[8]["delta"] != `{"q":"go"}` {
+		t.Fatalf("unexpected tool call %v %v", events[7], events[8])
+	}
+	if events[10]["content"] != "found" || events[10]["role"] != "tool" {
+		t.Fatalf("unexpected tool call result %v", events[10])
+	}
+	patch := []interface{}{
+		map[string]interface{}{"op": "remove", "path": "/draft"},
+		map[string]interface{}{"op": "add", "path": "/turns", "value": float64(1)},
+	}
+	if !reflect.DeepEqual(events[11]["delta"], patch) {
+		t.Fatalf("unexpected state delta %v", events[11]["delta"])
+	}
+
+	// 客户端发送完整的消息列表，线程中只追加新的一轮
+	_, events = run(t, h, `{
+		"threadId": "t1",
+		"messages": [
+			{"id": "m1", "role": "user", "content": "hi"},
+			{"id": "m2", "role": "assistant", "content": "looking up"},
+			{"id": "m3", "role": "user", "content": [{"type": "text", "text": "again"}]}
+		]
+	}`)
+	for _, event := range events {
+		if event["type"] == "STATE_DELTA" {
+			if value := event["delta"].([]interface{})[0].(map[string]interface{})["value"]; value != float64(3) {
+				t.Fatalf("expected thread to continue with 3 messages, got %v", value)
+			}
+		}
+	}
+	if runID, _ := events[0]["runId"].(string); runID == "" || events[len(events)-1]["type"] != "RUN_FINISHED" {
+		t.Fatalf("unexpected events %v", eventTypes(events))
+	}
+
+	_, events = run(t, h, `{"threadId": "t2", "messages": [{"id": "m1", "role": "user", "content": "fail"}]}`)
+	last := events[len(events)-1]
+	if last["type"] != "RUN_ERROR" || last["message"] != "agent failed" {
+		t.Fatalf("unexpected last event %v", last)
+	}
+
+	if code, _ := run(t, h, `{"threadId": "t3", "messages": [{"id": "m1", "role": "robot", "content": "hi"}]}`); code != http.StatusBadRequest {
+		t.Fatalf("expected bad request, got %d", code)
+	}
+	if code, _ := run(t, h, `{"threadId": "t3", "state": [1], "messages": []}`); code != http.StatusBadRequest {
+		t.Fatalf("expected bad request, got %d", code)
+	}
+}
+
+func TestMetadataPatch(t *testing.T) {
+	translator := newTranslator("t1", "r1")
+	translator.setSnapshot(map[string]interface{}{"c~d": "x", "e": 1})
+
+	// 客户端没有的 key 被设置为 nil 时不输出 remove
+	patch := translator.metadataPatch(map[string]interface{}{"a/b": 1, "c~d": nil, "e": 2, "new": nil})
+	expected := []PatchOperation{
+		{Op: "add", Path: "/a~1b", Value: 1},
+		{Op: "remove", Path: "/c~0d"},
+		{Op: "replace", Path: "/e", Value: 2},
+	}
+	if !reflect.DeepEqual(patch, expected) {
+		t.Fatalf("unexpected patch %v", patch)
+	}
+
+	// 之后的修改基于更新后的快照
+	patch = translator.metadataPatch(map[string]interface{}{"a/b": 2, "c~d": nil})
+	expected = []PatchOperation{{Op: "replace", Path: "/a~1b", Value: 2}}
+	if !reflect.DeepEqual(patch, expected) {
+		t.Fatalf("unexpected patch %v", patch)
+	}
+}
@@ -0,0 +1,149 @@
+package agui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/futurxlab/golanggraph/server/internal/runutil"
+	"github.com/futurxlab/golanggraph/xerror"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// RunAgentInput AG-UI 客户端发起执行的请求体。Tools、Context 和 ForwardedProps 由 Flow 中的节点决定，被忽略
+type RunAgentInput struct {
+	ThreadID string    `json:"threadId"`
+	RunID    string    `json:"runId"`
+	Messages []Message `json:"messages"`
+	// State 前端的共享状态，为对象时其中 StateKeys 允许的字段覆盖线程 Metadata 中的同名字段
+	State          json.RawMessage `json:"state,omitempty"`
+	Tools          json.RawMessage `json:"tools,omitempty"`
+	Context        json.RawMessage `json:"context,omitempty"`
+	ForwardedProps json.RawMessage `json:"forwardedProps,omitempty"`
+}
+
+// Message AG-UI 格式的消息，用户消息的 Content 可以是字符串或 input content 数组
+type Message struct {
+	ID         string          `json:"id"`
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content,omitempty"`
+	Name       string          `json:"name,omitempty"`
+	ToolCalls  []ToolCall      `json:"toolCalls,omitempty"`
+	ToolCallID string          `json:"toolCallId,omitempty"`
+}
+
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function FunctionCall `json:"function"`
+}
+
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// inputContent content 数组中的元素，支持 text 和图片类型的 binary
+type inputContent struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Data     string `json:"data,omitempty"`
+}
+
+// toMessageContent 把 AG-UI 格式的消息转换为 History 中的消息
+func (m *Message) toMessageContent() (llms.MessageContent, error) {
+	switch m.Role {
+	case roleSystem, roleDeveloper:
+		parts, err := m.contentParts()
+		return llms.MessageContent{Role: llms.ChatMessageTypeSystem, Parts: parts}, err
+	case roleUser:
+		parts, err := m.contentParts()
+		return llms.MessageContent{Role: llms.ChatMessageTypeHuman, Parts: parts}, err
+	case roleAssistant:
+		parts, err := m.contentParts()
+		if err != nil {
+			return llms.MessageContent{}, err
+		}
+		for _, call := range m.ToolCalls {
+			parts = append(parts, llms.ToolCall{
+				ID:   call.ID,
+				Type: call.Type,
+				FunctionCall: &llms.FunctionCall{
+					Name:      call.Function.Name,
+					Arguments: call.Function.Arguments,
+				},
+			})
+		}
+		return llms.MessageContent{Role: llms.ChatMessageTypeAI, Parts: parts}, nil
+	case roleTool:
+		parts, err := m.contentParts()
+		if err != nil {
+			return llms.MessageContent{}, err
+		}
+		return llms.MessageContent{
+			Role: llms.ChatMessageTypeTool,
+			Parts: []llms.ContentPart{llms.ToolCallResponse{
+				ToolCallID: m.ToolCallID,
+				Name:       m.Name,
+				Content:    runutil.TextOf(llms.MessageContent{Parts: parts}),
+			}},
+		}, nil
+	}
+
+	return llms.MessageContent{}, xerror.Wrap(fmt.Errorf("%w: unsupported role %q", errBadRequest, m.Role))
+}
+
+// contentParts 解析字符串或 input content 数组，null 或缺省时没有内容
+func (m *Message) contentParts() ([]llms.ContentPart, error) {
+	if len(m.Content) == 0 || string(m.Content) == "null" {
+		return nil, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(m.Content, &text); err == nil {
+		return []llms.ContentPart{llms.TextContent{Text: text}}, nil
+	}
+
+	var items []inputContent
+	if err := json.Unmarshal(m.Content, &items); err != nil {
+		return nil, xerror.Wrap(fmt.Errorf("%w: invalid content: %s", errBadRequest, err))
+	}
+
+	parts := make([]llms.ContentPart, 0, len(items))
+	for _, item := range items {
+		switch {
+		case item.Type == "text":
+			parts = append(parts, llms.TextContent{Text: item.Text})
+		case item.Type == "binary" && strings.HasPrefix(item.MimeType, "image/") && item.URL != "":
+			parts = append(parts, llms.ImageURLContent{URL: item.URL})
+		case item.Type == "binary" && strings.HasPrefix(item.MimeType, "image/") && item.Data != "":
+			parts = append(parts, llms.ImageURLContent{URL: "data:" + item.MimeType + ";base64," + item.Data})
+		default:
+			return nil, xerror.Wrap(fmt.Errorf("%w: unsupported content type %q %q", errBadRequest, item.Type, item.MimeType))
+		}
+	}
+	return parts, nil
+}
+
+// newTurn 返回最后一条 assistant 消息之后的非 system 消息，即线程中还没有的新一轮输入
+func newTurn(messages []Message) []Message {
+	start := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == roleAssistant {
+			start = i + 1
+			break
+		}
+	}
+
+	turn := make([]Message, 0, len(messages)-start)
+	for _, message := range messages[start:] {
+		if message.Role == roleSystem || message.Role == roleDeveloper {
+			continue
+		}
+		turn = append(turn, message)
+	}
+	return turn
+}
@@ -0,0 +1,41 @@
+package agui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/futurxlab/golanggraph/xerror"
+)
+
+// sseWriter 以 AG-UI 的 SSE 编码写入事件，每个事件为一行 data
+type sseWriter struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+}
+
+func newSSEWriter(w http.ResponseWriter) *sseWriter {
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	return &sseWriter{w: w, controller: http.NewResponseController(w)}
+}
+
+func (s *sseWriter) write(event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return xerror.Wrap(err)
+	}
+
+	if _, err := fmt.Fprintf(s.w, "data: %s\n\n", data); err != nil {
+		return xerror.Wrap(err)
+	}
+	if err := s.controller.Flush(); err != nil {
+		return xerror.Wrap(err)
+	}
+	return nil
+}
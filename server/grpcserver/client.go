@@ -0,0 +1,177 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/server/grpcserver/flowpb"
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
+
+	"google.golang.org/grpc"
+)
+
+// Client 以 Flow 的调用方式执行远程注册的 Flow。
+// 执行时 input 的线程 ID 指定线程，History 为追加到线程的新消息，Metadata 合并到线程的 Metadata；
+// 错误保留 gRPC 状态，可以用 status.Code 判断
+type Client struct {
+	client flowpb.FlowServiceClient
+}
+
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{client: flowpb.NewFlowServiceClient(conn)}
+}
+
+func (c *Client) ListFlows(ctx context.Context) ([]string, error) {
+	response, err := c.client.ListFlows(ctx, &flowpb.ListFlowsRequest{})
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+	return response.GetFlows(), nil
+}
+
+// Exec 执行并返回执行结束时的状态
+func (c *Client) Exec(ctx context.Context, flowName string, input state.State) (state.State, error) {
+	request, err := runRequest(flowName, &input, nil)
+	if err != nil {
+		return state.State{}, err
+	}
+
+	response, err := c.client.Run(ctx, request)
+	if err != nil {
+		return state.State{}, xerror.Wrap(err)
+	}
+	return decodeResult(response)
+}
+
+// Stream 执行并迭代流式事件，与 Flow.Stream 一致：执行失败时最后返回一次错误，停止迭代会取消远程执行
+func (c *Client) Stream(ctx context.Context, flowName string, input state.State, modes ...flowcontract.StreamMode) iter.Seq2[*flowcontract.FlowStreamEvent, error] {
+	return func(yield func(*flowcontract.FlowStreamEvent, error) bool) {
+		request, err := runRequest(flowName, &input, modes)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		stream, err := c.client.StreamRun(ctx, request)
+		if err != nil {
+			yield(nil, xerror.Wrap(err))
+			return
+		}
+		receive(stream, yield)
+	}
+}
+
+// Resume 从线程最新的检查点恢复执行
+func (c *Client) Resume(ctx context.Context, flowName string, threadID string) (state.State, error) {
+	response, err := c.client.Resume(ctx, &flowpb.ResumeRequest{Flow: flowName, ThreadId: threadID})
+	if err != nil {
+		return state.State{}, xerror.Wrap(err)
+	}
+	return decodeResult(response)
+}
+
+// ResumeStream 从线程最新的检查点恢复执行并迭代流式事件
+func (c *Client) ResumeStream(ctx context.Context, flowName string, threadID string, modes ...flowcontract.StreamMode) iter.Seq2[*flowcontract.FlowStreamEvent, error] {
+	return func(yield func(*flowcontract.FlowStreamEvent, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		stream, err := c.client.StreamResume(ctx, &flowpb.ResumeRequest{
+			Flow:        flowName,
+			ThreadId:    threadID,
+			StreamModes: modeNames(modes),
+		})
+		if err != nil {
+			yield(nil, xerror.Wrap(err))
+			return
+		}
+		receive(stream, yield)
+	}
+}
+
+// GetState 返回线程的状态和检查点 ID，checkpointID 为空时返回最新的检查点，
+// 服务端的 Checkpointer 不支持 CheckpointLister 时最新检查点的 ID 为空
+func (c *Client) GetState(ctx context.Context, flowName string, threadID string, checkpointID string) (*state.State, string, error) {
+	response, err := c.client.GetState(ctx, &flowpb.GetStateRequest{
+		Flow:         flowName,
+		ThreadId:     threadID,
+		CheckpointId: checkpointID,
+	})
+	if err != nil {
+		return nil, "", xerror.Wrap(err)
+	}
+
+	s, err := DecodeState(response.GetState())
+	if err != nil {
+		return nil, "", err
+	}
+	return s, response.GetCheckpointId(), nil
+}
+
+func runRequest(flowName string, input *state.State, modes []flowcontract.StreamMode) (*flowpb.RunRequest, error) {
+	messages, err := EncodeMessages(input.History)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := EncodeMetadata(input.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return &flowpb.RunRequest{
+		Flow:        flowName,
+		ThreadId:    input.GetThreadID(),
+		Messages:    messages,
+		Metadata:    metadata,
+		StreamModes: modeNames(modes),
+	}, nil
+}
+
+func decodeResult(response *flowpb.RunResponse) (state.State, error) {
+	result, err := DecodeState(response.GetState())
+	if err != nil {
+		return state.State{}, err
+	}
+	if result == nil {
+		return state.State{}, nil
+	}
+	return *result, nil
+}
+
+// receive 读取事件直到流结束，流以错误状态结束时返回该错误
+func receive(stream grpc.ServerStreamingClient[flowpb.Event], yield func(*flowcontract.FlowStreamEvent, error) bool) {
+	for {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			yield(nil, xerror.Wrap(err))
+			return
+		}
+
+		decoded, err := DecodeEvent(event)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if !yield(decoded, nil) {
+			return
+		}
+	}
+}
+
+func modeNames(modes []flowcontract.StreamMode) []string {
+	names := make([]string, 0, len(modes))
+	for _, mode := range modes {
+		names = append(names, string(mode))
+	}
+	return names
+}
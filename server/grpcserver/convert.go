@@ -0,0 +1,292 @@
+package grpcserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/server/grpcserver/flowpb"
+	"github.com/futurxlab/golanggraph/server/internal/runutil"
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
+
+	"github.com/tmc/langchaingo/llms"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// EncodeState 把状态转换为 protobuf 表示，包括下一步、待恢复和待合并的节点
+func EncodeState(s *state.State) (*flowpb.State, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	history, err := EncodeMessages(s.History)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := EncodeMetadata(s.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &flowpb.State{
+		ThreadId:  s.GetThreadID(),
+		Node:      s.GetNode(),
+		NextNodes: s.GetNextNodes(),
+		History:   history,
+		Metadata:  metadata,
+	}
+	for _, task := range s.GetPendingTasks() {
+		pending, err := EncodeState(task.State)
+		if err != nil {
+			return nil, err
+		}
+		result.PendingTasks = append(result.PendingTasks, &flowpb.PendingNode{Node: task.Node, State: pending})
+	}
+	for _, write := range s.GetPendingWrites() {
+		pending, err := EncodeState(write.State)
+		if err != nil {
+			return nil, err
+		}
+		result.PendingWrites = append(result.PendingWrites, &flowpb.PendingNode{Node: write.Node, State: pending})
+	}
+	return result, nil
+}
+
+// DecodeState 把 protobuf 表示还原为状态，nil 时返回 nil
+func DecodeState(s *flowpb.State) (*state.State, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	history, err := DecodeMessages(s.GetHistory())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &state.State{
+		History:  history,
+		Metadata: DecodeMetadata(s.GetMetadata()),
+	}
+	result.SetThreadID(s.GetThreadId())
+	result.SetNode(s.GetNode())
+	result.SetNextNodes(s.GetNextNodes())
+
+	var tasks []state.PendingTask
+	for _, task := range s.GetPendingTasks() {
+		pending, err := DecodeState(task.GetState())
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, state.PendingTask{Node: task.GetNode(), State: pending})
+	}
+	result.SetPendingTasks(tasks)
+
+	var writes []state.PendingWrite
+	for _, write := range s.GetPendingWrites() {
+		pending, err := DecodeState(write.GetState())
+		if err != nil {
+			return nil, err
+		}
+		writes = append(writes, state.PendingWrite{Node: write.GetNode(), State: pending})
+	}
+	result.SetPendingWrites(writes)
+
+	return result, nil
+}
+
+// EncodeMetadata 以 JSON 的形式转换 Metadata，值需要可以被 JSON 序列化，nil 值转换为 null
+func EncodeMetadata(metadata map[string]interface{}) (*structpb.Struct, error) {
+	if metadata == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+	result := &structpb.Struct{}
+	if err := result.UnmarshalJSON(data); err != nil {
+		return nil, xerror.Wrap(err)
+	}
+	return result, nil
+}
+
+// DecodeMetadata 还原 Metadata，数字统一为 float64，与 JSON 反序列化的结果一致
+func DecodeMetadata(metadata *structpb.Struct) map[string]interface{} {
+	if metadata == nil {
+		return nil
+	}
+	return metadata.AsMap()
+}
+
+func EncodeMessages(messages []llms.MessageContent) ([]*flowpb.Message, error) {
+	result := make([]*flowpb.Message, 0, len(messages))
+	for _, message := range messages {
+		encoded, err := EncodeMessage(message)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, encoded)
+	}
+	return result, nil
+}
+
+func DecodeMessages(messages []*flowpb.Message) ([]llms.MessageContent, error) {
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	result := make([]llms.MessageContent, 0, len(messages))
+	for _, message := range messages {
+		decoded, err := DecodeMessage(message)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, decoded)
+	}
+	return result, nil
+}
+
+// EncodeMessage 转换一条消息，不支持的 ContentPart 类型返回错误
+func EncodeMessage(message llms.MessageContent) (*flowpb.Message, error) {
+	result := &flowpb.Message{Role: string(message.Role)}
+	for _, part := range message.Parts {
+		encoded := &flowpb.Part{}
+		switch p := part.(type) {
+		case llms.TextContent:
+			encoded.Part = &flowpb.Part_Text{Text: &flowpb.TextPart{Text: p.Text}}
+		case llms.ImageURLContent:
+			encoded.Part = &flowpb.Part_ImageUrl{ImageUrl: &flowpb.ImageURLPart{Url: p.URL, Detail: p.Detail}}
+		case llms.BinaryContent:
+			encoded.Part = &flowpb.Part_Binary{Binary: &flowpb.BinaryPart{MimeType: p.MIMEType, Data: p.Data}}
+		case llms.ToolCall:
+			toolCall := &flowpb.ToolCallPart{Id: p.ID, Type: p.Type}
+			if p.FunctionCall != nil {
+				toolCall.Name, toolCall.Arguments = p.FunctionCall.Name, p.FunctionCall.Arguments
+			}
+			encoded.Part = &flowpb.Part_ToolCall{ToolCall: toolCall}
+		case llms.ToolCallResponse:
+			encoded.Part = &flowpb.Part_ToolCallResponse{ToolCallResponse: &flowpb.ToolCallResponsePart{
+				ToolCallId: p.ToolCallID,
+				Name:       p.Name,
+				Content:    p.Content,
+			}}
+		default:
+			return nil, xerror.New(fmt.Sprintf("unsupported content part %T", part))
+		}
+		result.Parts = append(result.Parts, encoded)
+	}
+	return result, nil
+}
+
+// DecodeMessage 还原一条消息，没有设置内容的 Part 返回错误
+func DecodeMessage(message *flowpb.Message) (llms.MessageContent, error) {
+	result := llms.MessageContent{Role: llms.ChatMessageType(message.GetRole())}
+	for _, part := range message.GetParts() {
+		switch p := part.GetPart().(type) {
+		case *flowpb.Part_Text:
+			result.Parts = append(result.Parts, llms.TextContent{Text: p.Text.GetText()})
+		case *flowpb.Part_ImageUrl:
+			result.Parts = append(result.Parts, llms.ImageURLContent{URL: p.ImageUrl.GetUrl(), Detail: p.ImageUrl.GetDetail()})
+		case *flowpb.Part_Binary:
+			result.Parts = append(result.Parts, llms.BinaryContent{MIMEType: p.Binary.GetMimeType(), Data: p.Binary.GetData()})
+		case *flowpb.Part_ToolCall:
+			result.Parts = append(result.Parts, llms.ToolCall{
+				ID:   p.ToolCall.GetId(),
+				Type: p.ToolCall.GetType(),
+				FunctionCall: &llms.FunctionCall{
+					Name:      p.ToolCall.GetName(),
+					Arguments: p.ToolCall.GetArguments(),
+				},
+			})
+		case *flowpb.Part_ToolCallResponse:
+			result.Parts = append(result.Parts, llms.ToolCallResponse{
+				ToolCallID: p.ToolCallResponse.GetToolCallId(),
+				Name:       p.ToolCallResponse.GetName(),
+				Content:    p.ToolCallResponse.GetContent(),
+			})
+		default:
+			return llms.MessageContent{}, xerror.Wrap(fmt.Errorf("%w: empty message part", errBadRequest))
+		}
+	}
+	return result, nil
+}
+
+// EncodeEvent 转换流式事件，Err 转换为去掉调用栈的错误信息
+func EncodeEvent(event *flowcontract.FlowStreamEvent) (*flowpb.Event, error) {
+	fullState, err := EncodeState(event.FullState)
+	if err != nil {
+		return nil, err
+	}
+	update, err := EncodeState(event.Update)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &flowpb.Event{
+		Type:         string(event.Type),
+		Node:         event.Node,
+		ThreadId:     event.ThreadID,
+		RunId:        event.RunID,
+		Chunk:        event.Chunk,
+		FullState:    fullState,
+		Update:       update,
+		NextNodes:    event.NextNodes,
+		CheckpointId: event.CheckpointID,
+	}
+	if event.Duration > 0 {
+		result.Duration = durationpb.New(event.Duration)
+	}
+	if event.ToolCall != nil {
+		result.ToolCall = &flowpb.ToolCall{
+			Id:        event.ToolCall.ID,
+			Name:      event.ToolCall.Name,
+			Arguments: event.ToolCall.Arguments,
+			Result:    event.ToolCall.Result,
+		}
+	}
+	if event.Err != nil {
+		result.Error = runutil.ErrorMessage(event.Err)
+	}
+	return result, nil
+}
+
+// DecodeEvent 还原流式事件，错误信息还原为 Err
+func DecodeEvent(event *flowpb.Event) (*flowcontract.FlowStreamEvent, error) {
+	fullState, err := DecodeState(event.GetFullState())
+	if err != nil {
+		return nil, err
+	}
+	update, err := DecodeState(event.GetUpdate())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &flowcontract.FlowStreamEvent{
+		Type:         flowcontract.EventType(event.GetType()),
+		Node:         event.GetNode(),
+		ThreadID:     event.GetThreadId(),
+		RunID:        event.GetRunId(),
+		Chunk:        event.GetChunk(),
+		FullState:    fullState,
+		Update:       update,
+		NextNodes:    event.GetNextNodes(),
+		Duration:     event.GetDuration().AsDuration(),
+		CheckpointID: event.GetCheckpointId(),
+	}
+	if toolCall := event.GetToolCall(); toolCall != nil {
+		result.ToolCall = &flowcontract.ToolCallEvent{
+			ID:        toolCall.GetId(),
+			Name:      toolCall.GetName(),
+			Arguments: toolCall.GetArguments(),
+			Result:    toolCall.GetResult(),
+		}
+	}
+	if event.GetError() != "" {
+		result.Err = errors.New(event.GetError())
+	}
+	return result, nil
+}
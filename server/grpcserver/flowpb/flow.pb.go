@@ -0,0 +1,1410 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: flow.proto
+
+package flowpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ListFlowsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFlowsRequest) Reset() {
+	*x = ListFlowsRequest{}
+	mi := &file_flow_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFlowsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFlowsRequest) ProtoMessage() {}
+
+func (x *ListFlowsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_flow_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFlowsRequest.ProtoReflect.Descriptor instead.
+func (*ListFlowsRequest) Descriptor() ([]byte, []int) {
+	return file_flow_proto_rawDescGZIP(), []int{0}
+}
+
+type ListFlowsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Flows         []string               `protobuf:"bytes,1,rep,name=flows,proto3" json:"flows,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFlowsResponse) Reset() {
+	*x = ListFlowsResponse{}
+	mi := &file_flow_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFlowsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFlowsResponse) ProtoMessage() {}
+
+func (x *ListFlowsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_flow_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFlowsResponse.ProtoReflect.Descriptor instead.
+func (*ListFlowsResponse) Descriptor() ([]byte, []int) {
+	return file_flow_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListFlowsResponse) GetFlows() []string {
+	if x != nil {
+		return x.Flows
+	}
+	return nil
+}
+
+// RunRequest 执行请求。messages 追加到线程的历史消息之后，metadata 合并到线程的 Metadata，值为 null 的 key 被删除；
+// thread_id 为空时创建新的线程
+type RunRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Flow     string                 `protobuf:"bytes,1,opt,name=flow,proto3" json:"flow,omitempty"`
+	ThreadId string                 `protobuf:"bytes,2,opt,name=thread_id,json=threadId,proto3" json:"thread_id,omitempty"`
+	Messages []*Message             `protobuf:"bytes,3,rep,name=messages,proto3" json:"messages,omitempty"`
+	Metadata *structpb.Struct       `protobuf:"bytes,4,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	// stream_modes 流式输出的内容：values、updates、messages 或 debug，为空时使用 Flow 的默认模式
+	StreamModes   []string `protobuf:"bytes,5,rep,name=stream_modes,json=streamModes,proto3" json:"stream_modes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunRequest) Reset() {
+	*x = RunRequest{}
+	mi := &file_flow_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunRequest) ProtoMessage() {}
+
+func (x *RunRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_flow_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunRequest.ProtoReflect.Descriptor instead.
+func (*RunRequest) Descriptor() ([]byte, []int) {
+	return file_flow_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RunRequest) GetFlow() string {
+	if x != nil {
+		return x.Flow
+	}
+	return ""
+}
+
+func (x *RunRequest) GetThreadId() string {
+	if x != nil {
+		return x.ThreadId
+	}
+	return ""
+}
+
+func (x *RunRequest) GetMessages() []*Message {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+func (x *RunRequest) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *RunRequest) GetStreamModes() []string {
+	if x != nil {
+		return x.StreamModes
+	}
+	return nil
+}
+
+type ResumeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Flow          string                 `protobuf:"bytes,1,opt,name=flow,proto3" json:"flow,omitempty"`
+	ThreadId      string                 `protobuf:"bytes,2,opt,name=thread_id,json=threadId,proto3" json:"thread_id,omitempty"`
+	StreamModes   []string               `protobuf:"bytes,3,rep,name=stream_modes,json=streamModes,proto3" json:"stream_modes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResumeRequest) Reset() {
+	*x = ResumeRequest{}
+	mi := &file_flow_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResumeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResumeRequest) ProtoMessage() {}
+
+func (x *ResumeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_flow_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResumeRequest.ProtoReflect.Descriptor instead.
+func (*ResumeRequest) Descriptor() ([]byte, []int) {
+	return file_flow_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ResumeRequest) GetFlow() string {
+	if x != nil {
+		return x.Flow
+	}
+	return ""
+}
+
+func (x *ResumeRequest) GetThreadId() string {
+	if x != nil {
+		return x.ThreadId
+	}
+	return ""
+}
+
+func (x *ResumeRequest) GetStreamModes() []string {
+	if x != nil {
+		return x.StreamModes
+	}
+	return nil
+}
+
+type RunResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	State         *State                 `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunResponse) Reset() {
+	*x = RunResponse{}
+	mi := &file_flow_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunResponse) ProtoMessage() {}
+
+func (x *RunResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_flow_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunResponse.ProtoReflect.Descriptor instead.
+func (*RunResponse) Descriptor() ([]byte, []int) {
+	return file_flow_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RunResponse) GetState() *State {
+	if x != nil {
+		return x.State
+	}
+	return nil
+}
+
+type GetStateRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Flow     string                 `protobuf:"bytes,1,opt,name=flow,proto3" json:"flow,omitempty"`
+	ThreadId string                 `protobuf:"bytes,2,opt,name=thread_id,json=threadId,proto3" json:"thread_id,omitempty"`
+	// checkpoint_id 为空时返回最新的检查点
+	CheckpointId  string `protobuf:"bytes,3,opt,name=checkpoint_id,json=checkpointId,proto3" json:"checkpoint_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStateRequest) Reset() {
+	*x = GetStateRequest{}
+	mi := &file_flow_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStateRequest) ProtoMessage() {}
+
+func (x *GetStateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_flow_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStateRequest.ProtoReflect.Descriptor instead.
+func (*GetStateRequest) Descriptor() ([]byte, []int) {
+	return file_flow_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetStateRequest) GetFlow() string {
+	if x != nil {
+		return x.Flow
+	}
+	return ""
+}
+
+func (x *GetStateRequest) GetThreadId() string {
+	if x != nil {
+		return x.ThreadId
+	}
+	return ""
+}
+
+func (x *GetStateRequest) GetCheckpointId() string {
+	if x != nil {
+		return x.CheckpointId
+	}
+	return ""
+}
+
+type GetStateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	State         *State                 `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`
+	CheckpointId  string                 `protobuf:"bytes,2,opt,name=checkpoint_id,json=checkpointId,proto3" json:"checkpoint_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStateResponse) Reset() {
+	*x = GetStateResponse{}
+	mi := &file_flow_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStateResponse) ProtoMessage() {}
+
+func (x *GetStateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_flow_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStateResponse.ProtoReflect.Descriptor instead.
+func (*GetStateResponse) Descriptor() ([]byte, []int) {
+	return file_flow_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetStateResponse) GetState() *State {
+	if x != nil {
+		return x.State
+	}
+	return nil
+}
+
+func (x *GetStateResponse) GetCheckpointId() string {
+	if x != nil {
+		return x.CheckpointId
+	}
+	return ""
+}
+
+// State 对应 state.State，包括恢复执行需要的内部字段
+type State struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ThreadId      string                 `protobuf:"bytes,1,opt,name=thread_id,json=threadId,proto3" json:"thread_id,omitempty"`
+	Node          string                 `protobuf:"bytes,2,opt,name=node,proto3" json:"node,omitempty"`
+	NextNodes     []string               `protobuf:"bytes,3,rep,name=next_nodes,json=nextNodes,proto3" json:"next_nodes,omitempty"`
+	History       []*Message             `protobuf:"bytes,4,rep,name=history,proto3" json:"history,omitempty"`
+	Metadata      *structpb.Struct       `protobuf:"bytes,5,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	PendingTasks  []*PendingNode         `protobuf:"bytes,6,rep,name=pending_tasks,json=pendingTasks,proto3" json:"pending_tasks,omitempty"`
+	PendingWrites []*PendingNode         `protobuf:"bytes,7,rep,name=pending_writes,json=pendingWrites,proto3" json:"pending_writes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *State) Reset() {
+	*x = State{}
+	mi := &file_flow_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *State) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*State) ProtoMessage() {}
+
+func (x *State) ProtoReflect() protoreflect.Message {
+	mi := &file_flow_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use State.ProtoReflect.Descriptor instead.
+func (*State) Descriptor() ([]byte, []int) {
+	return file_flow_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *State) GetThreadId() string {
+	if x != nil {
+		return x.ThreadId
+	}
+	return ""
+}
+
+func (x *State) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+func (x *State) GetNextNodes() []string {
+	if x != nil {
+		return x.NextNodes
+	}
+	return nil
+}
+
+func (x *State) GetHistory() []*Message {
+	if x != nil {
+		return x.History
+	}
+	return nil
+}
+
+func (x *State) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *State) GetPendingTasks() []*PendingNode {
+	if x != nil {
+		return x.PendingTasks
+	}
+	return nil
+}
+
+func (x *State) GetPendingWrites() []*PendingNode {
+	if x != nil {
+		return x.PendingWrites
+	}
+	return nil
+}
+
+// PendingNode 对应 state.PendingTask 和 state.PendingWrite
+type PendingNode struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Node          string                 `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	State         *State                 `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PendingNode) Reset() {
+	*x = PendingNode{}
+	mi := &file_flow_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PendingNode) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PendingNode) ProtoMessage() {}
+
+func (x *PendingNode) ProtoReflect() protoreflect.Message {
+	mi := &file_flow_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PendingNode.ProtoReflect.Descriptor instead.
+func (*PendingNode) Descriptor() ([]byte, []int) {
+	return file_flow_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *PendingNode) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+func (x *PendingNode) GetState() *State {
+	if x != nil {
+		return x.State
+	}
+	return nil
+}
+
+// Message 对应 llms.MessageContent，role 为 llms.ChatMessageType 的值：ai、human、system、generic、function 或 tool
+type Message struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Role          string                 `protobuf:"bytes,1,opt,name=role,proto3" json:"role,omitempty"`
+	Parts         []*Part                `protobuf:"bytes,2,rep,name=parts,proto3" json:"parts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Message) Reset() {
+	*x = Message{}
+	mi := &file_flow_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Message) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Message) ProtoMessage() {}
+
+func (x *Message) ProtoReflect() protoreflect.Message {
+	mi := &file_flow_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Message.ProtoReflect.Descriptor instead.
+func (*Message) Descriptor() ([]byte, []int) {
+	return file_flow_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Message) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *Message) GetParts() []*Part {
+	if x != nil {
+		return x.Parts
+	}
+	return nil
+}
+
+type Part struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Part:
+	//
+	//	*Part_Text
+	//	*Part_ImageUrl
+	//	*Part_Binary
+	//	*Part_ToolCall
+	//	*Part_ToolCallResponse
+	Part          isPart_Part `protobuf_oneof:"part"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Part) Reset() {
+	*x = Part{}
+	mi := &file_flow_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Part) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Part) ProtoMessage() {}
+
+func (x *Part) ProtoReflect() protoreflect.Message {
+	mi := &file_flow_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Part.ProtoReflect.Descriptor instead.
+func (*Part) Descriptor() ([]byte, []int) {
+	return file_flow_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Part) GetPart() isPart_Part {
+	if x != nil {
+		return x.Part
+	}
+	return nil
+}
+
+func (x *Part) GetText() *TextPart {
+	if x != nil {
+		if x, ok := x.Part.(*Part_Text); ok {
+			return x.Text
+		}
+	}
+	return nil
+}
+
+func (x *Part) GetImageUrl() *ImageURLPart {
+	if x != nil {
+		if x, ok := x.Part.(*Part_ImageUrl); ok {
+			return x.ImageUrl
+		}
+	}
+	return nil
+}
+
+func (x *Part) GetBinary() *BinaryPart {
+	if x != nil {
+		if x, ok := x.Part.(*Part_Binary); ok {
+			return x.Binary
+		}
+	}
+	return nil
+}
+
+func (x *Part) GetToolCall() *ToolCallPart {
+	if x != nil {
+		if x, ok := x.Part.(*Part_ToolCall); ok {
+			return x.ToolCall
+		}
+	}
+	return nil
+}
+
+func (x *Part) GetToolCallResponse() *ToolCallResponsePart {
+	if x != nil {
+		if x, ok := x.Part.(*Part_ToolCallResponse); ok {
+			return x.ToolCallResponse
+		}
+	}
+	return nil
+}
+
+type isPart_Part interface {
+	isPart_Part()
+}
+
+type Part_Text struct {
+	Text *TextPart `protobuf:"bytes,1,opt,name=text,proto3,oneof"`
+}
+
+type Part_ImageUrl struct {
+	ImageUrl *ImageURLPart `protobuf:"bytes,2,opt,name=image_url,json=imageUrl,proto3,oneof"`
+}
+
+type Part_Binary struct {
+	Binary *BinaryPart `protobuf:"bytes,3,opt,name=binary,proto3,oneof"`
+}
+
+type Part_ToolCall struct {
+	ToolCall *ToolCallPart `protobuf:"bytes,4,opt,name=tool_call,json=toolCall,proto3,oneof"`
+}
+
+type Part_ToolCallResponse struct {
+	ToolCallResponse *ToolCallResponsePart `protobuf:"bytes,5,opt,name=tool_call_response,json=toolCallResponse,proto3,oneof"`
+}
+
+func (*Part_Text) isPart_Part() {}
+
+func (*Part_ImageUrl) isPart_Part() {}
+
+func (*Part_Binary) isPart_Part() {}
+
+func (*Part_ToolCall) isPart_Part() {}
+
+func (*Part_ToolCallResponse) isPart_Part() {}
+
+type TextPart struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TextPart) Reset() {
+	*x = TextPart{}
+	mi := &file_flow_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TextPart) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TextPart) ProtoMessage() {}
+
+func (x *TextPart) ProtoReflect() protoreflect.Message {
+	mi := &file_flow_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TextPart.ProtoReflect.Descriptor instead.
+func (*TextPart) Descriptor() ([]byte, []int) {
+	return file_flow_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *TextPart) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type ImageURLPart struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Detail        string                 `protobuf:"bytes,2,opt,name=detail,proto3" json:"detail,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImageURLPart) Reset() {
+	*x = ImageURLPart{}
+	mi := &file_flow_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImageURLPart) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImageURLPart) ProtoMessage() {}
+
+func (x *ImageURLPart) ProtoReflect() protoreflect.Message {
+	mi := &file_flow_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImageURLPart.ProtoReflect.Descriptor instead.
+func (*ImageURLPart) Descriptor() ([]byte, []int) {
+	return file_flow_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ImageURLPart) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *ImageURLPart) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+type BinaryPart struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MimeType      string                 `protobuf:"bytes,1,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BinaryPart) Reset() {
+	*x = BinaryPart{}
+	mi := &file_flow_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BinaryPart) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BinaryPart) ProtoMessage() {}
+
+func (x *BinaryPart) ProtoReflect() protoreflect.Message {
+	mi := &file_flow_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BinaryPart.ProtoReflect.Descriptor instead.
+func (*BinaryPart) Descriptor() ([]byte, []int) {
+	return file_flow_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *BinaryPart) GetMimeType() string {
+	if x != nil {
+		return x.MimeType
+	}
+	return ""
+}
+
+func (x *BinaryPart) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type ToolCallPart struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Arguments     string                 `protobuf:"bytes,4,opt,name=arguments,proto3" json:"arguments,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ToolCallPart) Reset() {
+	*x = ToolCallPart{}
+	mi := &file_flow_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolCallPart) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolCallPart) ProtoMessage() {}
+
+func (x *ToolCallPart) ProtoReflect() protoreflect.Message {
+	mi := &file_flow_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolCallPart.ProtoReflect.Descriptor instead.
+func (*ToolCallPart) Descriptor() ([]byte, []int) {
+	return file_flow_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ToolCallPart) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ToolCallPart) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ToolCallPart) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ToolCallPart) GetArguments() string {
+	if x != nil {
+		return x.Arguments
+	}
+	return ""
+}
+
+type ToolCallResponsePart struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ToolCallId    string                 `protobuf:"bytes,1,opt,name=tool_call_id,json=toolCallId,proto3" json:"tool_call_id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Content       string                 `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ToolCallResponsePart) Reset() {
+	*x = ToolCallResponsePart{}
+	mi := &file_flow_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolCallResponsePart) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolCallResponsePart) ProtoMessage() {}
+
+func (x *ToolCallResponsePart) ProtoReflect() protoreflect.Message {
+	mi := &file_flow_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolCallResponsePart.ProtoReflect.Descriptor instead.
+func (*ToolCallResponsePart) Descriptor() ([]byte, []int) {
+	return file_flow_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ToolCallResponsePart) GetToolCallId() string {
+	if x != nil {
+		return x.ToolCallId
+	}
+	return ""
+}
+
+func (x *ToolCallResponsePart) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ToolCallResponsePart) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+// Event 对应 flowcontract.FlowStreamEvent，type 为 flowcontract.EventType 的值
+type Event struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Node          string                 `protobuf:"bytes,2,opt,name=node,proto3" json:"node,omitempty"`
+	ThreadId      string                 `protobuf:"bytes,3,opt,name=thread_id,json=threadId,proto3" json:"thread_id,omitempty"`
+	RunId         string                 `protobuf:"bytes,4,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	Chunk         string                 `protobuf:"bytes,5,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	FullState     *State                 `protobuf:"bytes,6,opt,name=full_state,json=fullState,proto3" json:"full_state,omitempty"`
+	Update        *State                 `protobuf:"bytes,7,opt,name=update,proto3" json:"update,omitempty"`
+	NextNodes     []string               `protobuf:"bytes,8,rep,name=next_nodes,json=nextNodes,proto3" json:"next_nodes,omitempty"`
+	Duration      *durationpb.Duration   `protobuf:"bytes,9,opt,name=duration,proto3" json:"duration,omitempty"`
+	ToolCall      *ToolCall              `protobuf:"bytes,10,opt,name=tool_call,json=toolCall,proto3" json:"tool_call,omitempty"`
+	CheckpointId  string                 `protobuf:"bytes,11,opt,name=checkpoint_id,json=checkpointId,proto3" json:"checkpoint_id,omitempty"`
+	Error         string                 `protobuf:"bytes,12,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_flow_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_flow_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_flow_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+func (x *Event) GetThreadId() string {
+	if x != nil {
+		return x.ThreadId
+	}
+	return ""
+}
+
+func (x *Event) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *Event) GetChunk() string {
+	if x != nil {
+		return x.Chunk
+	}
+	return ""
+}
+
+func (x *Event) GetFullState() *State {
+	if x != nil {
+		return x.FullState
+	}
+	return nil
+}
+
+func (x *Event) GetUpdate() *State {
+	if x != nil {
+		return x.Update
+	}
+	return nil
+}
+
+func (x *Event) GetNextNodes() []string {
+	if x != nil {
+		return x.NextNodes
+	}
+	return nil
+}
+
+func (x *Event) GetDuration() *durationpb.Duration {
+	if x != nil {
+		return x.Duration
+	}
+	return nil
+}
+
+func (x *Event) GetToolCall() *ToolCall {
+	if x != nil {
+		return x.ToolCall
+	}
+	return nil
+}
+
+func (x *Event) GetCheckpointId() string {
+	if x != nil {
+		return x.CheckpointId
+	}
+	return ""
+}
+
+func (x *Event) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ToolCall struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Arguments     string                 `protobuf:"bytes,3,opt,name=arguments,proto3" json:"arguments,omitempty"`
+	Result        string                 `protobuf:"bytes,4,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ToolCall) Reset() {
+	*x = ToolCall{}
+	mi := &file_flow_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolCall) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolCall) ProtoMessage() {}
+
+func (x *ToolCall) ProtoReflect() protoreflect.Message {
+	mi := &file_flow_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolCall.ProtoReflect.Descriptor instead.
+func (*ToolCall) Descriptor() ([]byte, []int) {
+	return file_flow_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ToolCall) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ToolCall) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ToolCall) GetArguments() string {
+	if x != nil {
+		return x.Arguments
+	}
+	return ""
+}
+
+func (x *ToolCall) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+var File_flow_proto protoreflect.FileDescriptor
+
+const file_flow_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"flow.proto\x12\x0egolanggraph.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1cgoogle/protobuf/struct.proto\"\x12\n" +
+	"\x10ListFlowsRequest\")\n" +
+	"\x11ListFlowsResponse\x12\x14\n" +
+	"\x05flows\x18\x01 \x03(\tR\x05flows\"\xca\x01\n" +
+	"\n" +
+	"RunRequest\x12\x12\n" +
+	"\x04flow\x18\x01 \x01(\tR\x04flow\x12\x1b\n" +
+	"\tthread_id\x18\x02 \x01(\tR\bthreadId\x123\n" +
+	"\bmessages\x18\x03 \x03(\v2\x17.golanggraph.v1.MessageR\bmessages\x123\n" +
+	"\bmetadata\x18\x04 \x01(\v2\x17.google.protobuf.StructR\bmetadata\x12!\n" +
+	"\fstream_modes\x18\x05 \x03(\tR\vstreamModes\"c\n" +
+	"\rResumeRequest\x12\x12\n" +
+	"\x04flow\x18\x01 \x01(\tR\x04flow\x12\x1b\n" +
+	"\tthread_id\x18\x02 \x01(\tR\bthreadId\x12!\n" +
+	"\fstream_modes\x18\x03 \x03(\tR\vstreamModes\":\n" +
+	"\vRunResponse\x12+\n" +
+	"\x05state\x18\x01 \x01(\v2\x15.golanggraph.v1.StateR\x05state\"g\n" +
+	"\x0fGetStateRequest\x12\x12\n" +
+	"\x04flow\x18\x01 \x01(\tR\x04flow\x12\x1b\n" +
+	"\tthread_id\x18\x02 \x01(\tR\bthreadId\x12#\n" +
+	"\rcheckpoint_id\x18\x03 \x01(\tR\fcheckpointId\"d\n" +
+	"\x10GetStateResponse\x12+\n" +
+	"\x05state\x18\x01 \x01(\v2\x15.golanggraph.v1.StateR\x05state\x12#\n" +
+	"\rcheckpoint_id\x18\x02 \x01(\tR\fcheckpointId\"\xc5\x02\n" +
+	"\x05State\x12\x1b\n" +
+	"\tthread_id\x18\x01 \x01(\tR\bthreadId\x12\x12\n" +
+	"\x04node\x18\x02 \x01(\tR\x04node\x12\x1d\n" +
+	"\n" +
+	"next_nodes\x18\x03 \x03(\tR\tnextNodes\x121\n" +
+	"\ahistory\x18\x04 \x03(\v2\x17.golanggraph.v1.MessageR\ahistory\x123\n" +
+	"\bmetadata\x18\x05 \x01(\v2\x17.google.protobuf.StructR\bmetadata\x12@\n" +
+	"\rpending_tasks\x18\x06 \x03(\v2\x1b.golanggraph.v1.PendingNodeR\fpendingTasks\x12B\n" +
+	"\x0epending_writes\x18\a \x03(\v2\x1b.golanggraph.v1.PendingNodeR\rpendingWrites\"N\n" +
+	"\vPendingNode\x12\x12\n" +
+	"\x04node\x18\x01 \x01(\tR\x04node\x12+\n" +
+	"\x05state\x18\x02 \x01(\v2\x15.golanggraph.v1.StateR\x05state\"I\n" +
+	"\aMessage\x12\x12\n" +
+	"\x04role\x18\x01 \x01(\tR\x04role\x12*\n" +
+	"\x05parts\x18\x02 \x03(\v2\x14.golanggraph.v1.PartR\x05parts\"\xc4\x02\n" +
+	"\x04Part\x12.\n" +
+	"\x04text\x18\x01 \x01(\v2\x18.golanggraph.v1.TextPartH\x00R\x04text\x12;\n" +
+	"\timage_url\x18\x02 \x01(\v2\x1c.golanggraph.v1.ImageURLPartH\x00R\bimageUrl\x124\n" +
+	"\x06binary\x18\x03 \x01(\v2\x1a.golanggraph.v1.BinaryPartH\x00R\x06binary\x12;\n" +
+	"\ttool_call\x18\x04 \x01(\v2\x1c.golanggraph.v1.ToolCallPartH\x00R\btoolCall\x12T\n" +
+	"\x12tool_call_response\x18\x05 \x01(\v2$.golanggraph.v1.ToolCallResponsePartH\x00R\x10toolCallResponseB\x06\n" +
+	"\x04part\"\x1e\n" +
+	"\bTextPart\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\"8\n" +
+	"\fImageURLPart\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x16\n" +
+	"\x06detail\x18\x02 \x01(\tR\x06detail\"=\n" +
+	"\n" +
+	"BinaryPart\x12\x1b\n" +
+	"\tmime_type\x18\x01 \x01(\tR\bmimeType\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"d\n" +
+	"\fToolCallPart\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x1c\n" +
+	"\targuments\x18\x04 \x01(\tR\targuments\"f\n" +
+	"\x14ToolCallResponsePart\x12 \n" +
+	"\ftool_call_id\x18\x01 \x01(\tR\n" +
+	"toolCallId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x18\n" +
+	"\acontent\x18\x03 \x01(\tR\acontent\"\xa6\x03\n" +
+	"\x05Event\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x12\n" +
+	"\x04node\x18\x02 \x01(\tR\x04node\x12\x1b\n" +
+	"\tthread_id\x18\x03 \x01(\tR\bthreadId\x12\x15\n" +
+	"\x06run_id\x18\x04 \x01(\tR\x05runId\x12\x14\n" +
+	"\x05chunk\x18\x05 \x01(\tR\x05chunk\x124\n" +
+	"\n" +
+	"full_state\x18\x06 \x01(\v2\x15.golanggraph.v1.StateR\tfullState\x12-\n" +
+	"\x06update\x18\a \x01(\v2\x15.golanggraph.v1.StateR\x06update\x12\x1d\n" +
+	"\n" +
+	"next_nodes\x18\b \x03(\tR\tnextNodes\x125\n" +
+	"\bduration\x18\t \x01(\v2\x19.google.protobuf.DurationR\bduration\x125\n" +
+	"\ttool_call\x18\n" +
+	" \x01(\v2\x18.golanggraph.v1.ToolCallR\btoolCall\x12#\n" +
+	"\rcheckpoint_id\x18\v \x01(\tR\fcheckpointId\x12\x14\n" +
+	"\x05error\x18\f \x01(\tR\x05error\"d\n" +
+	"\bToolCall\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1c\n" +
+	"\targuments\x18\x03 \x01(\tR\targuments\x12\x16\n" +
+	"\x06result\x18\x04 \x01(\tR\x06result2\xbe\x03\n" +
+	"\vFlowService\x12P\n" +
+	"\tListFlows\x12 .golanggraph.v1.ListFlowsRequest\x1a!.golanggraph.v1.ListFlowsResponse\x12>\n" +
+	"\x03Run\x12\x1a.golanggraph.v1.RunRequest\x1a\x1b.golanggraph.v1.RunResponse\x12@\n" +
+	"\tStreamRun\x12\x1a.golanggraph.v1.RunRequest\x1a\x15.golanggraph.v1.Event0\x01\x12D\n" +
+	"\x06Resume\x12\x1d.golanggraph.v1.ResumeRequest\x1a\x1b.golanggraph.v1.RunResponse\x12F\n" +
+	"\fStreamResume\x12\x1d.golanggraph.v1.ResumeRequest\x1a\x15.golanggraph.v1.Event0\x01\x12M\n" +
+	"\bGetState\x12\x1f.golanggraph.v1.GetStateRequest\x1a .golanggraph.v1.GetStateResponseB;Z9github.com/futurxlab/golanggraph/server/grpcserver/flowpbb\x06proto3"
+
+var (
+	file_flow_proto_rawDescOnce sync.Once
+	file_flow_proto_rawDescData []byte
+)
+
+func file_flow_proto_rawDescGZIP() []byte {
+	file_flow_proto_rawDescOnce.Do(func() {
+		file_flow_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_flow_proto_rawDesc), len(file_flow_proto_rawDesc)))
+	})
+	return file_flow_proto_rawDescData
+}
+
+var file_flow_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_flow_proto_goTypes = []any{
+	(*ListFlowsRequest)(nil),     // 0: golanggraph.v1.ListFlowsRequest
+	(*ListFlowsResponse)(nil),    // 1: golanggraph.v1.ListFlowsResponse
+	(*RunRequest)(nil),           // 2: golanggraph.v1.RunRequest
+	(*ResumeRequest)(nil),        // 3: golanggraph.v1.ResumeRequest
+	(*RunResponse)(nil),          // 4: golanggraph.v1.RunResponse
+	(*GetStateRequest)(nil),      // 5: golanggraph.v1.GetStateRequest
+	(*GetStateResponse)(nil),     // 6: golanggraph.v1.GetStateResponse
+	(*State)(nil),                // 7: golanggraph.v1.State
+	(*PendingNode)(nil),          // 8: golanggraph.v1.PendingNode
+	(*Message)(nil),              // 9: golanggraph.v1.Message
+	(*Part)(nil),                 // 10: golanggraph.v1.Part
+	(*TextPart)(nil),             // 11: golanggraph.v1.TextPart
+	(*ImageURLPart)(nil),         // 12: golanggraph.v1.ImageURLPart
+	(*BinaryPart)(nil),           // 13: golanggraph.v1.BinaryPart
+	(*ToolCallPart)(nil),         // 14: golanggraph.v1.ToolCallPart
+	(*ToolCallResponsePart)(nil), // 15: golanggraph.v1.ToolCallResponsePart
+	(*Event)(nil),                // 16: golanggraph.v1.Event
+	(*ToolCall)(nil),             // 17: golanggraph.v1.ToolCall
+	(*structpb.Struct)(nil),      // 18: google.protobuf.Struct
+	(*durationpb.Duration)(nil),  // 19: google.protobuf.Duration
+}
+var file_flow_proto_depIdxs = []int32{
+	9,  // 0: golanggraph.v1.RunRequest.messages:type_name -> golanggraph.v1.Message
+	18, // 1: golanggraph.v1.RunRequest.metadata:type_name -> google.protobuf.Struct
+	7,  // 2: golanggraph.v1.RunResponse.state:type_name -> golanggraph.v1.State
+	7,  // 3: golanggraph.v1.GetStateResponse.state:type_name -> golanggraph.v1.State
+	9,  // 4: golanggraph.v1.State.history:type_name -> golanggraph.v1.Message
+	18, // 5: golanggraph.v1.State.metadata:type_name -> google.protobuf.Struct
+	8,  // 6: golanggraph.v1.State.pending_tasks:type_name -> golanggraph.v1.PendingNode
+	8,  // 7: golanggraph.v1.State.pending_writes:type_name -> golanggraph.v1.PendingNode
+	7,  // 8: golanggraph.v1.PendingNode.state:type_name -> golanggraph.v1.State
+	10, // 9: golanggraph.v1.Message.parts:type_name -> golanggraph.v1.Part
+	11, // 10: golanggraph.v1.Part.text:type_name -> golanggraph.v1.TextPart
+	12, // 11: golanggraph.v1.Part.image_url:type_name -> golanggraph.v1.ImageURLPart
+	13, // 12: golanggraph.v1.Part.binary:type_name -> golanggraph.v1.BinaryPart
+	14, // 13: golanggraph.v1.Part.tool_call:type_name -> golanggraph.v1.ToolCallPart
+	15, // 14: golanggraph.v1.Part.tool_call_response:type_name -> golanggraph.v1.ToolCallResponsePart
+	7,  // 15: golanggraph.v1.Event.full_state:type_name -> golanggraph.v1.State
+	7,  // 16: golanggraph.v1.Event.update:type_name -> golanggraph.v1.State
+	19, // 17: golanggraph.v1.Event.duration:type_name -> google.protobuf.Duration
+	17, // 18: golanggraph.v1.Event.tool_call:type_name -> golanggraph.v1.ToolCall
+	0,  // 19: golanggraph.v1.FlowService.ListFlows:input_type -> golanggraph.v1.ListFlowsRequest
+	2,  // 20: golanggraph.v1.FlowService.Run:input_type -> golanggraph.v1.RunRequest
+	2,  // 21: golanggraph.v1.FlowService.StreamRun:input_type -> golanggraph.v1.RunRequest
+	3,  // 22: golanggraph.v1.FlowService.Resume:input_type -> golanggraph.v1.ResumeRequest
+	3,  // 23: golanggraph.v1.FlowService.StreamResume:input_type -> golanggraph.v1.ResumeRequest
+	5,  // 24: golanggraph.v1.FlowService.GetState:input_type -> golanggraph.v1.GetStateRequest
+	1,  // 25: golanggraph.v1.FlowService.ListFlows:output_type -> golanggraph.v1.ListFlowsResponse
+	4,  // 26: golanggraph.v1.FlowService.Run:output_type -> golanggraph.v1.RunResponse
+	16, // 27: golanggraph.v1.FlowService.StreamRun:output_type -> golanggraph.v1.Event
+	4,  // 28: golanggraph.v1.FlowService.Resume:output_type -> golanggraph.v1.RunResponse
+	16, // 29: golanggraph.v1.FlowService.StreamResume:output_type -> golanggraph.v1.Event
+	6,  // 30: golanggraph.v1.FlowService.GetState:output_type -> golanggraph.v1.GetStateResponse
+	25, // [25:31] is the sub-list for method output_type
+	19, // [19:25] is the sub-list for method input_type
+	19, // [19:19] is the sub-list for extension type_name
+	19, // [19:19] is the sub-list for extension extendee
+	0,  // [0:19] is the sub-list for field type_name
+}
+
+func init() { file_flow_proto_init() }
+func file_flow_proto_init() {
+	if File_flow_proto != nil {
+		return
+	}
+	file_flow_proto_msgTypes[10].OneofWrappers = []any{
+		(*Part_Text)(nil),
+		(*Part_ImageUrl)(nil),
+		(*Part_Binary)(nil),
+		(*Part_ToolCall)(nil),
+		(*Part_ToolCallResponse)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_flow_proto_rawDesc), len(file_flow_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   18,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_flow_proto_goTypes,
+		DependencyIndexes: file_flow_proto_depIdxs,
+		MessageInfos:      file_flow_proto_msgTypes,
+	}.Build()
+	File_flow_proto = out.File
+	file_flow_proto_goTypes = nil
+	file_flow_proto_depIdxs = nil
+}
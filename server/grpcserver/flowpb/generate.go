@@ -0,0 +1,4 @@
+// Package flowpb 是 flow.proto 生成的 protobuf 消息和 gRPC 服务定义
+package flowpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative flow.proto
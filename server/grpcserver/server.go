@@ -0,0 +1,295 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"sync"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/flow"
+	"github.com/futurxlab/golanggraph/logger"
+	"github.com/futurxlab/golanggraph/server/grpcserver/flowpb"
+	"github.com/futurxlab/golanggraph/server/internal/runutil"
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrFlowNotFound 请求的 Flow 没有注册
+	ErrFlowNotFound = errors.New("flow not found")
+
+	// errBadRequest 请求参数错误
+	errBadRequest = errors.New("bad request")
+)
+
+type Options struct {
+	Logger logger.ILogger
+}
+
+type Option func(*Options)
+
+func WithLogger(logger logger.ILogger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// Server 实现 flowpb.FlowServiceServer，以 gRPC 发布注册的 Flow。
+// 与 REST 接口一致，执行在线程最新的状态上继续，上次执行的下一步和待恢复节点被丢弃，需要继续时使用 Resume；
+// 错误以 gRPC 状态码返回：Flow 或线程不存在为 NotFound，线程被占用为 Aborted，请求参数错误为 InvalidArgument
+type Server struct {
+	flowpb.UnimplementedFlowServiceServer
+
+	options *Options
+
+	mu    sync.RWMutex
+	flows map[string]*flow.Flow
+}
+
+var _ flowpb.FlowServiceServer = (*Server)(nil)
+
+func NewServer(opts ...Option) (*Server, error) {
+	defaultLogger, err := logger.NewLogger()
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+
+	options := &Options{
+		Logger: defaultLogger,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &Server{
+		options: options,
+		flows:   make(map[string]*flow.Flow),
+	}, nil
+}
+
+// Register 以 Flow 的名称注册，名称重复时返回错误
+func (s *Server) Register(f *flow.Flow) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.flows[f.Name()]; exists {
+		return xerror.New(fmt.Sprintf("duplicate flow name: %s", f.Name()))
+	}
+	s.flows[f.Name()] = f
+
+	return nil
+}
+
+// RegisterService 把服务注册到 gRPC 服务器
+func (s *Server) RegisterService(registrar grpc.ServiceRegistrar) {
+	flowpb.RegisterFlowServiceServer(registrar, s)
+}
+
+func (s *Server) ListFlows(ctx context.Context, request *flowpb.ListFlowsRequest) (*flowpb.ListFlowsResponse, error) {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.flows))
+	for name := range s.flows {
+		names = append(names, name)
+	}
+	s.mu.RUnlock()
+
+	slices.Sort(names)
+	return &flowpb.ListFlowsResponse{Flows: names}, nil
+}
+
+func (s *Server) Run(ctx context.Context, request *flowpb.RunRequest) (*flowpb.RunResponse, error) {
+	f, err := s.lookup(request.GetFlow())
+	if err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+
+	update, err := runUpdate(request)
+	if err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+
+	result, err := f.Continue(ctx, request.GetThreadId(), update, nil)
+	if err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+
+	return s.runResponse(ctx, &result)
+}
+
+func (s *Server) StreamRun(request *flowpb.RunRequest, stream grpc.ServerStreamingServer[flowpb.Event]) error {
+	ctx := stream.Context()
+	f, err := s.lookup(request.GetFlow())
+	if err != nil {
+		return s.toStatus(ctx, err)
+	}
+
+	update, err := runUpdate(request)
+	if err != nil {
+		return s.toStatus(ctx, err)
+	}
+
+	return s.send(ctx, stream, f.ContinueStream(ctx, request.GetThreadId(), update, streamModes(request.GetStreamModes())...))
+}
+
+func (s *Server) Resume(ctx context.Context, request *flowpb.ResumeRequest) (*flowpb.RunResponse, error) {
+	f, err := s.resumeInput(request)
+	if err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+
+	result, err := f.ResumeThread(ctx, request.GetThreadId(), nil)
+	if err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+
+	return s.runResponse(ctx, &result)
+}
+
+func (s *Server) StreamResume(request *flowpb.ResumeRequest, stream grpc.ServerStreamingServer[flowpb.Event]) error {
+	ctx := stream.Context()
+	f, err := s.resumeInput(request)
+	if err != nil {
+		return s.toStatus(ctx, err)
+	}
+
+	return s.send(ctx, stream, f.ResumeThreadStream(ctx, request.GetThreadId(), streamModes(request.GetStreamModes())...))
+}
+
+func (s *Server) GetState(ctx context.Context, request *flowpb.GetStateRequest) (*flowpb.GetStateResponse, error) {
+	f, err := s.lookup(request.GetFlow())
+	if err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+
+	var current *state.State
+	checkpointID := request.GetCheckpointId()
+	if checkpointID != "" {
+		current, err = f.Checkpointer().GetByID(ctx, request.GetThreadId(), checkpointID)
+		if err != nil {
+			err = xerror.Wrap(err)
+		}
+	} else {
+		var checkpoint *flowcontract.Checkpoint
+		current, checkpoint, err = runutil.LatestCheckpoint(ctx, f.Checkpointer(), request.GetThreadId())
+		if checkpoint != nil {
+			checkpointID = checkpoint.ID
+		}
+	}
+	if err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+
+	encoded, err := EncodeState(current)
+	if err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+	return &flowpb.GetStateResponse{State: encoded, CheckpointId: checkpointID}, nil
+}
+
+func (s *Server) lookup(name string) (*flow.Flow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f, ok := s.flows[name]
+	if !ok {
+		return nil, xerror.Wrap(fmt.Errorf("%w: %s", ErrFlowNotFound, name))
+	}
+	return f, nil
+}
+
+// runUpdate 返回写入本轮输入的函数：追加请求的消息并合并 Metadata，由 Flow.Continue 在线程最新的状态上调用
+func runUpdate(request *flowpb.RunRequest) (func(current *state.State) error, error) {
+	messages, err := DecodeMessages(request.GetMessages())
+	if err != nil {
+		return nil, err
+	}
+	metadata := DecodeMetadata(request.GetMetadata())
+
+	return func(current *state.State) error {
+		current.History = append(current.History, messages...)
+		for k, v := range metadata {
+			if v == nil {
+				delete(current.Metadata, k)
+				continue
+			}
+			current.Metadata[k] = v
+		}
+		return nil
+	}, nil
+}
+
+// resumeInput 返回恢复执行的 Flow，线程最新的检查点由 Flow.ResumeThread 在线程锁内读取
+func (s *Server) resumeInput(request *flowpb.ResumeRequest) (*flow.Flow, error) {
+	if request.GetThreadId() == "" {
+		return nil, xerror.Wrap(fmt.Errorf("%w: thread_id is required", errBadRequest))
+	}
+	return s.lookup(request.GetFlow())
+}
+
+func (s *Server) runResponse(ctx context.Context, result *state.State) (*flowpb.RunResponse, error) {
+	encoded, err := EncodeState(result)
+	if err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+	return &flowpb.RunResponse{State: encoded}, nil
+}
+
+// send 输出流式事件，执行失败时在 run_end 事件之后以错误状态结束
+func (s *Server) send(ctx context.Context, stream grpc.ServerStreamingServer[flowpb.Event], events iter.Seq2[*flowcontract.FlowStreamEvent, error]) error {
+	for event, err := range events {
+		if err != nil {
+			return s.toStatus(ctx, err)
+		}
+
+		encoded, err := EncodeEvent(event)
+		if err != nil {
+			return s.toStatus(ctx, err)
+		}
+		// 停止迭代会取消执行
+		if err := stream.Send(encoded); err != nil {
+			s.options.Logger.Warnf(ctx, "send flow event failed %s", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// toStatus 按错误类型转换为 gRPC 状态，错误信息去掉调用栈
+func (s *Server) toStatus(ctx context.Context, err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, ErrFlowNotFound), errors.Is(err, flowcontract.ErrCheckpointNotFound):
+		code = codes.NotFound
+	case errors.Is(err, flowcontract.ErrThreadBusy):
+		code = codes.Aborted
+	case errors.Is(err, errBadRequest):
+		code = codes.InvalidArgument
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	default:
+		s.options.Logger.Errorf(ctx, "grpc flow call failed %s", err)
+	}
+
+	return status.Error(code, runutil.ErrorMessage(err))
+}
+
+func streamModes(modes []string) []flow.ExecOption {
+	if len(modes) == 0 {
+		return nil
+	}
+
+	streamModes := make([]flowcontract.StreamMode, 0, len(modes))
+	for _, mode := range modes {
+		streamModes = append(streamModes, flowcontract.StreamMode(mode))
+	}
+	return []flow.ExecOption{flow.WithStreamMode(streamModes...)}
+}
@@ -0,0 +1,242 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/futurxlab/golanggraph/checkpointer"
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/edge"
+	"github.com/futurxlab/golanggraph/flow"
+	"github.com/futurxlab/golanggraph/logger"
+	"github.com/futurxlab/golanggraph/state"
+
+	"github.com/tmc/langchaingo/llms"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type replyNode struct {
+	fail atomic.Bool
+}
+
+func (n *replyNode) Name() string {
+	return "reply"
+}
+
+func (n *replyNode) Run(ctx context.Context, currentState *state.State, streamFunc flowcontract.StreamFunc) error {
+	if n.fail.Load() {
+		return errors.New("model unavailable")
+	}
+
+	last := currentState.History[len(currentState.History)-1]
+	text := last.Parts[0].(llms.TextContent).Text
+	if err := streamFunc(ctx, &flowcontract.FlowStreamEvent{Type: flowcontract.EventLLMToken, Chunk: "echo: " + text}); err != nil {
+		return err
+	}
+	currentState.History = append(currentState.History, llms.TextParts(llms.ChatMessageTypeAI, "echo: "+text))
+	currentState.Metadata["turns"] = float64(len(currentState.History) / 2)
+	return nil
+}
+
+func newTestClient(t *testing.T) (*Client, *replyNode) {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := &replyNode{}
+	f, err := flow.NewFlowBuilder(logger).
+		SetName("echo").
+		SetCheckpointer(checkpointer.NewInMemoryCheckpointer()).
+		AddNode(node).
+		AddEdge(edge.Edge{From: flow.StartNode, To: node.Name()}).
+		AddEdge(edge.Edge{From: node.Name(), To: flow.EndNode}).
+		Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := NewServer(WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Register(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Register(f); err == nil {
+		t.Fatal("expected duplicate registration to fail")
+	}
+
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	server.RegisterService(grpcServer)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return NewClient(conn), node
+}
+
+func userMessage(threadID, text string) state.State {
+	input := state.State{History: []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, text)}}
+	input.SetThreadID(threadID)
+	return input
+}
+
+func TestServer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	client, node := newTestClient(t)
+
+	flows, err := client.ListFlows(ctx)
+	if err != nil || !reflect.DeepEqual(flows, []string{"echo"}) {
+		t.Fatalf("unexpected flows %v %v", flows, err)
+	}
+
+	if _, err := client.Exec(ctx, "missing", userMessage("t1", "hi")); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if _, _, err := client.GetState(ctx, "echo", "t1", ""); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	input := userMessage("t1", "hi")
+	input.Metadata = map[string]interface{}{"user": "alice"}
+	result, err := client.Exec(ctx, "echo", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.History) != 2 || result.History[1].Parts[0].(llms.TextContent).Text != "echo: hi" {
+		t.Fatalf("unexpected history %+v", result.History)
+	}
+	if result.GetThreadID() != "t1" || result.Metadata["user"] != "alice" || result.Metadata["turns"] != float64(1) {
+		t.Fatalf("unexpected state %+v", result)
+	}
+
+	// 流式执行在线程上继续
+	var tokens []string
+	var final *state.State
+	for event, err := range client.Stream(ctx, "echo", userMessage("t1", "again"), flowcontract.StreamModeMessages) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch event.Type {
+		case flowcontract.EventLLMToken:
+			tokens = append(tokens, event.Chunk)
+		case flowcontract.EventRunEnd:
+			final = event.FullState
+		}
+	}
+	if !reflect.DeepEqual(tokens, []string{"echo: again"}) || final == nil || len(final.History) != 4 {
+		t.Fatalf("unexpected stream %v %+v", tokens, final)
+	}
+
+	s, _, err := client.GetState(ctx, "echo", "t1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.History) != 4 || s.Metadata["turns"] != float64(2) {
+		t.Fatalf("unexpected state %+v", s)
+	}
+
+	// 失败后恢复
+	node.fail.Store(true)
+	var runErr error
+	var streamErr error
+	for event, err := range client.Stream(ctx, "echo", userMessage("t1", "third")) {
+		if err != nil {
+			streamErr = err
+			break
+		}
+		if event.Type == flowcontract.EventRunEnd {
+			runErr = event.Err
+		}
+	}
+	if runErr == nil || runErr.Error() != "model unavailable" || status.Code(streamErr) != codes.Internal {
+		t.Fatalf("expected failed run, got %v %v", runErr, streamErr)
+	}
+
+	node.fail.Store(false)
+	result, err = client.Resume(ctx, "echo", "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.History) != 6 || result.History[5].Parts[0].(llms.TextContent).Text != "echo: third" {
+		t.Fatalf("unexpected resumed history %+v", result.History)
+	}
+}
+
+func TestEncodeState(t *testing.T) {
+	pending := &state.State{
+		History:  []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "hi")},
+		Metadata: map[string]interface{}{"n": float64(1)},
+	}
+
+	s := &state.State{
+		History: []llms.MessageContent{
+			{Role: llms.ChatMessageTypeHuman, Parts: []llms.ContentPart{
+				llms.TextContent{Text: "look"},
+				llms.ImageURLContent{URL: "https://example.com/a.png", Detail: "low"},
+				llms.BinaryContent{MIMEType: "image/png", Data: []byte{1, 2}},
+			}},
+			{Role: llms.ChatMessageTypeAI, Parts: []llms.ContentPart{llms.ToolCall{
+				ID:           "call-1",
+				Type:         "function",
+				FunctionCall: &llms.FunctionCall{Name: "search", Arguments: `{"q":"go"}`},
+			}}},
+			{Role: llms.ChatMessageTypeTool, Parts: []llms.ContentPart{llms.ToolCallResponse{ToolCallID: "call-1", Name: "search", Content: "found"}}},
+		},
+		Metadata: map[string]interface{}{"list": []interface{}{"a", true}, "nested": map[string]interface{}{"x": float64(2)}},
+	}
+	s.SetThreadID("t1")
+	s.SetNode("chat")
+	s.SetNextNodes([]string{"tools"})
+	s.SetPendingTasks([]state.PendingTask{{Node: "tools", State: pending}})
+	s.SetPendingWrites([]state.PendingWrite{{Node: "chat", State: pending}})
+
+	encoded, err := EncodeState(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeState(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := s.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := decoded.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(expected) != string(actual) {
+		t.Fatalf("state changed after round trip\n%s\n%s", expected, actual)
+	}
+
+	if _, err := EncodeMessage(llms.MessageContent{Parts: []llms.ContentPart{nil}}); err == nil {
+		t.Fatal("expected unsupported part to fail")
+	}
+}
@@ -0,0 +1,82 @@
+package httpstream
+
+import (
+	"encoding/json"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/server/internal/runutil"
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
+)
+
+// ToolCall 工具调用事件的 JSON 表示
+type ToolCall struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments,omitempty"`
+	Result    string `json:"result,omitempty"`
+}
+
+// Event 流式事件的 JSON 表示，State 和 Update 使用 state.State 的序列化格式
+type Event struct {
+	ID           string          `json:"id,omitempty"`
+	Type         string          `json:"type"`
+	Node         string          `json:"node,omitempty"`
+	ThreadID     string          `json:"thread_id,omitempty"`
+	RunID        string          `json:"run_id,omitempty"`
+	Chunk        string          `json:"chunk,omitempty"`
+	State        json.RawMessage `json:"state,omitempty"`
+	Update       json.RawMessage `json:"update,omitempty"`
+	NextNodes    []string        `json:"next_nodes,omitempty"`
+	DurationMs   int64           `json:"duration_ms,omitempty"`
+	ToolCall     *ToolCall       `json:"tool_call,omitempty"`
+	CheckpointID string          `json:"checkpoint_id,omitempty"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// NewEvent 把流式事件转换为 JSON 表示
+func NewEvent(event *flowcontract.FlowStreamEvent) (*Event, error) {
+	result := &Event{
+		Type:         string(event.Type),
+		Node:         event.Node,
+		ThreadID:     event.ThreadID,
+		RunID:        event.RunID,
+		Chunk:        event.Chunk,
+		NextNodes:    event.NextNodes,
+		DurationMs:   event.Duration.Milliseconds(),
+		CheckpointID: event.CheckpointID,
+	}
+
+	var err error
+	if result.State, err = serializeState(event.FullState); err != nil {
+		return nil, err
+	}
+	if result.Update, err = serializeState(event.Update); err != nil {
+		return nil, err
+	}
+
+	if event.ToolCall != nil {
+		result.ToolCall = &ToolCall{
+			ID:        event.ToolCall.ID,
+			Name:      event.ToolCall.Name,
+			Arguments: event.ToolCall.Arguments,
+			Result:    event.ToolCall.Result,
+		}
+	}
+	if event.Err != nil {
+		result.Error = runutil.ErrorMessage(event.Err)
+	}
+
+	return result, nil
+}
+
+func serializeState(s *state.State) (json.RawMessage, error) {
+	if s == nil {
+		return nil, nil
+	}
+	data, err := s.Serialize()
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+	return data, nil
+}
@@ -0,0 +1,435 @@
+package httpstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/flow"
+	"github.com/futurxlab/golanggraph/logger"
+	"github.com/futurxlab/golanggraph/server/internal/runutil"
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+const (
+	DefaultHeartbeatInterval = time.Second * 15
+
+	// LastEventIDHeader 断线重连时 EventSource 自动带上的最后一个事件 ID
+	LastEventIDHeader = "Last-Event-ID"
+	// LastEventIDParam 无法设置请求头的客户端（例如 WebSocket）通过该查询参数传递最后一个事件 ID
+	LastEventIDParam = "last_event_id"
+	// StreamModeParam 逗号分隔的输出模式，覆盖 WithStreamModes 的配置
+	StreamModeParam = "stream_mode"
+)
+
+type Options struct {
+	Logger logger.ILogger
+	// HeartbeatInterval 没有事件时发送心跳的间隔，默认为 DefaultHeartbeatInterval，小于 0 时不发送
+	HeartbeatInterval time.Duration
+	// StreamModes 默认的输出模式，为空时使用 flow 的默认模式
+	StreamModes []flowcontract.StreamMode
+	// EnableWebSocket 为 true 时 WebSocket 握手请求以 WebSocket 消息输出事件
+	EnableWebSocket bool
+	// AllowedOrigins 除同源外允许发起 WebSocket 握手的 Origin，"*" 允许任意来源，默认只允许同源
+	AllowedOrigins []string
+	// DecodeState 从请求中解析执行的初始状态，默认为 DecodeRequest
+	DecodeState func(r *http.Request) (state.State, error)
+	// DecodeInput 从请求中解析线程 ID 和写入本轮输入的函数，设置后优先于 DecodeState，
+	// 以 Flow.ContinueStream 在线程最新的状态上执行
+	DecodeInput func(r *http.Request) (threadID string, update func(current *state.State) error, err error)
+}
+
+type Option func(*Options)
+
+func WithLogger(logger logger.ILogger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+func WithHeartbeatInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.HeartbeatInterval = interval
+	}
+}
+
+func WithStreamModes(modes ...flowcontract.StreamMode) Option {
+	return func(o *Options) {
+		o.StreamModes = modes
+	}
+}
+
+func WithWebSocket() Option {
+	return func(o *Options) {
+		o.EnableWebSocket = true
+	}
+}
+
+// WithAllowedOrigins 允许跨域的 WebSocket 握手，例如 "https://app.example.com"
+func WithAllowedOrigins(origins ...string) Option {
+	return func(o *Options) {
+		o.AllowedOrigins = append(o.AllowedOrigins, origins...)
+	}
+}
+
+func WithStateDecoder(decode func(r *http.Request) (state.State, error)) Option {
+	return func(o *Options) {
+		o.DecodeState = decode
+	}
+}
+
+// WithInputDecoder 在线程最新的状态上执行新一轮，读取最新状态发生在获取线程锁之后
+func WithInputDecoder(decode func(r *http.Request) (threadID string, update func(current *state.State) error, err error)) Option {
+	return func(o *Options) {
+		o.DecodeInput = decode
+	}
+}
+
+// Handler 每个请求执行一次 Flow，并以 SSE（或 WebSocket）输出流式事件。
+// 客户端断开时取消执行；事件 ID 由最近保存的检查点 ID 和序号组成，
+// 带 Last-Event-ID 重连时先补发之后保存的检查点，再从线程最新的检查点继续执行。
+// 断开时执行被取消，重连可能与正在退出的执行重叠，Flow 应配置 ThreadLockingWait。
+type Handler struct {
+	flow    *flow.Flow
+	options *Options
+}
+
+func NewHandler(f *flow.Flow, opts ...Option) (*Handler, error) {
+	defaultLogger, err := logger.NewLogger()
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+
+	options := &Options{
+		Logger:            defaultLogger,
+		HeartbeatInterval: DefaultHeartbeatInterval,
+		DecodeState:       DecodeRequest,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &Handler{flow: f, options: options}, nil
+}
+
+// runRequest DecodeRequest 解析的请求体，查询参数 thread_id 和 message 作为默认值
+type runRequest struct {
+	ThreadID string                 `json:"thread_id"`
+	Message  string                 `json:"message"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// DecodeRequest 默认的初始状态解析：POST 请求读取 JSON 请求体，GET 请求读取查询参数，
+// message 作为用户消息追加到 History
+func DecodeRequest(r *http.Request) (state.State, error) {
+	query := r.URL.Query()
+	request := runRequest{
+		ThreadID: query.Get("thread_id"),
+		Message:  query.Get("message"),
+	}
+
+	if r.Method == http.MethodPost && r.Body != nil {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+			return state.State{}, xerror.Wrap(err)
+		}
+	}
+
+	initState := state.State{Metadata: request.Metadata}
+	if initState.Metadata == nil {
+		initState.Metadata = make(map[string]interface{})
+	}
+	if request.Message != "" {
+		initState.History = append(initState.History, llms.TextParts(llms.ChatMessageTypeHuman, request.Message))
+	}
+	initState.SetThreadID(request.ThreadID)
+
+	return initState, nil
+}
+
+// runFunc 从起始节点执行并返回流式事件
+type runFunc func(ctx context.Context) iter.Seq2[*flowcontract.FlowStreamEvent, error]
+
+// decode 解析请求，返回线程 ID 和从起始节点执行的方式
+func (h *Handler) decode(r *http.Request) (string, runFunc, error) {
+	if h.options.DecodeInput != nil {
+		threadID, update, err := h.options.DecodeInput(r)
+		if err != nil {
+			return "", nil, err
+		}
+		return threadID, func(ctx context.Context) iter.Seq2[*flowcontract.FlowStreamEvent, error] {
+			return h.flow.ContinueStream(ctx, threadID, update, flow.WithStreamMode(flowcontract.StreamModeDebug))
+		}, nil
+	}
+
+	initState, err := h.options.DecodeState(r)
+	if err != nil {
+		return "", nil, err
+	}
+	return initState.GetThreadID(), func(ctx context.Context) iter.Seq2[*flowcontract.FlowStreamEvent, error] {
+		return h.flow.Stream(ctx, initState, flow.WithStreamMode(flowcontract.StreamModeDebug))
+	}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	threadID, run, err := h.decode(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lastEventID := r.Header.Get(LastEventIDHeader)
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get(LastEventIDParam)
+	}
+	if lastEventID != "" && threadID == "" {
+		http.Error(w, "thread_id is required to resume a stream", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	var writer eventWriter
+	if h.options.EnableWebSocket && isWebSocketUpgrade(r) {
+		conn, err := upgradeWebSocket(w, r, h.options.AllowedOrigins)
+		if err != nil {
+			// Upgrader 已经写入错误响应
+			h.options.Logger.Warnf(ctx, "upgrade websocket failed %s", err)
+			return
+		}
+		defer conn.close()
+
+		// 连接接管后 r.Context() 不再感知断开，由读循环发现客户端关闭
+		go func() {
+			_ = conn.readLoop()
+			cancel()
+		}()
+		writer = conn
+	} else {
+		writer = newSSEWriter(w)
+	}
+
+	if h.options.HeartbeatInterval > 0 {
+		// 返回前等待心跳退出，避免在请求结束后写入
+		stopped := make(chan struct{})
+		defer func() {
+			cancel()
+			<-stopped
+		}()
+		go func() {
+			defer close(stopped)
+			h.heartbeat(ctx, cancel, writer)
+		}()
+	}
+
+	h.serve(ctx, writer, threadID, run, parseEventID(lastEventID), h.streamModes(r))
+}
+
+// heartbeat 定期发送心跳，写入失败说明客户端已经断开
+func (h *Handler) heartbeat(ctx context.Context, cancel context.CancelFunc, writer eventWriter) {
+	ticker := time.NewTicker(h.options.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := writer.heartbeat(); err != nil {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+func (h *Handler) streamModes(r *http.Request) []flowcontract.StreamMode {
+	param := r.URL.Query().Get(StreamModeParam)
+	if param == "" {
+		return h.options.StreamModes
+	}
+
+	modes := make([]flowcontract.StreamMode, 0)
+	for _, mode := range strings.Split(param, ",") {
+		if mode = strings.TrimSpace(mode); mode != "" {
+			modes = append(modes, flowcontract.StreamMode(mode))
+		}
+	}
+	return modes
+}
+
+// serve 执行或恢复 Flow 并输出事件。执行以 debug 模式运行以获得检查点事件，再按请求的模式过滤
+func (h *Handler) serve(ctx context.Context, writer eventWriter, threadID string, run runFunc, cursor *eventCursor, modes []flowcontract.StreamMode) {
+	var events iter.Seq2[*flowcontract.FlowStreamEvent, error]
+
+	if cursor != nil {
+		latest, err := h.replay(ctx, writer, threadID, cursor, modes)
+		if err != nil {
+			h.writeError(ctx, writer, cursor, err)
+			return
+		}
+		if latest == nil || completed(latest) {
+			return
+		}
+		// 在线程锁内重新读取最新检查点，和其他执行排队时不会基于补发时的检查点重复执行
+		events = h.flow.ResumeThreadStream(ctx, threadID, flow.WithStreamMode(flowcontract.StreamModeDebug))
+	} else {
+		cursor = &eventCursor{}
+		events = run(ctx)
+	}
+
+	ended := false
+	for event, err := range events {
+		if err != nil {
+			// 执行开始前的错误（例如线程正在执行）没有 run_end 事件
+			if !ended {
+				h.writeError(ctx, writer, cursor, err)
+			}
+			return
+		}
+
+		if event.Type == flowcontract.EventCheckpointSaved {
+			cursor.checkpointID = event.CheckpointID
+		}
+		if event.Type == flowcontract.EventRunEnd {
+			ended = true
+		}
+
+		filtered := flow.FilterEvent(event, modes...)
+		if filtered == nil {
+			continue
+		}
+		if err := h.write(writer, filtered, cursor); err != nil {
+			// 停止迭代会取消执行
+			h.options.Logger.Warnf(ctx, "write stream event failed %s", err)
+			return
+		}
+	}
+}
+
+// completed 判断检查点是否为已经结束的执行：没有待恢复节点，并且下一个节点只有结束节点
+func completed(latest *state.State) bool {
+	if len(latest.GetPendingTasks()) > 0 {
+		return false
+	}
+	for _, next := range latest.GetNextNodes() {
+		if next != flow.EndNode {
+			return false
+		}
+	}
+	return true
+}
+
+// replay 补发 cursor 之后保存的检查点，返回线程最新的检查点，线程不存在时返回 nil。
+// Checkpointer 不支持 CheckpointLister 时不补发
+func (h *Handler) replay(ctx context.Context, writer eventWriter, threadID string, cursor *eventCursor, modes []flowcontract.StreamMode) (*state.State, error) {
+	checkpointer := h.flow.Checkpointer()
+
+	lister, ok := checkpointer.(flowcontract.CheckpointLister)
+	if !ok {
+		latest, err := checkpointer.GetLastest(ctx, threadID)
+		if errors.Is(err, flowcontract.ErrCheckpointNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, xerror.Wrap(err)
+		}
+		return latest, nil
+	}
+
+	var latest *state.State
+	found := cursor.checkpointID == ""
+	for checkpoint, err := range lister.Iter(ctx, threadID) {
+		if errors.Is(err, flowcontract.ErrCheckpointNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, xerror.Wrap(err)
+		}
+
+		latest = checkpoint.State
+		if !found {
+			found = checkpoint.ID == cursor.checkpointID
+			continue
+		}
+
+		// 失败时保存的检查点记录的是待恢复节点的输入，不是节点输出
+		cursor.checkpointID = checkpoint.ID
+		if len(checkpoint.State.GetPendingTasks()) > 0 {
+			continue
+		}
+
+		filtered := flow.FilterEvent(&flowcontract.FlowStreamEvent{
+			Type:         flowcontract.EventNodeEnd,
+			Node:         checkpoint.Node,
+			ThreadID:     threadID,
+			FullState:    checkpoint.State,
+			CheckpointID: checkpoint.ID,
+		}, modes...)
+		if filtered == nil {
+			continue
+		}
+		if err := h.write(writer, filtered, cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	return latest, nil
+}
+
+func (h *Handler) write(writer eventWriter, event *flowcontract.FlowStreamEvent, cursor *eventCursor) error {
+	payload, err := NewEvent(event)
+	if err != nil {
+		return err
+	}
+	payload.ID = cursor.next()
+	return writer.writeEvent(payload)
+}
+
+func (h *Handler) writeError(ctx context.Context, writer eventWriter, cursor *eventCursor, err error) {
+	h.options.Logger.Errorf(ctx, "stream flow failed %s", err)
+	if err := writer.writeEvent(&Event{
+		ID:    cursor.next(),
+		Type:  string(flowcontract.EventError),
+		Error: runutil.ErrorMessage(err),
+	}); err != nil {
+		h.options.Logger.Warnf(ctx, "write stream error failed %s", err)
+	}
+}
+
+// eventCursor 事件 ID，格式为 <最近保存的检查点 ID>:<序号>，序号在重连后继续递增
+type eventCursor struct {
+	checkpointID string
+	seq          int
+}
+
+// parseEventID 解析客户端的最后一个事件 ID，id 为空时返回 nil
+func parseEventID(id string) *eventCursor {
+	if id == "" {
+		return nil
+	}
+
+	index := strings.LastIndex(id, ":")
+	if index < 0 {
+		return &eventCursor{checkpointID: id}
+	}
+
+	seq, _ := strconv.Atoi(id[index+1:])
+	return &eventCursor{checkpointID: id[:index], seq: seq}
+}
+
+func (c *eventCursor) next() string {
+	c.seq++
+	return fmt.Sprintf("%s:%d", c.checkpointID, c.seq)
+}
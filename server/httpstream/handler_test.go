@@ -0,0 +1,389 @@
+package httpstream
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/futurxlab/golanggraph/checkpointer"
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/edge"
+	"github.com/futurxlab/golanggraph/flow"
+	"github.com/futurxlab/golanggraph/logger"
+	"github.com/futurxlab/golanggraph/state"
+
+	"github.com/gorilla/websocket"
+	"github.com/tmc/langchaingo/llms"
+)
+
+type echoNode struct {
+	fail  atomic.Bool
+	delay time.Duration
+	// started 节点开始执行时关闭，canceled 节点发现 ctx 结束时关闭
+	started  chan struct{}
+	canceled chan struct{}
+}
+
+func (n *echoNode) Name() string {
+	return "echo"
+}
+
+func (n *echoNode) Run(ctx context.Context, currentState *state.State, streamFunc flowcontract.StreamFunc) error {
+	if n.started != nil {
+		close(n.started)
+		<-ctx.Done()
+		close(n.canceled)
+		return ctx.Err()
+	}
+	if n.fail.Load() {
+		return errors.New("upstream unavailable")
+	}
+
+	time.Sleep(n.delay)
+	if err := streamFunc(ctx, &flowcontract.FlowStreamEvent{Type: flowcontract.EventLLMToken, Chunk: "hi"}); err != nil {
+		return err
+	}
+	currentState.History = append(currentState.History, llms.TextParts(llms.ChatMessageTypeAI, "hi"))
+	return nil
+}
+
+func newTestFlow(t *testing.T, node flowcontract.Node) *flow.Flow {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := flow.NewFlowBuilder(logger).
+		SetName("stream").
+		SetCheckpointer(checkpointer.NewInMemoryCheckpointer()).
+		SetThreadLocking(flow.ThreadLockingWait).
+		AddNode(node).
+		AddEdge(edge.Edge{From: flow.StartNode, To: node.Name()}).
+		AddEdge(edge.Edge{From: node.Name(), To: flow.EndNode}).
+		Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// readSSE 读取响应中的所有事件和心跳数量
+func readSSE(t *testing.T, body io.Reader) ([]Event, int) {
+	events := make([]Event, 0)
+	heartbeats := 0
+
+	var id string
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == ": ping":
+			heartbeats++
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			var event Event
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+				t.Fatal(err)
+			}
+			if event.ID != id {
+				t.Fatalf("frame id %s does not match event id %s", id, event.ID)
+			}
+			events = append(events, event)
+		}
+	}
+	return events, heartbeats
+}
+
+func eventTypes(events []Event) []string {
+	types := make([]string, 0, len(events))
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestHandlerSSE(t *testing.T) {
+	t.Run("streams events with heartbeat", func(t *testing.T) {
+		handler, err := NewHandler(newTestFlow(t, &echoNode{delay: 50 * time.Millisecond}), WithHeartbeatInterval(10*time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		response, err := http.Post(server.URL, "application/json", strings.NewReader(`{"thread_id":"t1","message":"hello"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		if contentType := response.Header.Get("Content-Type"); contentType != "text/event-stream" {
+			t.Fatalf("unexpected content type %s", contentType)
+		}
+
+		events, heartbeats := readSSE(t, response.Body)
+		expected := []string{"run_start", "llm_token", "node_end", "run_end"}
+		if strings.Join(eventTypes(events), ",") != strings.Join(expected, ",") {
+			t.Fatalf("expected %v, got %v", expected, eventTypes(events))
+		}
+		if heartbeats == 0 {
+			t.Fatal("expected heartbeats while the node was running")
+		}
+		if events[0].ThreadID != "t1" || events[1].Chunk != "hi" || len(events[2].State) == 0 {
+			t.Fatalf("unexpected events %+v", events)
+		}
+	})
+
+	t.Run("stream mode query selects events", func(t *testing.T) {
+		handler, err := NewHandler(newTestFlow(t, &echoNode{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		response, err := http.Get(server.URL + "?thread_id=t2&message=hello&stream_mode=messages")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		events, _ := readSSE(t, response.Body)
+		expected := []string{"run_start", "llm_token", "run_end"}
+		if strings.Join(eventTypes(events), ",") != strings.Join(expected, ",") {
+			t.Fatalf("expected %v, got %v", expected, eventTypes(events))
+		}
+	})
+
+	t.Run("client disconnect cancels the run", func(t *testing.T) {
+		node := &echoNode{started: make(chan struct{}), canceled: make(chan struct{})}
+		handler, err := NewHandler(newTestFlow(t, node))
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		request, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"?thread_id=t3", nil)
+		go func() {
+			<-node.started
+			cancel()
+		}()
+		if response, err := http.DefaultClient.Do(request); err == nil {
+			_, _ = io.Copy(io.Discard, response.Body)
+			response.Body.Close()
+		}
+
+		select {
+		case <-node.canceled:
+		case <-time.After(5 * time.Second):
+			t.Fatal("run was not canceled after the client disconnected")
+		}
+	})
+
+	t.Run("reconnect replays checkpoints and resumes", func(t *testing.T) {
+		node := &echoNode{}
+		node.fail.Store(true)
+		handler, err := NewHandler(newTestFlow(t, node))
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		response, err := http.Get(server.URL + "?thread_id=t4&message=hello")
+		if err != nil {
+			t.Fatal(err)
+		}
+		events, _ := readSSE(t, response.Body)
+		response.Body.Close()
+
+		last := events[len(events)-1]
+		if last.Type != "run_end" || last.Error == "" {
+			t.Fatalf("expected failed run, got %+v", last)
+		}
+
+		// 第一个事件之后保存了起始节点的检查点，重连时补发并从失败的节点继续执行
+		node.fail.Store(false)
+		request, _ := http.NewRequest(http.MethodGet, server.URL+"?thread_id=t4&stream_mode=values", nil)
+		request.Header.Set(LastEventIDHeader, events[0].ID)
+		response, err = http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		resumed, _ := readSSE(t, response.Body)
+		expected := []string{"node_end", "run_start", "node_end", "run_end"}
+		if strings.Join(eventTypes(resumed), ",") != strings.Join(expected, ",") {
+			t.Fatalf("expected %v, got %v", expected, eventTypes(resumed))
+		}
+		if resumed[0].CheckpointID == "" || resumed[2].Node != "echo" || resumed[3].Error != "" {
+			t.Fatalf("unexpected resumed events %+v", resumed)
+		}
+		if !strings.HasSuffix(resumed[0].ID, ":2") {
+			t.Fatalf("expected sequence to continue after the last event id, got %s", resumed[0].ID)
+		}
+
+		// 执行已经结束，再次重连只补发检查点后关闭，不会开始新的执行
+		request, _ = http.NewRequest(http.MethodGet, server.URL+"?thread_id=t4", nil)
+		request.Header.Set(LastEventIDHeader, resumed[0].ID)
+		response, err = http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		replayed, _ := readSSE(t, response.Body)
+		for _, event := range replayed {
+			if event.Type != "node_end" {
+				t.Fatalf("expected only replayed checkpoints after the run ended, got %v", eventTypes(replayed))
+			}
+		}
+		if len(replayed) == 0 {
+			t.Fatal("expected checkpoints saved by the resumed run to be replayed")
+		}
+	})
+}
+
+func TestHandlerWebSocket(t *testing.T) {
+	t.Run("streams events as messages", func(t *testing.T) {
+		handler, err := NewHandler(newTestFlow(t, &echoNode{}), WithWebSocket())
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"?thread_id=ws&message=hello", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		types := make([]string, 0)
+		for {
+			var event Event
+			if err := conn.ReadJSON(&event); err != nil {
+				if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+					t.Fatal(err)
+				}
+				break
+			}
+			types = append(types, event.Type)
+		}
+
+		expected := []string{"run_start", "llm_token", "node_end", "run_end"}
+		if strings.Join(types, ",") != strings.Join(expected, ",") {
+			t.Fatalf("expected %v, got %v", expected, types)
+		}
+	})
+
+	t.Run("rejects cross origin handshakes unless allowed", func(t *testing.T) {
+		handler, err := NewHandler(newTestFlow(t, &echoNode{}), WithWebSocket(), WithAllowedOrigins("https://app.example.com"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		url := "ws" + strings.TrimPrefix(server.URL, "http") + "?thread_id=origin&message=hello"
+		_, response, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://evil.example.com"}})
+		if err == nil || response == nil || response.StatusCode != http.StatusForbidden {
+			t.Fatalf("expected cross origin handshake to be rejected, got %v", err)
+		}
+
+		for _, origin := range []string{server.URL, "https://app.example.com"} {
+			conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {origin}})
+			if err != nil {
+				t.Fatalf("expected origin %s to be allowed, got %v", origin, err)
+			}
+			conn.Close()
+		}
+	})
+
+	t.Run("unmasked client frame closes with protocol error", func(t *testing.T) {
+		node := &echoNode{started: make(chan struct{}), canceled: make(chan struct{})}
+		handler, err := NewHandler(newTestFlow(t, node), WithWebSocket())
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef"))
+		request := "GET /?thread_id=unmasked HTTP/1.1\r\n" +
+			"Host: " + strings.TrimPrefix(server.URL, "http://") + "\r\n" +
+			"Connection: Upgrade\r\n" +
+			"Upgrade: websocket\r\n" +
+			"Sec-WebSocket-Version: 13\r\n" +
+			"Sec-WebSocket-Key: " + key + "\r\n\r\n"
+		if _, err := conn.Write([]byte(request)); err != nil {
+			t.Fatal(err)
+		}
+
+		reader := bufio.NewReader(conn)
+		response, err := http.ReadResponse(reader, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.StatusCode != http.StatusSwitchingProtocols {
+			t.Fatalf("unexpected handshake response %+v", response)
+		}
+
+		<-node.started
+		// 未加掩码的文本帧
+		if _, err := conn.Write([]byte{0x81, 0x02, 'h', 'i'}); err != nil {
+			t.Fatal(err)
+		}
+
+		for {
+			var header [2]byte
+			if _, err := io.ReadFull(reader, header[:]); err != nil {
+				t.Fatal(err)
+			}
+			length := uint64(header[1] & 0x7F)
+			if length == 126 {
+				var extended [2]byte
+				_, _ = io.ReadFull(reader, extended[:])
+				length = uint64(binary.BigEndian.Uint16(extended[:]))
+			}
+			payload := make([]byte, length)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				t.Fatal(err)
+			}
+
+			if header[0]&0x0F == websocket.CloseMessage {
+				if code := binary.BigEndian.Uint16(payload); code != websocket.CloseProtocolError {
+					t.Fatalf("expected close code %d, got %d", websocket.CloseProtocolError, code)
+				}
+				break
+			}
+		}
+
+		select {
+		case <-node.canceled:
+		case <-time.After(5 * time.Second):
+			t.Fatal("run was not canceled after the protocol error")
+		}
+	})
+}
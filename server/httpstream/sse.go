@@ -0,0 +1,68 @@
+package httpstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/futurxlab/golanggraph/xerror"
+)
+
+// eventWriter 把事件写给客户端，heartbeat 与事件可能在不同的 goroutine 中写入
+type eventWriter interface {
+	writeEvent(event *Event) error
+	heartbeat() error
+}
+
+// sseWriter 以 Server-Sent Events 的格式写入事件
+type sseWriter struct {
+	mu         sync.Mutex
+	w          http.ResponseWriter
+	controller *http.ResponseController
+}
+
+func newSSEWriter(w http.ResponseWriter) *sseWriter {
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	return &sseWriter{w: w, controller: http.NewResponseController(w)}
+}
+
+func (s *sseWriter) writeEvent(event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return xerror.Wrap(err)
+	}
+
+	var frame bytes.Buffer
+	if event.ID != "" {
+		fmt.Fprintf(&frame, "id: %s\n", event.ID)
+	}
+	fmt.Fprintf(&frame, "event: %s\ndata: %s\n\n", event.Type, data)
+
+	return s.write(frame.Bytes())
+}
+
+// heartbeat 发送注释行，保持连接不被代理断开
+func (s *sseWriter) heartbeat() error {
+	return s.write([]byte(": ping\n\n"))
+}
+
+func (s *sseWriter) write(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.w.Write(data); err != nil {
+		return xerror.Wrap(err)
+	}
+	if err := s.controller.Flush(); err != nil {
+		return xerror.Wrap(err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package httpstream
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/futurxlab/golanggraph/xerror"
+
+	"github.com/gorilla/websocket"
+)
+
+// maxClientMessage 客户端消息的最大长度，客户端只需要发送控制帧
+const maxClientMessage = 1 << 16
+
+// websocketWriteTimeout 单次写入的超时时间，避免客户端不读取时阻塞执行
+const websocketWriteTimeout = time.Second * 10
+
+// isWebSocketUpgrade 判断请求是否为 WebSocket 握手
+func isWebSocketUpgrade(r *http.Request) bool {
+	return websocket.IsWebSocketUpgrade(r)
+}
+
+// checkOrigin 允许没有 Origin 的非浏览器客户端、同源请求和 allowed 中的来源，
+// allowed 中的 "*" 允许任意来源
+func checkOrigin(allowed []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+
+		u, err := url.Parse(origin)
+		if err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+
+		for _, o := range allowed {
+			if o == "*" || strings.EqualFold(o, origin) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// websocketConn 服务端向客户端发送文本消息的 WebSocket 连接，
+// 每个事件作为一条文本消息发送，客户端发送的数据消息被忽略
+type websocketConn struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+// upgradeWebSocket 完成握手并接管连接，失败时 Upgrader 已经写入错误响应
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request, allowedOrigins []string) (*websocketConn, error) {
+	upgrader := websocket.Upgrader{CheckOrigin: checkOrigin(allowedOrigins)}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+	conn.SetReadLimit(maxClientMessage)
+
+	return &websocketConn{conn: conn}, nil
+}
+
+func (c *websocketConn) writeEvent(event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return xerror.Wrap(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(websocketWriteTimeout)); err != nil {
+		return xerror.Wrap(err)
+	}
+	if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		return xerror.Wrap(err)
+	}
+	return nil
+}
+
+func (c *websocketConn) heartbeat() error {
+	if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(websocketWriteTimeout)); err != nil {
+		return xerror.Wrap(err)
+	}
+	return nil
+}
+
+// readLoop 读取客户端的消息直到连接关闭或出错，ping 和 close 由 websocket.Conn 响应，
+// 协议错误（例如未加掩码的帧）会以 1002 关闭连接
+func (c *websocketConn) readLoop() error {
+	for {
+		if _, _, err := c.conn.NextReader(); err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return nil
+			}
+			return xerror.Wrap(err)
+		}
+	}
+}
+
+// close 发送关闭帧后关闭连接
+func (c *websocketConn) close() error {
+	message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	_ = c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(websocketWriteTimeout))
+	return c.conn.Close()
+}
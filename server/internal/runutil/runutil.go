@@ -0,0 +1,64 @@
+// Package runutil 各个协议适配器（SSE、REST、OpenAI、MCP、A2A、AG-UI、gRPC）共用的执行辅助函数
+package runutil
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// ErrorMessage 返回发给客户端的错误信息，去掉 xerror 附加的调用栈
+func ErrorMessage(err error) string {
+	message, _, _ := strings.Cut(err.Error(), "\n")
+	return message
+}
+
+// TextOf 返回消息中的文本内容
+func TextOf(message llms.MessageContent) string {
+	var builder strings.Builder
+	for _, part := range message.Parts {
+		if text, ok := part.(llms.TextContent); ok {
+			builder.WriteString(text.Text)
+		}
+	}
+	return builder.String()
+}
+
+// Reply 返回执行新增的最后一条 AI 消息的文本，inputLen 为执行前 History 的长度
+func Reply(history []llms.MessageContent, inputLen int) string {
+	if inputLen > len(history) {
+		inputLen = 0
+	}
+	for i := len(history) - 1; i >= inputLen; i-- {
+		if history[i].Role == llms.ChatMessageTypeAI {
+			return TextOf(history[i])
+		}
+	}
+	return ""
+}
+
+// LatestCheckpoint 返回线程最新的检查点，Checkpointer 不支持 CheckpointLister 时只有状态没有元信息
+func LatestCheckpoint(ctx context.Context, checkpointer flowcontract.Checkpointer, threadID string) (*state.State, *flowcontract.Checkpoint, error) {
+	if lister, ok := checkpointer.(flowcontract.CheckpointLister); ok {
+		checkpoints, _, err := lister.List(ctx, threadID, flowcontract.WithReverse(), flowcontract.WithLimit(1))
+		if err != nil {
+			return nil, nil, xerror.Wrap(err)
+		}
+		if len(checkpoints) == 0 {
+			return nil, nil, xerror.Wrap(fmt.Errorf("thread %s: %w", threadID, flowcontract.ErrCheckpointNotFound))
+		}
+		return checkpoints[0].State, checkpoints[0], nil
+	}
+
+	latest, err := checkpointer.GetLastest(ctx, threadID)
+	if err != nil {
+		return nil, nil, xerror.Wrap(err)
+	}
+	return latest, nil, nil
+}
@@ -0,0 +1,198 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/flow"
+	"github.com/futurxlab/golanggraph/logger"
+	"github.com/futurxlab/golanggraph/server/internal/runutil"
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/tmc/langchaingo/llms"
+)
+
+const (
+	// MessageArgument 工具参数中的用户消息
+	MessageArgument = "message"
+	// ThreadIDArgument 工具参数中的线程 ID，传入上次调用返回的线程 ID 可以继续对话
+	ThreadIDArgument = "thread_id"
+
+	DefaultVersion = "1.0.0"
+)
+
+type Options struct {
+	Logger logger.ILogger
+	// ToolName 工具名称，默认为 Flow 的名称
+	ToolName string
+	// Description 工具描述，调用方的模型依据它决定何时调用
+	Description string
+	// Version MCP 服务的版本，默认为 DefaultVersion
+	Version string
+	// ServerOptions 创建 MCPServer 的额外选项
+	ServerOptions []server.ServerOption
+}
+
+type Option func(*Options)
+
+func WithLogger(logger logger.ILogger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+func WithToolName(name string) Option {
+	return func(o *Options) {
+		o.ToolName = name
+	}
+}
+
+func WithDescription(description string) Option {
+	return func(o *Options) {
+		o.Description = description
+	}
+}
+
+func WithVersion(version string) Option {
+	return func(o *Options) {
+		o.Version = version
+	}
+}
+
+func WithServerOptions(opts ...server.ServerOption) Option {
+	return func(o *Options) {
+		o.ServerOptions = append(o.ServerOptions, opts...)
+	}
+}
+
+// ToolResult 工具调用的结构化结果
+type ToolResult struct {
+	ThreadID string `json:"thread_id"`
+	Reply    string `json:"reply"`
+}
+
+// Server 把 Flow 发布为 MCP 服务中的一个工具。每次调用以 message 作为用户消息执行一次 Flow，
+// 带 thread_id 时在线程最新的状态上继续；调用方请求进度通知时 LLM token 以 notifications/progress 发送
+type Server struct {
+	flow    *flow.Flow
+	options *Options
+	mcp     *server.MCPServer
+}
+
+func NewServer(f *flow.Flow, opts ...Option) (*Server, error) {
+	defaultLogger, err := logger.NewLogger()
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+
+	options := &Options{
+		Logger:      defaultLogger,
+		ToolName:    f.Name(),
+		Description: "Run the " + f.Name() + " agent with a message and return its reply",
+		Version:     DefaultVersion,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	s := &Server{
+		flow:    f,
+		options: options,
+		mcp:     server.NewMCPServer(f.Name(), options.Version, append([]server.ServerOption{server.WithToolCapabilities(false)}, options.ServerOptions...)...),
+	}
+
+	s.mcp.AddTool(mcp.NewTool(options.ToolName,
+		mcp.WithDescription(options.Description),
+		mcp.WithString(MessageArgument, mcp.Required(), mcp.Description("The user message sent to the agent")),
+		mcp.WithString(ThreadIDArgument, mcp.Description("Thread ID returned by a previous call, to continue that conversation")),
+	), s.callTool)
+
+	return s, nil
+}
+
+// MCPServer 返回底层的 MCPServer，可以继续添加工具、资源或提示词
+func (s *Server) MCPServer() *server.MCPServer {
+	return s.mcp
+}
+
+// ServeStdio 通过标准输入输出提供服务，直到 ctx 结束或输入关闭
+func (s *Server) ServeStdio(ctx context.Context, stdin io.Reader, stdout io.Writer) error {
+	if err := server.NewStdioServer(s.mcp).Listen(ctx, stdin, stdout); err != nil && !errors.Is(err, context.Canceled) {
+		return xerror.Wrap(err)
+	}
+	return nil
+}
+
+// SSEHandler 返回 SSE 传输的 http.Handler，默认路径为 /sse 和 /message
+func (s *Server) SSEHandler(opts ...server.SSEOption) http.Handler {
+	return server.NewSSEServer(s.mcp, opts...)
+}
+
+// StreamableHTTPHandler 返回 Streamable HTTP 传输的 http.Handler，默认路径为 /mcp
+func (s *Server) StreamableHTTPHandler(opts ...server.StreamableHTTPOption) http.Handler {
+	return server.NewStreamableHTTPServer(s.mcp, opts...)
+}
+
+// callTool 执行 Flow，执行失败作为工具错误返回给调用方的模型，而不是协议错误
+func (s *Server) callTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	message, err := request.RequireString(MessageArgument)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// 线程存在时在线程最新的状态上追加消息，inputLen 为执行前 History 的长度
+	inputLen := 0
+	update := func(current *state.State) error {
+		current.History = append(current.History, llms.TextParts(llms.ChatMessageTypeHuman, message))
+		inputLen = len(current.History)
+		return nil
+	}
+
+	result, err := s.flow.Continue(ctx, request.GetString(ThreadIDArgument, ""), update, s.progressFunc(request), flow.WithStreamMode(flowcontract.StreamModeMessages))
+	if err != nil {
+		s.options.Logger.Errorf(ctx, "call tool %s failed %s", s.options.ToolName, err)
+		return mcp.NewToolResultError(runutil.ErrorMessage(err)), nil
+	}
+
+	toolResult := &ToolResult{ThreadID: result.GetThreadID(), Reply: runutil.Reply(result.History, inputLen)}
+	// 按 MCP 规范同时以 JSON 文本返回结构化结果，不支持 structuredContent 的客户端也能拿到线程 ID
+	text, err := json.Marshal(toolResult)
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+	return mcp.NewToolResultStructured(toolResult, string(text)), nil
+}
+
+// progressFunc 调用方带 progressToken 时把 LLM token 作为进度通知发送，否则不输出
+func (s *Server) progressFunc(request mcp.CallToolRequest) flowcontract.StreamFunc {
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return nil
+	}
+
+	token := request.Params.Meta.ProgressToken
+	// 并行节点可能同时输出 token
+	var progress atomic.Int64
+	return func(ctx context.Context, event *flowcontract.FlowStreamEvent) error {
+		if event.Type != flowcontract.EventLLMToken || event.Chunk == "" {
+			return nil
+		}
+
+		if err := s.mcp.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+			"progressToken": token,
+			"progress":      progress.Add(1),
+			"message":       event.Chunk,
+		}); err != nil {
+			// 进度通知发送失败不影响执行
+			s.options.Logger.Warnf(ctx, "send progress notification failed %s", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,199 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/futurxlab/golanggraph/checkpointer"
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/edge"
+	"github.com/futurxlab/golanggraph/flow"
+	"github.com/futurxlab/golanggraph/logger"
+	"github.com/futurxlab/golanggraph/state"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// countNode 回复当前线程中的消息数，并以 token 事件输出
+type countNode struct{}
+
+func (n *countNode) Name() string {
+	return "count"
+}
+
+func (n *countNode) Run(ctx context.Context, currentState *state.State, streamFunc flowcontract.StreamFunc) error {
+	reply := "messages: " + string(rune('0'+len(currentState.History)))
+	if err := streamFunc(ctx, &flowcontract.FlowStreamEvent{Type: flowcontract.EventLLMToken, Chunk: reply}); err != nil {
+		return err
+	}
+	currentState.History = append(currentState.History, llms.TextParts(llms.ChatMessageTypeAI, reply))
+	return nil
+}
+
+func newTestServer(t *testing.T) *Server {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := &countNode{}
+	f, err := flow.NewFlowBuilder(logger).
+		SetName("counter").
+		SetCheckpointer(checkpointer.NewInMemoryCheckpointer()).
+		AddNode(node).
+		AddEdge(edge.Edge{From: flow.StartNode, To: node.Name()}).
+		AddEdge(edge.Edge{From: node.Name(), To: flow.EndNode}).
+		Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewServer(f, WithLogger(logger), WithDescription("Counts messages"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func initialize(t *testing.T, ctx context.Context, c *client.Client) {
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	request := mcp.InitializeRequest{}
+	request.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	request.Params.ClientInfo = mcp.Implementation{Name: "test", Version: "1.0.0"}
+	if _, err := c.Initialize(ctx, request); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func callTool(t *testing.T, ctx context.Context, c *client.Client, arguments map[string]any, progressToken mcp.ProgressToken) *mcp.CallToolResult {
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "counter"
+	request.Params.Arguments = arguments
+	if progressToken != nil {
+		request.Params.Meta = &mcp.Meta{ProgressToken: progressToken}
+	}
+
+	result, err := c.CallTool(ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func structured(t *testing.T, result *mcp.CallToolResult) (string, string) {
+	if result.IsError {
+		t.Fatalf("unexpected tool error %+v", result.Content)
+	}
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("unexpected content %#v", result.Content)
+	}
+	toolResult := &ToolResult{}
+	if err := json.Unmarshal([]byte(text.Text), toolResult); err != nil {
+		t.Fatal(err)
+	}
+	return toolResult.ThreadID, toolResult.Reply
+}
+
+func TestStreamableHTTP(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	httpServer := httptest.NewServer(newTestServer(t).StreamableHTTPHandler())
+	defer httpServer.Close()
+
+	c, err := client.NewStreamableHttpClient(httpServer.URL + "/mcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	initialize(t, ctx, c)
+
+	tools, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools.Tools) != 1 || tools.Tools[0].Name != "counter" || tools.Tools[0].Description != "Counts messages" {
+		t.Fatalf("unexpected tools %+v", tools.Tools)
+	}
+	if required := tools.Tools[0].InputSchema.Required; len(required) != 1 || required[0] != MessageArgument {
+		t.Fatalf("unexpected required arguments %v", required)
+	}
+
+	threadID, reply := structured(t, callTool(t, ctx, c, map[string]any{MessageArgument: "hi"}, nil))
+	if threadID == "" || reply != "messages: 1" {
+		t.Fatalf("unexpected first result %s %s", threadID, reply)
+	}
+
+	// 带上次返回的线程 ID 继续对话
+	_, reply = structured(t, callTool(t, ctx, c, map[string]any{MessageArgument: "again", ThreadIDArgument: threadID}, nil))
+	if reply != "messages: 3" {
+		t.Fatalf("expected thread to continue, got %s", reply)
+	}
+}
+
+func TestStdio(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- newTestServer(t).ServeStdio(ctx, serverIn, serverOut)
+	}()
+
+	c := client.NewClient(transport.NewIO(clientIn, clientOut, io.NopCloser(&io.LimitedReader{})))
+
+	var mu sync.Mutex
+	var progress []string
+	c.OnNotification(func(notification mcp.JSONRPCNotification) {
+		if notification.Method != "notifications/progress" {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		message, _ := notification.Params.AdditionalFields["message"].(string)
+		progress = append(progress, message)
+	})
+	initialize(t, ctx, c)
+
+	if _, reply := structured(t, callTool(t, ctx, c, map[string]any{MessageArgument: "hi"}, "p1")); reply != "messages: 1" {
+		t.Fatalf("unexpected reply %s", reply)
+	}
+
+	// 客户端异步分发通知
+	received := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), progress...)
+	}
+	for deadline := time.Now().Add(time.Second * 2); len(received()) == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if got := received(); len(got) != 1 || got[0] != "messages: 1" {
+		t.Fatalf("unexpected progress notifications %v", got)
+	}
+
+	result := callTool(t, ctx, c, map[string]any{}, nil)
+	if !result.IsError {
+		t.Fatalf("expected missing message to be a tool error")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,307 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/flow"
+	"github.com/futurxlab/golanggraph/logger"
+	"github.com/futurxlab/golanggraph/server/internal/runutil"
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
+
+	"github.com/google/uuid"
+)
+
+// DefaultThreadHeader 默认的线程 ID 请求头，响应中也会带上该请求头
+const DefaultThreadHeader = "X-Thread-ID"
+
+const (
+	finishReasonStop = "stop"
+	roleAssistant    = "assistant"
+)
+
+// errBadRequest 请求参数错误
+var errBadRequest = errors.New("bad request")
+
+type Options struct {
+	Logger logger.ILogger
+	// Model 请求没有指定 model 时响应中的模型名称，默认为 Flow 的名称
+	Model string
+	// ThreadHeader 线程 ID 请求头，默认为 DefaultThreadHeader
+	ThreadHeader string
+	// AnswerNode 流式请求只实时输出该节点的 LLM token；为空时实时输出所有节点的 token，
+	// 流程中有不属于回复的 LLM 输出（例如规划、工具调用）时需要配置
+	AnswerNode string
+	// UserAsThread 为 true 时没有线程 ID 请求头的请求以 user 字段作为线程 ID。
+	// user 由客户端任意填写，只应在可信的调用方之间开启
+	UserAsThread bool
+}
+
+type Option func(*Options)
+
+func WithLogger(logger logger.ILogger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+func WithModel(model string) Option {
+	return func(o *Options) {
+		o.Model = model
+	}
+}
+
+func WithThreadHeader(header string) Option {
+	return func(o *Options) {
+		o.ThreadHeader = header
+	}
+}
+
+// WithAnswerNode 流式请求只实时输出 node 的 LLM token，其他节点（例如规划、工具调用）的 token 不输出
+func WithAnswerNode(node string) Option {
+	return func(o *Options) {
+		o.AnswerNode = node
+	}
+}
+
+// WithUserAsThread 没有线程 ID 请求头时以 user 字段作为线程 ID
+func WithUserAsThread() Option {
+	return func(o *Options) {
+		o.UserAsThread = true
+	}
+}
+
+// Handler 以 OpenAI chat completion 接口（POST /v1/chat/completions）执行 Flow。
+// 请求带线程 ID（ThreadHeader 请求头，开启 UserAsThread 时也可以是 user 字段）时在线程最新的状态上继续对话，
+// 只追加最后一条 assistant 消息之后的新消息，线程已有历史时忽略 system 消息；
+// 没有线程 ID 时每个请求都以 messages 作为完整的对话执行。
+// 响应内容为执行新增的最后一条 AI 消息；流式请求实时输出 LLM token（配置了 AnswerNode 时只输出该节点的 token），
+// 没有输出任何 token 时在结束时一次性输出回复
+type Handler struct {
+	flow    *flow.Flow
+	options *Options
+}
+
+func NewHandler(f *flow.Flow, opts ...Option) (*Handler, error) {
+	defaultLogger, err := logger.NewLogger()
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+
+	options := &Options{
+		Logger:       defaultLogger,
+		Model:        f.Name(),
+		ThreadHeader: DefaultThreadHeader,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &Handler{flow: f, options: options}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		h.writeJSON(w, http.StatusMethodNotAllowed, &errorResponse{Error: errorBody{
+			Message: "method not allowed",
+			Type:    "invalid_request_error",
+		}})
+		return
+	}
+
+	request := &ChatCompletionRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		h.writeError(w, r, xerror.Wrap(fmt.Errorf("%w: %s", errBadRequest, err)))
+		return
+	}
+	if len(request.Messages) == 0 {
+		h.writeError(w, r, xerror.Wrap(fmt.Errorf("%w: messages is required", errBadRequest)))
+		return
+	}
+
+	threadID := r.Header.Get(h.options.ThreadHeader)
+	if threadID == "" && h.options.UserAsThread {
+		threadID = request.User
+	}
+
+	input := &turn{messages: request.Messages}
+	if threadID != "" {
+		w.Header().Set(h.options.ThreadHeader, threadID)
+	}
+
+	completion := &ChatCompletion{
+		ID:      "chatcmpl-" + uuid.New().String(),
+		Created: time.Now().Unix(),
+		Model:   request.Model,
+	}
+	if completion.Model == "" {
+		completion.Model = h.options.Model
+	}
+
+	if request.Stream {
+		h.stream(w, r, threadID, input, completion)
+		return
+	}
+	h.complete(w, r, threadID, input, completion)
+}
+
+// turn 一次请求的输入，由 Flow.Continue 在线程最新的状态上写入
+type turn struct {
+	messages []ChatMessage
+	// inputLen 执行前 History 的长度，apply 之后有效
+	inputLen int
+}
+
+// apply 线程已有历史时只追加新一轮的消息，否则追加全部消息
+func (t *turn) apply(current *state.State) error {
+	messages := t.messages
+	if len(current.History) > 0 {
+		messages = newTurn(messages)
+	}
+	for i := range messages {
+		message, err := messages[i].toMessageContent()
+		if err != nil {
+			return err
+		}
+		current.History = append(current.History, message)
+	}
+
+	t.inputLen = len(current.History)
+	return nil
+}
+
+// newTurn 返回最后一条 assistant 消息之后的非 system 消息，即线程中还没有的新一轮输入
+func newTurn(messages []ChatMessage) []ChatMessage {
+	start := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "assistant" {
+			start = i + 1
+			break
+		}
+	}
+
+	turn := make([]ChatMessage, 0, len(messages)-start)
+	for _, message := range messages[start:] {
+		if message.Role == "system" || message.Role == "developer" {
+			continue
+		}
+		turn = append(turn, message)
+	}
+	return turn
+}
+
+func (h *Handler) complete(w http.ResponseWriter, r *http.Request, threadID string, input *turn, completion *ChatCompletion) {
+	result, err := h.flow.Continue(r.Context(), threadID, input.apply, nil)
+	if err != nil {
+		h.writeError(w, r, xerror.Wrap(err))
+		return
+	}
+
+	stop := finishReasonStop
+	completion.Object = "chat.completion"
+	completion.Choices = []Choice{{
+		Message: &ResponseMessage{
+			Role:    roleAssistant,
+			Content: runutil.Reply(result.History, input.inputLen),
+		},
+		FinishReason: &stop,
+	}}
+
+	h.writeJSON(w, http.StatusOK, completion)
+}
+
+// stream 以 chat.completion.chunk 输出 LLM token，第一个事件到达后才写入响应头，
+// 执行开始前的错误（例如线程被占用）仍以错误状态码返回
+func (h *Handler) stream(w http.ResponseWriter, r *http.Request, threadID string, input *turn, completion *ChatCompletion) {
+	completion.Object = "chat.completion.chunk"
+
+	var writer *chunkWriter
+	streamed := false
+	for event, err := range h.flow.ContinueStream(r.Context(), threadID, input.apply, flow.WithStreamMode(flowcontract.StreamModeMessages)) {
+		if err != nil {
+			if writer == nil {
+				h.writeError(w, r, xerror.Wrap(err))
+				return
+			}
+			h.options.Logger.Errorf(r.Context(), "chat completion stream failed %s", err)
+			if err := writer.writeError(err); err != nil {
+				h.options.Logger.Warnf(r.Context(), "write chat completion error failed %s", err)
+			}
+			return
+		}
+
+		if writer == nil {
+			writer = newChunkWriter(w, completion)
+			if err := writer.writeDelta(&ResponseMessage{Role: roleAssistant}, nil); err != nil {
+				h.options.Logger.Warnf(r.Context(), "write chat completion chunk failed %s", err)
+				return
+			}
+		}
+
+		content := ""
+		switch event.Type {
+		case flowcontract.EventLLMToken:
+			if h.options.AnswerNode != "" && event.Node != h.options.AnswerNode {
+				continue
+			}
+			content = event.Chunk
+			streamed = streamed || content != ""
+		case flowcontract.EventRunEnd:
+			if event.Err == nil && !streamed && event.FullState != nil {
+				content = runutil.Reply(event.FullState.History, input.inputLen)
+			}
+		}
+		if content == "" {
+			continue
+		}
+		if err := writer.writeDelta(&ResponseMessage{Content: content}, nil); err != nil {
+			h.options.Logger.Warnf(r.Context(), "write chat completion chunk failed %s", err)
+			return
+		}
+	}
+
+	if writer == nil {
+		return
+	}
+	stop := finishReasonStop
+	if err := writer.writeDelta(&ResponseMessage{}, &stop); err != nil {
+		h.options.Logger.Warnf(r.Context(), "write chat completion chunk failed %s", err)
+		return
+	}
+	if err := writer.done(); err != nil {
+		h.options.Logger.Warnf(r.Context(), "write chat completion done failed %s", err)
+	}
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.options.Logger.Warnf(context.Background(), "write response failed %s", err)
+	}
+}
+
+// writeError 以 OpenAI 的错误格式返回
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, errorType := http.StatusInternalServerError, "server_error"
+	switch {
+	case errors.Is(err, errBadRequest):
+		status, errorType = http.StatusBadRequest, "invalid_request_error"
+	case errors.Is(err, flowcontract.ErrThreadBusy):
+		status, errorType = http.StatusConflict, "invalid_request_error"
+	default:
+		h.options.Logger.Errorf(r.Context(), "chat completion failed %s", err)
+	}
+
+	h.writeJSON(w, status, &errorResponse{Error: errorBody{
+		Message: runutil.ErrorMessage(err),
+		Type:    errorType,
+	}})
+}
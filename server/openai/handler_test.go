@@ -0,0 +1,282 @@
+package openai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/futurxlab/golanggraph/checkpointer"
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/edge"
+	"github.com/futurxlab/golanggraph/flow"
+	"github.com/futurxlab/golanggraph/logger"
+	"github.com/futurxlab/golanggraph/server/internal/runutil"
+	"github.com/futurxlab/golanggraph/state"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// echoNode 回复最后一条用户消息，并以两个 token 事件输出
+type echoNode struct {
+	tokens bool
+}
+
+func (n *echoNode) Name() string {
+	return "echo"
+}
+
+func (n *echoNode) Run(ctx context.Context, currentState *state.State, streamFunc flowcontract.StreamFunc) error {
+	text := runutil.TextOf(currentState.History[len(currentState.History)-1])
+	if n.tokens {
+		for _, chunk := range []string{"echo: ", text} {
+			if err := streamFunc(ctx, &flowcontract.FlowStreamEvent{Type: flowcontract.EventLLMToken, Chunk: chunk}); err != nil {
+				return err
+			}
+		}
+	}
+	currentState.History = append(currentState.History, llms.TextParts(llms.ChatMessageTypeAI, "echo: "+text))
+	return nil
+}
+
+// plannerNode 输出不属于回复的 token
+type plannerNode struct{}
+
+func (n *plannerNode) Name() string {
+	return "planner"
+}
+
+func (n *plannerNode) Run(ctx context.Context, currentState *state.State, streamFunc flowcontract.StreamFunc) error {
+	return streamFunc(ctx, &flowcontract.FlowStreamEvent{Type: flowcontract.EventLLMToken, Chunk: "thinking..."})
+}
+
+func newTestHandler(t *testing.T, tokens bool, opts ...Option) (*httptest.Server, flowcontract.Checkpointer) {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	memory := checkpointer.NewInMemoryCheckpointer()
+	planner, node := &plannerNode{}, &echoNode{tokens: tokens}
+	f, err := flow.NewFlowBuilder(logger).
+		SetName("echo-agent").
+		SetCheckpointer(memory).
+		AddNode(planner).
+		AddNode(node).
+		AddEdge(edge.Edge{From: flow.StartNode, To: planner.Name()}).
+		AddEdge(edge.Edge{From: planner.Name(), To: node.Name()}).
+		AddEdge(edge.Edge{From: node.Name(), To: flow.EndNode}).
+		Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler, err := NewHandler(f, append([]Option{WithLogger(logger)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server, memory
+}
+
+func post(t *testing.T, url string, threadID string, body string) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if threadID != "" {
+		req.Header.Set(DefaultThreadHeader, threadID)
+	}
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { response.Body.Close() })
+	return response
+}
+
+func decodeCompletion(t *testing.T, response *http.Response) *ChatCompletion {
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d", response.StatusCode)
+	}
+	completion := &ChatCompletion{}
+	if err := json.NewDecoder(response.Body).Decode(completion); err != nil {
+		t.Fatal(err)
+	}
+	return completion
+}
+
+func TestChatCompletion(t *testing.T) {
+	server, memory := newTestHandler(t, true)
+
+	t.Run("stateless", func(t *testing.T) {
+		completion := decodeCompletion(t, post(t, server.URL, "", `{
+			"model": "gpt-4o",
+			"messages": [
+				{"role": "system", "content": "be brief"},
+				{"role": "user", "content": [{"type": "text", "text": "hi"}]}
+			]
+		}`))
+
+		if completion.Object != "chat.completion" || completion.Model != "gpt-4o" || len(completion.Choices) != 1 {
+			t.Fatalf("unexpected completion %+v", completion)
+		}
+		choice := completion.Choices[0]
+		if choice.Message.Role != "assistant" || choice.Message.Content != "echo: hi" || *choice.FinishReason != "stop" {
+			t.Fatalf("unexpected choice %+v", choice.Message)
+		}
+	})
+
+	t.Run("thread header continues the conversation", func(t *testing.T) {
+		response := post(t, server.URL, "t1", `{"messages": [{"role": "system", "content": "be brief"}, {"role": "user", "content": "hello"}]}`)
+		if response.Header.Get(DefaultThreadHeader) != "t1" {
+			t.Fatalf("expected thread header in response")
+		}
+		decodeCompletion(t, response)
+
+		// 客户端重发完整对话，只有最后一轮追加到线程
+		completion := decodeCompletion(t, post(t, server.URL, "t1", `{"messages": [
+			{"role": "system", "content": "be brief"},
+			{"role": "user", "content": "hello"},
+			{"role": "assistant", "content": "echo: hello"},
+			{"role": "user", "content": "again"}
+		]}`))
+		if completion.Choices[0].Message.Content != "echo: again" {
+			t.Fatalf("unexpected reply %+v", completion.Choices[0].Message)
+		}
+
+		latest, err := memory.GetLastest(context.Background(), "t1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(latest.History) != 5 {
+			t.Fatalf("expected 5 messages in thread, got %d", len(latest.History))
+		}
+	})
+
+	t.Run("user field is not a thread by default", func(t *testing.T) {
+		decodeCompletion(t, post(t, server.URL, "", `{"user": "u0", "messages": [{"role": "user", "content": "one"}]}`))
+
+		if _, err := memory.GetLastest(context.Background(), "u0"); err == nil {
+			t.Fatal("expected user field to be ignored without WithUserAsThread")
+		}
+	})
+
+	t.Run("user field as thread", func(t *testing.T) {
+		server, memory := newTestHandler(t, true, WithUserAsThread())
+		decodeCompletion(t, post(t, server.URL, "", `{"user": "u1", "messages": [{"role": "user", "content": "one"}]}`))
+		decodeCompletion(t, post(t, server.URL, "", `{"user": "u1", "messages": [{"role": "user", "content": "two"}]}`))
+
+		latest, err := memory.GetLastest(context.Background(), "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(latest.History) != 4 {
+			t.Fatalf("expected 4 messages in thread, got %d", len(latest.History))
+		}
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		response := post(t, server.URL, "", `{"messages": [{"role": "robot", "content": "hi"}]}`)
+		var body errorResponse
+		if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if response.StatusCode != http.StatusBadRequest || body.Error.Type != "invalid_request_error" {
+			t.Fatalf("unexpected error %d %+v", response.StatusCode, body)
+		}
+	})
+}
+
+// readChunks 读取流式响应中的 chunk，返回拼接的内容和是否收到 [DONE]
+func readChunks(t *testing.T, response *http.Response) (string, []*ChatCompletion, bool) {
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected response %d %s", response.StatusCode, response.Header.Get("Content-Type"))
+	}
+
+	var content strings.Builder
+	var chunks []*ChatCompletion
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			return content.String(), chunks, true
+		}
+
+		chunk := &ChatCompletion{}
+		if err := json.Unmarshal([]byte(data), chunk); err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, chunk)
+		content.WriteString(chunk.Choices[0].Delta.Content)
+	}
+	return content.String(), chunks, false
+}
+
+func TestChatCompletionStream(t *testing.T) {
+	for _, c := range []struct {
+		tokens     bool
+		answerNode string
+		content    string
+		// 角色和结束各一个 chunk，其余为内容
+		chunks int
+	}{
+		// 默认输出所有节点的 token，包括规划节点
+		{tokens: true, answerNode: "", content: "thinking...echo: hi", chunks: 5},
+		{tokens: true, answerNode: "echo", content: "echo: hi", chunks: 4},
+		{tokens: false, answerNode: "", content: "thinking...", chunks: 3},
+		// 回复节点没有输出 token 时在结束时一次性输出回复
+		{tokens: false, answerNode: "echo", content: "echo: hi", chunks: 3},
+	} {
+		server, _ := newTestHandler(t, c.tokens, WithAnswerNode(c.answerNode))
+
+		content, chunks, done := readChunks(t, post(t, server.URL, "", `{"stream": true, "messages": [{"role": "user", "content": "hi"}]}`))
+		if !done || content != c.content {
+			t.Fatalf("tokens=%v answer=%q: unexpected stream %q done=%v", c.tokens, c.answerNode, content, done)
+		}
+		if len(chunks) != c.chunks {
+			t.Fatalf("tokens=%v answer=%q: expected %d chunks, got %d", c.tokens, c.answerNode, c.chunks, len(chunks))
+		}
+
+		first, last := chunks[0], chunks[len(chunks)-1]
+		if first.Object != "chat.completion.chunk" || first.Model != "echo-agent" || first.Choices[0].Delta.Role != "assistant" {
+			t.Fatalf("unexpected first chunk %+v", first)
+		}
+		if last.ID != first.ID || last.Choices[0].FinishReason == nil || *last.Choices[0].FinishReason != "stop" {
+			t.Fatalf("unexpected last chunk %+v", last)
+		}
+	}
+}
+
+func TestToMessageContent(t *testing.T) {
+	assistant := ChatMessage{
+		Role:      "assistant",
+		ToolCalls: []ToolCall{{ID: "call_1", Type: "function", Function: FunctionCall{Name: "search", Arguments: `{"q":"go"}`}}},
+	}
+	message, err := assistant.toMessageContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, ok := message.Parts[0].(llms.ToolCall)
+	if message.Role != llms.ChatMessageTypeAI || !ok || call.FunctionCall.Name != "search" {
+		t.Fatalf("unexpected assistant message %+v", message)
+	}
+
+	tool := ChatMessage{Role: "tool", ToolCallID: "call_1", Content: json.RawMessage(`"result"`)}
+	message, err = tool.toMessageContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, ok := message.Parts[0].(llms.ToolCallResponse)
+	if message.Role != llms.ChatMessageTypeTool || !ok || response.ToolCallID != "call_1" || response.Content != "result" {
+		t.Fatalf("unexpected tool message %+v", message)
+	}
+}
@@ -0,0 +1,163 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/futurxlab/golanggraph/server/internal/runutil"
+	"github.com/futurxlab/golanggraph/xerror"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// ChatCompletionRequest OpenAI chat completion 请求中 Handler 使用的字段，其余字段（temperature、tools 等）由 Flow 中的节点决定，被忽略
+type ChatCompletionRequest struct {
+	Model    string        `json:"model"`
+	Messages []ChatMessage `json:"messages"`
+	Stream   bool          `json:"stream,omitempty"`
+	// User 开启 UserAsThread 且没有线程请求头时作为线程 ID
+	User string `json:"user,omitempty"`
+}
+
+// ChatMessage OpenAI 格式的消息，Content 可以是字符串或 content part 数组
+type ChatMessage struct {
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content,omitempty"`
+	Name       string          `json:"name,omitempty"`
+	ToolCalls  []ToolCall      `json:"tool_calls,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+}
+
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function FunctionCall `json:"function"`
+}
+
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// contentPart content 数组中的元素，支持 text 和 image_url
+type contentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL    string `json:"url"`
+		Detail string `json:"detail,omitempty"`
+	} `json:"image_url,omitempty"`
+}
+
+// ChatCompletion 非流式响应
+type ChatCompletion struct {
+	ID      string   `json:"id"`
+	Object  string   `json:"object"`
+	Created int64    `json:"created"`
+	Model   string   `json:"model"`
+	Choices []Choice `json:"choices"`
+}
+
+type Choice struct {
+	Index        int              `json:"index"`
+	Message      *ResponseMessage `json:"message,omitempty"`
+	Delta        *ResponseMessage `json:"delta,omitempty"`
+	FinishReason *string          `json:"finish_reason"`
+}
+
+// ResponseMessage 响应中的助手消息，流式响应中为增量
+type ResponseMessage struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content"`
+}
+
+type errorResponse struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+// toMessageContent 把 OpenAI 格式的消息转换为 History 中的消息
+func (m *ChatMessage) toMessageContent() (llms.MessageContent, error) {
+	switch m.Role {
+	case "system", "developer":
+		parts, err := m.contentParts()
+		return llms.MessageContent{Role: llms.ChatMessageTypeSystem, Parts: parts}, err
+	case "user":
+		parts, err := m.contentParts()
+		return llms.MessageContent{Role: llms.ChatMessageTypeHuman, Parts: parts}, err
+	case "assistant":
+		parts, err := m.contentParts()
+		if err != nil {
+			return llms.MessageContent{}, err
+		}
+		for _, call := range m.ToolCalls {
+			parts = append(parts, llms.ToolCall{
+				ID:   call.ID,
+				Type: call.Type,
+				FunctionCall: &llms.FunctionCall{
+					Name:      call.Function.Name,
+					Arguments: call.Function.Arguments,
+				},
+			})
+		}
+		return llms.MessageContent{Role: llms.ChatMessageTypeAI, Parts: parts}, nil
+	case "tool":
+		text, err := m.text()
+		if err != nil {
+			return llms.MessageContent{}, err
+		}
+		return llms.MessageContent{
+			Role: llms.ChatMessageTypeTool,
+			Parts: []llms.ContentPart{llms.ToolCallResponse{
+				ToolCallID: m.ToolCallID,
+				Name:       m.Name,
+				Content:    text,
+			}},
+		}, nil
+	}
+
+	return llms.MessageContent{}, xerror.Wrap(fmt.Errorf("%w: unsupported role %q", errBadRequest, m.Role))
+}
+
+// contentParts 解析字符串或 content part 数组，null 或缺省时没有内容
+func (m *ChatMessage) contentParts() ([]llms.ContentPart, error) {
+	if len(m.Content) == 0 || string(m.Content) == "null" {
+		return nil, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(m.Content, &text); err == nil {
+		return []llms.ContentPart{llms.TextContent{Text: text}}, nil
+	}
+
+	var items []contentPart
+	if err := json.Unmarshal(m.Content, &items); err != nil {
+		return nil, xerror.Wrap(fmt.Errorf("%w: invalid content: %s", errBadRequest, err))
+	}
+
+	parts := make([]llms.ContentPart, 0, len(items))
+	for _, item := range items {
+		switch {
+		case item.Type == "text":
+			parts = append(parts, llms.TextContent{Text: item.Text})
+		case item.Type == "image_url" && item.ImageURL != nil:
+			parts = append(parts, llms.ImageURLContent{URL: item.ImageURL.URL, Detail: item.ImageURL.Detail})
+		default:
+			return nil, xerror.Wrap(fmt.Errorf("%w: unsupported content type %q", errBadRequest, item.Type))
+		}
+	}
+	return parts, nil
+}
+
+// text 返回消息中所有文本的拼接
+func (m *ChatMessage) text() (string, error) {
+	parts, err := m.contentParts()
+	if err != nil {
+		return "", err
+	}
+	return runutil.TextOf(llms.MessageContent{Parts: parts}), nil
+}
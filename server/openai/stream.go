@@ -0,0 +1,64 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/futurxlab/golanggraph/server/internal/runutil"
+	"github.com/futurxlab/golanggraph/xerror"
+)
+
+// chunkWriter 以 OpenAI 流式响应的 SSE 格式写入 chat.completion.chunk，结束时写入 [DONE]
+type chunkWriter struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+	completion *ChatCompletion
+}
+
+func newChunkWriter(w http.ResponseWriter, completion *ChatCompletion) *chunkWriter {
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	return &chunkWriter{w: w, controller: http.NewResponseController(w), completion: completion}
+}
+
+func (c *chunkWriter) writeDelta(delta *ResponseMessage, finishReason *string) error {
+	chunk := *c.completion
+	chunk.Choices = []Choice{{Delta: delta, FinishReason: finishReason}}
+	return c.writeData(&chunk)
+}
+
+// writeError 执行失败时写入错误，OpenAI SDK 会把它作为 API 错误抛出
+func (c *chunkWriter) writeError(err error) error {
+	return c.writeData(&errorResponse{Error: errorBody{
+		Message: runutil.ErrorMessage(err),
+		Type:    "server_error",
+	}})
+}
+
+func (c *chunkWriter) done() error {
+	return c.write([]byte("data: [DONE]\n\n"))
+}
+
+func (c *chunkWriter) writeData(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return xerror.Wrap(err)
+	}
+	return c.write([]byte(fmt.Sprintf("data: %s\n\n", data)))
+}
+
+func (c *chunkWriter) write(data []byte) error {
+	if _, err := c.w.Write(data); err != nil {
+		return xerror.Wrap(err)
+	}
+	if err := c.controller.Flush(); err != nil {
+		return xerror.Wrap(err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
+)
+
+// decodeRunInput 解析路径中的线程 ID 和请求，返回由 Flow.Continue 在线程最新的状态上写入消息和 Metadata 的函数。
+// 线程不存在时从空状态开始；上次执行的下一步和待恢复节点被丢弃，需要继续时使用 resume
+func (s *Server) decodeRunInput(r *http.Request) (string, func(current *state.State) error, error) {
+	request, err := decodeStateRequest(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	update := func(current *state.State) error {
+		request.apply(current)
+		return nil
+	}
+	return r.PathValue("thread"), update, nil
+}
+
+// run 同步执行，返回执行结束时的状态
+func (s *Server) run(w http.ResponseWriter, r *http.Request) {
+	registered, err := s.lookup(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	threadID, update, err := s.decodeRunInput(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	result, err := registered.flow.Continue(r.Context(), threadID, update, nil)
+	if err != nil {
+		s.writeError(w, r, xerror.Wrap(err))
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, newStateResponse(threadID, &result, nil))
+}
+
+// streamRun 流式执行，由 httpstream.Handler 处理，重连时从线程最新的检查点恢复
+func (s *Server) streamRun(w http.ResponseWriter, r *http.Request) {
+	registered, err := s.lookup(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	registered.stream.ServeHTTP(w, r)
+}
+
+// resume 从线程最新的检查点同步恢复执行，没有需要恢复的节点时直接返回最新状态
+func (s *Server) resume(w http.ResponseWriter, r *http.Request) {
+	registered, err := s.lookup(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	// 在线程锁内读取最新检查点，排队的恢复不会重复执行已经完成的节点
+	threadID := r.PathValue("thread")
+	result, err := registered.flow.ResumeThread(r.Context(), threadID, nil)
+	if err != nil {
+		s.writeError(w, r, xerror.Wrap(err))
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, newStateResponse(threadID, &result, nil))
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/flow"
+	"github.com/futurxlab/golanggraph/logger"
+	"github.com/futurxlab/golanggraph/server/httpstream"
+	"github.com/futurxlab/golanggraph/server/internal/runutil"
+	"github.com/futurxlab/golanggraph/xerror"
+)
+
+var (
+	// ErrFlowNotFound 请求的 Flow 没有注册
+	ErrFlowNotFound = errors.New("flow not found")
+	// ErrThreadExists 创建的线程已经存在
+	ErrThreadExists = errors.New("thread already exists")
+	// ErrNotSupported Flow 使用的 Checkpointer 不支持该操作
+	ErrNotSupported = errors.New("not supported by checkpointer")
+)
+
+type Options struct {
+	Logger logger.ILogger
+	// StreamOptions 流式执行接口使用的 httpstream.Handler 选项
+	StreamOptions []httpstream.Option
+}
+
+type Option func(*Options)
+
+func WithLogger(logger logger.ILogger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+func WithStreamOptions(opts ...httpstream.Option) Option {
+	return func(o *Options) {
+		o.StreamOptions = append(o.StreamOptions, opts...)
+	}
+}
+
+// registeredFlow 注册的 Flow 及其流式执行接口
+type registeredFlow struct {
+	flow   *flow.Flow
+	stream *httpstream.Handler
+}
+
+// Server 以 REST 接口发布注册的 Flow，线程、状态和历史都保存在 Flow 使用的 Checkpointer 中：
+//
+//	GET    /flows                                      注册的 Flow 名称
+//	POST   /flows/{flow}/threads                       创建线程
+//	GET    /flows/{flow}/threads                       线程列表，需要 ThreadManager
+//	DELETE /flows/{flow}/threads/{thread}              删除线程，需要 ThreadManager
+//	GET    /flows/{flow}/threads/{thread}/state        最新状态，checkpoint_id 查询参数指定检查点
+//	POST   /flows/{flow}/threads/{thread}/state        修改状态，保存为新的检查点
+//	GET    /flows/{flow}/threads/{thread}/history      检查点历史，需要 CheckpointLister
+//	POST   /flows/{flow}/threads/{thread}/runs         同步执行
+//	GET|POST /flows/{flow}/threads/{thread}/runs/stream 流式执行，带 Last-Event-ID 时恢复
+//	POST   /flows/{flow}/threads/{thread}/resume       同步恢复失败或中断的执行
+type Server struct {
+	options *Options
+	mux     *http.ServeMux
+
+	mu    sync.RWMutex
+	flows map[string]*registeredFlow
+}
+
+func NewServer(opts ...Option) (*Server, error) {
+	defaultLogger, err := logger.NewLogger()
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+
+	options := &Options{
+		Logger: defaultLogger,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	s := &Server{
+		options: options,
+		mux:     http.NewServeMux(),
+		flows:   make(map[string]*registeredFlow),
+	}
+
+	s.mux.HandleFunc("GET /flows", s.listFlows)
+	s.mux.HandleFunc("POST /flows/{flow}/threads", s.createThread)
+	s.mux.HandleFunc("GET /flows/{flow}/threads", s.listThreads)
+	s.mux.HandleFunc("DELETE /flows/{flow}/threads/{thread}", s.deleteThread)
+	s.mux.HandleFunc("GET /flows/{flow}/threads/{thread}/state", s.getState)
+	s.mux.HandleFunc("POST /flows/{flow}/threads/{thread}/state", s.updateState)
+	s.mux.HandleFunc("GET /flows/{flow}/threads/{thread}/history", s.getHistory)
+	s.mux.HandleFunc("POST /flows/{flow}/threads/{thread}/runs", s.run)
+	s.mux.HandleFunc("/flows/{flow}/threads/{thread}/runs/stream", s.streamRun)
+	s.mux.HandleFunc("POST /flows/{flow}/threads/{thread}/resume", s.resume)
+
+	return s, nil
+}
+
+// Register 以 Flow 的名称注册，名称重复时返回错误
+func (s *Server) Register(f *flow.Flow) error {
+	stream, err := httpstream.NewHandler(f, append([]httpstream.Option{
+		httpstream.WithLogger(s.options.Logger),
+		httpstream.WithInputDecoder(s.decodeRunInput),
+	}, s.options.StreamOptions...)...)
+	if err != nil {
+		return xerror.Wrap(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.flows[f.Name()]; exists {
+		return xerror.New(fmt.Sprintf("duplicate flow name: %s", f.Name()))
+	}
+	s.flows[f.Name()] = &registeredFlow{flow: f, stream: stream}
+
+	return nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// lookup 根据路径中的 flow 参数查找注册的 Flow
+func (s *Server) lookup(r *http.Request) (*registeredFlow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	registered, ok := s.flows[r.PathValue("flow")]
+	if !ok {
+		return nil, xerror.Wrap(fmt.Errorf("%w: %s", ErrFlowNotFound, r.PathValue("flow")))
+	}
+	return registered, nil
+}
+
+func (s *Server) listFlows(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.flows))
+	for name := range s.flows {
+		names = append(names, name)
+	}
+	s.mu.RUnlock()
+
+	slices.Sort(names)
+	s.writeJSON(w, http.StatusOK, map[string][]string{"flows": names})
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.options.Logger.Warnf(context.Background(), "write response failed %s", err)
+	}
+}
+
+// writeError 按错误类型返回状态码
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrFlowNotFound), errors.Is(err, flowcontract.ErrCheckpointNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrThreadExists), errors.Is(err, flowcontract.ErrThreadBusy):
+		status = http.StatusConflict
+	case errors.Is(err, ErrNotSupported):
+		status = http.StatusNotImplemented
+	case errors.Is(err, errBadRequest):
+		status = http.StatusBadRequest
+	}
+
+	if status == http.StatusInternalServerError {
+		s.options.Logger.Errorf(r.Context(), "%s %s failed %s", r.Method, r.URL.Path, err)
+	}
+
+	s.writeJSON(w, status, map[string]string{"error": runutil.ErrorMessage(err)})
+}
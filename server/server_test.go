@@ -0,0 +1,235 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/futurxlab/golanggraph/checkpointer"
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/edge"
+	"github.com/futurxlab/golanggraph/flow"
+	"github.com/futurxlab/golanggraph/logger"
+	"github.com/futurxlab/golanggraph/state"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+type replyNode struct {
+	fail atomic.Bool
+}
+
+func (n *replyNode) Name() string {
+	return "reply"
+}
+
+func (n *replyNode) Run(ctx context.Context, currentState *state.State, streamFunc flowcontract.StreamFunc) error {
+	if n.fail.Load() {
+		return errors.New("model unavailable")
+	}
+
+	last := currentState.History[len(currentState.History)-1]
+	text := last.Parts[0].(llms.TextContent).Text
+	if err := streamFunc(ctx, &flowcontract.FlowStreamEvent{Type: flowcontract.EventLLMToken, Chunk: "echo: " + text}); err != nil {
+		return err
+	}
+	currentState.History = append(currentState.History, llms.TextParts(llms.ChatMessageTypeAI, "echo: "+text))
+	return nil
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, *replyNode) {
+	logger, err := logger.NewLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := &replyNode{}
+	f, err := flow.NewFlowBuilder(logger).
+		SetName("echo").
+		SetCheckpointer(checkpointer.NewInMemoryCheckpointer()).
+		SetThreadLocking(flow.ThreadLockingFail).
+		AddNode(node).
+		AddEdge(edge.Edge{From: flow.StartNode, To: node.Name()}).
+		AddEdge(edge.Edge{From: node.Name(), To: flow.EndNode}).
+		Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := NewServer(WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Register(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Register(f); err == nil {
+		t.Fatal("expected duplicate registration to fail")
+	}
+
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	return httpServer, node
+}
+
+func request(t *testing.T, method, url string, body interface{}, out interface{}) int {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if out != nil && response.StatusCode < http.StatusMultipleChoices {
+		if err := json.NewDecoder(response.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return response.StatusCode
+}
+
+func messageTexts(messages []llms.MessageContent) []string {
+	texts := make([]string, 0, len(messages))
+	for _, message := range messages {
+		texts = append(texts, message.Parts[0].(llms.TextContent).Text)
+	}
+	return texts
+}
+
+func TestServer(t *testing.T) {
+	server, node := newTestServer(t)
+	threads := server.URL + "/flows/echo/threads"
+
+	var flows map[string][]string
+	if status := request(t, http.MethodGet, server.URL+"/flows", nil, &flows); status != http.StatusOK || len(flows["flows"]) != 1 {
+		t.Fatalf("unexpected flows %d %v", status, flows)
+	}
+	if status := request(t, http.MethodPost, server.URL+"/flows/missing/threads", nil, nil); status != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown flow, got %d", status)
+	}
+
+	var created StateResponse
+	status := request(t, http.MethodPost, threads, StateRequest{ThreadID: "t1", Metadata: map[string]interface{}{"user": "u1"}}, &created)
+	if status != http.StatusCreated || created.ThreadID != "t1" || created.CheckpointID == "" {
+		t.Fatalf("unexpected created thread %d %+v", status, created)
+	}
+	if status := request(t, http.MethodPost, threads, StateRequest{ThreadID: "t1"}, nil); status != http.StatusConflict {
+		t.Fatalf("expected 409 for existing thread, got %d", status)
+	}
+
+	t.Run("sync runs continue the thread", func(t *testing.T) {
+		var result StateResponse
+		for _, message := range []string{"hello", "again"} {
+			if status := request(t, http.MethodPost, threads+"/t1/runs", StateRequest{Message: message}, &result); status != http.StatusOK {
+				t.Fatalf("run failed with %d", status)
+			}
+		}
+
+		expected := []string{"hello", "echo: hello", "again", "echo: again"}
+		if strings.Join(messageTexts(result.Messages), "|") != strings.Join(expected, "|") || result.Metadata["user"] != "u1" {
+			t.Fatalf("unexpected result %+v", result)
+		}
+	})
+
+	t.Run("state and history", func(t *testing.T) {
+		var latest StateResponse
+		if status := request(t, http.MethodGet, threads+"/t1/state", nil, &latest); status != http.StatusOK || len(latest.Messages) != 4 || latest.Step == 0 {
+			t.Fatalf("unexpected state %d %+v", status, latest)
+		}
+
+		var updated StateResponse
+		status := request(t, http.MethodPost, threads+"/t1/state", StateRequest{
+			Metadata: map[string]interface{}{"user": nil, "locale": "en"},
+			AsNode:   "admin",
+		}, &updated)
+		if status != http.StatusOK || updated.Node != "admin" || updated.Metadata["locale"] != "en" || updated.Metadata["user"] != nil {
+			t.Fatalf("unexpected update %d %+v", status, updated)
+		}
+
+		var page HistoryResponse
+		if status := request(t, http.MethodGet, threads+"/t1/history?limit=2&reverse=true", nil, &page); status != http.StatusOK {
+			t.Fatalf("history failed with %d", status)
+		}
+		if len(page.Checkpoints) != 2 || page.Checkpoints[0].CheckpointID != updated.CheckpointID || page.NextCursor == "" {
+			t.Fatalf("unexpected history %+v", page)
+		}
+		if status := request(t, http.MethodGet, threads+"/t1/history?cursor=abc", nil, nil); status != http.StatusBadRequest {
+			t.Fatalf("expected invalid cursor to be a bad request, got %d", status)
+		}
+
+		var byID StateResponse
+		if status := request(t, http.MethodGet, threads+"/t1/state?checkpoint_id="+created.CheckpointID, nil, &byID); status != http.StatusOK || len(byID.Messages) != 0 {
+			t.Fatalf("unexpected checkpoint state %d %+v", status, byID)
+		}
+
+		var list map[string][]*ThreadResponse
+		if status := request(t, http.MethodGet, threads, nil, &list); status != http.StatusOK || len(list["threads"]) != 1 {
+			t.Fatalf("unexpected threads %d %+v", status, list)
+		}
+	})
+
+	t.Run("resume a failed run", func(t *testing.T) {
+		node.fail.Store(true)
+		if status := request(t, http.MethodPost, threads+"/t2/runs", StateRequest{Message: "hi"}, nil); status != http.StatusInternalServerError {
+			t.Fatalf("expected failed run, got %d", status)
+		}
+
+		var pending StateResponse
+		if status := request(t, http.MethodGet, threads+"/t2/state", nil, &pending); status != http.StatusOK || len(pending.PendingNodes) != 1 {
+			t.Fatalf("expected pending node, got %d %+v", status, pending)
+		}
+
+		node.fail.Store(false)
+		var resumed StateResponse
+		if status := request(t, http.MethodPost, threads+"/t2/resume", nil, &resumed); status != http.StatusOK {
+			t.Fatalf("resume failed with %d", status)
+		}
+		if strings.Join(messageTexts(resumed.Messages), "|") != "hi|echo: hi" {
+			t.Fatalf("unexpected resumed state %+v", resumed)
+		}
+	})
+
+	t.Run("streaming run", func(t *testing.T) {
+		response, err := http.Post(threads+"/t3/runs/stream?stream_mode=messages", "application/json", strings.NewReader(`{"message":"stream"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		body, err := io.ReadAll(response.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(body), `"chunk":"echo: stream"`) || !strings.Contains(string(body), "event: run_end") {
+			t.Fatalf("unexpected stream %s", body)
+		}
+	})
+
+	t.Run("delete thread", func(t *testing.T) {
+		if status := request(t, http.MethodDelete, threads+"/t3", nil, nil); status != http.StatusNoContent {
+			t.Fatalf("delete failed with %d", status)
+		}
+		if status := request(t, http.MethodGet, threads+"/t3/state", nil, nil); status != http.StatusNotFound {
+			t.Fatalf("expected deleted thread to be missing, got %d", status)
+		}
+	})
+}
@@ -0,0 +1,349 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/server/internal/runutil"
+	"github.com/futurxlab/golanggraph/state"
+	"github.com/futurxlab/golanggraph/xerror"
+
+	"github.com/google/uuid"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// errBadRequest 请求参数错误
+var errBadRequest = errors.New("bad request")
+
+// StateRequest 创建线程、修改状态和执行时的输入。Message 作为一条用户消息追加在 Messages 之后，
+// Metadata 中值为 nil 的 key 被删除
+type StateRequest struct {
+	ThreadID string                 `json:"thread_id,omitempty"`
+	Message  string                 `json:"message,omitempty"`
+	Messages []llms.MessageContent  `json:"messages,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// AsNode 修改状态时记录为该节点保存的检查点
+	AsNode string `json:"as_node,omitempty"`
+}
+
+// StateResponse 线程在某个检查点的状态
+type StateResponse struct {
+	ThreadID     string                 `json:"thread_id"`
+	CheckpointID string                 `json:"checkpoint_id,omitempty"`
+	Step         int                    `json:"step,omitempty"`
+	CreatedAt    *time.Time             `json:"created_at,omitempty"`
+	Node         string                 `json:"node,omitempty"`
+	NextNodes    []string               `json:"next_nodes,omitempty"`
+	PendingNodes []string               `json:"pending_nodes,omitempty"`
+	Messages     []llms.MessageContent  `json:"messages"`
+	Metadata     map[string]interface{} `json:"metadata"`
+}
+
+// ThreadResponse 线程列表中的线程
+type ThreadResponse struct {
+	ThreadID        string    `json:"thread_id"`
+	CheckpointCount int       `json:"checkpoint_count"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// HistoryResponse 一页检查点历史，NextCursor 为空表示没有更多数据
+type HistoryResponse struct {
+	Checkpoints []*StateResponse `json:"checkpoints"`
+	NextCursor  string           `json:"next_cursor,omitempty"`
+}
+
+func newStateResponse(threadID string, s *state.State, checkpoint *flowcontract.Checkpoint) *StateResponse {
+	response := &StateResponse{
+		ThreadID:  threadID,
+		Node:      s.GetNode(),
+		NextNodes: s.GetNextNodes(),
+		Messages:  s.History,
+		Metadata:  s.Metadata,
+	}
+	if response.Messages == nil {
+		response.Messages = []llms.MessageContent{}
+	}
+	if response.Metadata == nil {
+		response.Metadata = map[string]interface{}{}
+	}
+	for _, task := range s.GetPendingTasks() {
+		response.PendingNodes = append(response.PendingNodes, task.Node)
+	}
+	if checkpoint != nil {
+		response.CheckpointID = checkpoint.ID
+		response.Step = checkpoint.Step
+		response.CreatedAt = &checkpoint.CreatedAt
+	}
+	return response
+}
+
+// decodeStateRequest 解析请求体，请求体为空时返回空请求
+func decodeStateRequest(r *http.Request) (*StateRequest, error) {
+	request := &StateRequest{}
+	if r.Body == nil {
+		return request, nil
+	}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil && !errors.Is(err, io.EOF) {
+		return nil, xerror.Wrap(fmt.Errorf("%w: %s", errBadRequest, err))
+	}
+	return request, nil
+}
+
+// apply 把请求中的消息和 Metadata 写入状态
+func (req *StateRequest) apply(s *state.State) {
+	s.History = append(s.History, req.Messages...)
+	if req.Message != "" {
+		s.History = append(s.History, llms.TextParts(llms.ChatMessageTypeHuman, req.Message))
+	}
+
+	if s.Metadata == nil {
+		s.Metadata = make(map[string]interface{})
+	}
+	for k, v := range req.Metadata {
+		if v == nil {
+			delete(s.Metadata, k)
+			continue
+		}
+		s.Metadata[k] = v
+	}
+}
+
+// saveState 保存状态为新的检查点，并返回包含检查点 ID 的响应
+func saveState(ctx context.Context, checkpointer flowcontract.Checkpointer, threadID string, s *state.State) (*StateResponse, error) {
+	s.SetThreadID(threadID)
+	checkpointID, err := checkpointer.Save(ctx, threadID, s)
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+
+	response := newStateResponse(threadID, s, nil)
+	response.CheckpointID = checkpointID
+	return response, nil
+}
+
+func (s *Server) createThread(w http.ResponseWriter, r *http.Request) {
+	registered, err := s.lookup(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	request, err := decodeStateRequest(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	threadID := request.ThreadID
+	if threadID == "" {
+		threadID = uuid.New().String()
+	}
+
+	// 在线程锁内检查线程是否存在并保存，并发创建同一个线程时只有一个成功
+	checkpointer := registered.flow.Checkpointer()
+	var response *StateResponse
+	err = registered.flow.UpdateThread(r.Context(), threadID, func(ctx context.Context) error {
+		if _, _, err := runutil.LatestCheckpoint(ctx, checkpointer, threadID); err == nil {
+			return xerror.Wrap(fmt.Errorf("%w: %s", ErrThreadExists, threadID))
+		} else if !errors.Is(err, flowcontract.ErrCheckpointNotFound) {
+			return err
+		}
+
+		initState := &state.State{}
+		request.apply(initState)
+
+		response, err = saveState(ctx, checkpointer, threadID, initState)
+		return err
+	})
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, response)
+}
+
+func (s *Server) listThreads(w http.ResponseWriter, r *http.Request) {
+	registered, err := s.lookup(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	manager, ok := registered.flow.Checkpointer().(flowcontract.ThreadManager)
+	if !ok {
+		s.writeError(w, r, xerror.Wrap(fmt.Errorf("list threads: %w", ErrNotSupported)))
+		return
+	}
+
+	threads, err := manager.ListThreads(r.Context())
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	response := make([]*ThreadResponse, 0, len(threads))
+	for _, thread := range threads {
+		response = append(response, &ThreadResponse{
+			ThreadID:        thread.ID,
+			CheckpointCount: thread.CheckpointCount,
+			UpdatedAt:       thread.UpdatedAt,
+		})
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string][]*ThreadResponse{"threads": response})
+}
+
+func (s *Server) deleteThread(w http.ResponseWriter, r *http.Request) {
+	registered, err := s.lookup(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	manager, ok := registered.flow.Checkpointer().(flowcontract.ThreadManager)
+	if !ok {
+		s.writeError(w, r, xerror.Wrap(fmt.Errorf("delete thread: %w", ErrNotSupported)))
+		return
+	}
+
+	if err := manager.DeleteThread(r.Context(), r.PathValue("thread")); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getState(w http.ResponseWriter, r *http.Request) {
+	registered, err := s.lookup(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	threadID := r.PathValue("thread")
+	checkpointer := registered.flow.Checkpointer()
+
+	if checkpointID := r.URL.Query().Get("checkpoint_id"); checkpointID != "" {
+		checkpointState, err := checkpointer.GetByID(r.Context(), threadID, checkpointID)
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+		response := newStateResponse(threadID, checkpointState, nil)
+		response.CheckpointID = checkpointID
+		s.writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	latest, checkpoint, err := runutil.LatestCheckpoint(r.Context(), checkpointer, threadID)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, newStateResponse(threadID, latest, checkpoint))
+}
+
+// updateState 在最新状态上写入请求的消息和 Metadata 并保存为新的检查点，下一步和待恢复的节点保持不变。
+// 读取和保存在线程锁内进行，不会被正在执行的 run 下一次保存的检查点覆盖
+func (s *Server) updateState(w http.ResponseWriter, r *http.Request) {
+	registered, err := s.lookup(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	request, err := decodeStateRequest(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	threadID := r.PathValue("thread")
+	checkpointer := registered.flow.Checkpointer()
+
+	var response *StateResponse
+	err = registered.flow.UpdateThread(r.Context(), threadID, func(ctx context.Context) error {
+		latest, _, err := runutil.LatestCheckpoint(ctx, checkpointer, threadID)
+		if err != nil {
+			return err
+		}
+
+		updated := latest.Clone()
+		request.apply(updated)
+		if request.AsNode != "" {
+			updated.SetNode(request.AsNode)
+		}
+
+		response, err = saveState(ctx, checkpointer, threadID, updated)
+		return err
+	})
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// getHistory 按 limit、cursor、reverse 查询参数分页返回检查点
+func (s *Server) getHistory(w http.ResponseWriter, r *http.Request) {
+	registered, err := s.lookup(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	lister, ok := registered.flow.Checkpointer().(flowcontract.CheckpointLister)
+	if !ok {
+		s.writeError(w, r, xerror.Wrap(fmt.Errorf("checkpoint history: %w", ErrNotSupported)))
+		return
+	}
+
+	query := r.URL.Query()
+	opts := []flowcontract.ListOption{flowcontract.WithCursor(query.Get("cursor"))}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			s.writeError(w, r, xerror.Wrap(fmt.Errorf("%w: invalid limit %s", errBadRequest, limit)))
+			return
+		}
+		opts = append(opts, flowcontract.WithLimit(n))
+	}
+	if node := query.Get("node"); node != "" {
+		opts = append(opts, flowcontract.WithNode(node))
+	}
+	if reverse, _ := strconv.ParseBool(query.Get("reverse")); reverse {
+		opts = append(opts, flowcontract.WithReverse())
+	}
+
+	threadID := r.PathValue("thread")
+	checkpoints, cursor, err := lister.List(r.Context(), threadID, opts...)
+	if errors.Is(err, flowcontract.ErrInvalidCursor) {
+		s.writeError(w, r, xerror.Wrap(fmt.Errorf("%w: invalid cursor %s", errBadRequest, query.Get("cursor"))))
+		return
+	}
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	response := &HistoryResponse{
+		Checkpoints: make([]*StateResponse, 0, len(checkpoints)),
+		NextCursor:  cursor,
+	}
+	for _, checkpoint := range checkpoints {
+		response.Checkpoints = append(response.Checkpoints, newStateResponse(threadID, checkpoint.State, checkpoint))
+	}
+
+	s.writeJSON(w, http.StatusOK, response)
+}
@@ -11,9 +11,25 @@ type State struct {
 	Metadata map[string]interface{}
 
 	// internal paramters
-	threadID  string
-	node      string
-	nextNodes []string
+	threadID      string
+	node          string
+	nextNodes     []string
+	pendingTasks  []PendingTask
+	pendingWrites []PendingWrite
+}
+
+// PendingTask is a node that was scheduled but did not complete before a run
+// stopped, together with the state it was scheduled with.
+type PendingTask struct {
+	Node  string
+	State *State
+}
+
+// PendingWrite is the output of a node that completed successfully but was not
+// yet consumed by the nodes depending on it when a run stopped.
+type PendingWrite struct {
+	Node  string
+	State *State
 }
 
 func (s *State) GetThreadID() string {
@@ -28,6 +44,16 @@ func (s *State) GetNextNodes() []string {
 	return s.nextNodes
 }
 
+// GetPendingTasks returns the nodes a resumed run has to execute again.
+func (s *State) GetPendingTasks() []PendingTask {
+	return s.pendingTasks
+}
+
+// GetPendingWrites returns the completed node outputs a resumed run reuses.
+func (s *State) GetPendingWrites() []PendingWrite {
+	return s.pendingWrites
+}
+
 func (s *State) SetThreadID(threadID string) {
 	s.threadID = threadID
 }
@@ -40,14 +66,57 @@ func (s *State) SetNextNodes(nextNodes []string) {
 	s.nextNodes = nextNodes
 }
 
+func (s *State) SetPendingTasks(tasks []PendingTask) {
+	s.pendingTasks = tasks
+}
+
+func (s *State) SetPendingWrites(writes []PendingWrite) {
+	s.pendingWrites = writes
+}
+
+// Clone returns a copy of the state that does not share History or Metadata with s.
+func (s *State) Clone() *State {
+	clone := *s
+	if s.History != nil {
+		clone.History = append([]llms.MessageContent{}, s.History...)
+	}
+	if s.Metadata != nil {
+		clone.Metadata = make(map[string]interface{}, len(s.Metadata))
+		for k, v := range s.Metadata {
+			clone.Metadata[k] = v
+		}
+	}
+	if s.nextNodes != nil {
+		clone.nextNodes = append([]string{}, s.nextNodes...)
+	}
+	if s.pendingTasks != nil {
+		clone.pendingTasks = append([]PendingTask{}, s.pendingTasks...)
+	}
+	if s.pendingWrites != nil {
+		clone.pendingWrites = append([]PendingWrite{}, s.pendingWrites...)
+	}
+	return &clone
+}
+
+// serializedState is the wire format used by Serialize and Deserialize, it keeps
+// the internal parameters which are dropped by json.Marshal.
+type serializedState struct {
+	ThreadID      string                 `json:"threadID"`
+	Node          string                 `json:"node"`
+	NextNodes     []string               `json:"nextNodes"`
+	History       []llms.MessageContent  `json:"history"`
+	Metadata      map[string]interface{} `json:"metadata"`
+	PendingTasks  []serializedPending    `json:"pendingTasks,omitempty"`
+	PendingWrites []serializedPending    `json:"pendingWrites,omitempty"`
+}
+
+type serializedPending struct {
+	Node  string          `json:"node"`
+	State serializedState `json:"state"`
+}
+
 func (s *State) Serialize() ([]byte, error) {
-	m := make(map[string]interface{})
-	m["threadID"] = s.threadID
-	m["node"] = s.node
-	m["nextNodes"] = s.nextNodes
-	m["history"] = s.History
-	m["metadata"] = s.Metadata
-	json, err := json.Marshal(m)
+	json, err := json.Marshal(s.serialized())
 	if err != nil {
 		return nil, err
 	}
@@ -55,21 +124,55 @@ func (s *State) Serialize() ([]byte, error) {
 }
 
 func (s *State) Deserialize(data []byte) error {
-	m := make(map[string]interface{})
+	var m serializedState
 	err := json.Unmarshal(data, &m)
 	if err != nil {
 		return err
 	}
 
-	s.threadID = m["threadID"].(string)
-	s.node = m["node"].(string)
-	s.nextNodes = m["nextNodes"].([]string)
-	s.History = m["history"].([]llms.MessageContent)
-	s.Metadata = m["metadata"].(map[string]interface{})
+	s.restore(m)
 
 	return nil
 }
 
+func (s *State) serialized() serializedState {
+	m := serializedState{
+		ThreadID:  s.threadID,
+		Node:      s.node,
+		NextNodes: s.nextNodes,
+		History:   s.History,
+		Metadata:  s.Metadata,
+	}
+	for _, task := range s.pendingTasks {
+		m.PendingTasks = append(m.PendingTasks, serializedPending{Node: task.Node, State: task.State.serialized()})
+	}
+	for _, write := range s.pendingWrites {
+		m.PendingWrites = append(m.PendingWrites, serializedPending{Node: write.Node, State: write.State.serialized()})
+	}
+	return m
+}
+
+func (s *State) restore(m serializedState) {
+	s.threadID = m.ThreadID
+	s.node = m.Node
+	s.nextNodes = m.NextNodes
+	s.History = m.History
+	s.Metadata = m.Metadata
+	s.pendingTasks = nil
+	s.pendingWrites = nil
+
+	for _, task := range m.PendingTasks {
+		pending := &State{}
+		pending.restore(task.State)
+		s.pendingTasks = append(s.pendingTasks, PendingTask{Node: task.Node, State: pending})
+	}
+	for _, write := range m.PendingWrites {
+		pending := &State{}
+		pending.restore(write.State)
+		s.pendingWrites = append(s.pendingWrites, PendingWrite{Node: write.Node, State: pending})
+	}
+}
+
 func (s *State) Merge(other *State) {
 	s.History = append(s.History, other.History...)
 	s.Metadata = mergeMetadata(s.Metadata, other.Metadata)
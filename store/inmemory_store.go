@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/xerror"
+)
+
+// InMemoryStore 实现了 Store 接口，使用内存存储记忆
+type InMemoryStore struct {
+	mu      sync.RWMutex
+	options *Options
+	// key 是拼接后的 namespace，value 是该 namespace 下的记忆
+	items map[string]map[string]vectorItem
+}
+
+// NewInMemoryStore 创建一个新的 InMemoryStore 实例
+func NewInMemoryStore(opts ...Option) *InMemoryStore {
+	return &InMemoryStore{
+		options: newOptions(opts...),
+		items:   make(map[string]map[string]vectorItem),
+	}
+}
+
+// Put 写入或覆盖记忆
+func (s *InMemoryStore) Put(ctx context.Context, namespace []string, key string, value map[string]interface{}) error {
+	joined, err := joinNamespace(namespace)
+	if err != nil {
+		return err
+	}
+
+	// 向量化可能较慢，放在锁外进行
+	vector, err := embed(ctx, s.options, value)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	item := &flowcontract.Item{
+		Namespace: append([]string{}, namespace...),
+		Key:       key,
+		Value:     copyValue(value),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if _, exists := s.items[joined]; !exists {
+		s.items[joined] = make(map[string]vectorItem)
+	}
+	if existing, exists := s.items[joined][key]; exists {
+		item.CreatedAt = existing.item.CreatedAt
+	}
+	s.items[joined][key] = vectorItem{item: item, vector: vector}
+
+	return nil
+}
+
+// Get 获取记忆
+func (s *InMemoryStore) Get(ctx context.Context, namespace []string, key string) (*flowcontract.Item, error) {
+	joined, err := joinNamespace(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if entry, exists := s.items[joined][key]; exists {
+		return copyItem(entry.item), nil
+	}
+
+	return nil, xerror.Wrap(fmt.Errorf("item %s not found in namespace %s: %w", key, joined, flowcontract.ErrItemNotFound))
+}
+
+// Delete 删除记忆，记忆不存在时不报错
+func (s *InMemoryStore) Delete(ctx context.Context, namespace []string, key string) error {
+	joined, err := joinNamespace(namespace)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.items[joined], key)
+	if len(s.items[joined]) == 0 {
+		delete(s.items, joined)
+	}
+
+	return nil
+}
+
+// List 按更新时间倒序返回 namespace 前缀下的记忆
+func (s *InMemoryStore) List(ctx context.Context, namespacePrefix []string, opts ...flowcontract.StoreOption) ([]*flowcontract.Item, error) {
+	storeOptions := flowcontract.NewStoreOptions(opts...)
+
+	items := make([]*flowcontract.Item, 0)
+	for _, candidate := range s.candidates(namespacePrefix) {
+		if matchFilter(candidate.item.Value, storeOptions.Filter) {
+			items = append(items, candidate.item)
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].UpdatedAt.After(items[j].UpdatedAt)
+	})
+
+	return paginate(items, storeOptions), nil
+}
+
+// Search 按与 query 的语义相似度倒序返回 namespace 前缀下的记忆
+func (s *InMemoryStore) Search(ctx context.Context, namespacePrefix []string, query string, opts ...flowcontract.StoreOption) ([]*flowcontract.Item, error) {
+	return rank(ctx, s.options, s.candidates(namespacePrefix), query, flowcontract.NewStoreOptions(opts...))
+}
+
+// candidates 返回 namespace 前缀下所有记忆的副本
+func (s *InMemoryStore) candidates(namespacePrefix []string) []vectorItem {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	candidates := make([]vectorItem, 0)
+	for namespace, items := range s.items {
+		if !hasNamespacePrefix(namespace, namespacePrefix) {
+			continue
+		}
+		for _, entry := range items {
+			candidates = append(candidates, vectorItem{item: copyItem(entry.item), vector: entry.vector})
+		}
+	}
+	return candidates
+}
+
+func copyItem(item *flowcontract.Item) *flowcontract.Item {
+	copied := *item
+	copied.Namespace = append([]string{}, item.Namespace...)
+	copied.Value = copyValue(item.Value)
+	return &copied
+}
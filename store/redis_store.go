@@ -0,0 +1,295 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/xerror"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisBatchSize 单次 pipeline 读取的最大记忆数量
+	redisBatchSize = 100
+)
+
+// redisRecord 记忆在 Redis 中的存储格式
+type redisRecord struct {
+	Namespace []string               `json:"namespace"`
+	Key       string                 `json:"key"`
+	Value     map[string]interface{} `json:"value"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+	Vector    []float32              `json:"vector,omitempty"`
+}
+
+// itemRef 记忆的位置
+type itemRef struct {
+	namespace string
+	key       string
+}
+
+// RedisStore 实现了 Store 接口，使用 Redis 存储记忆，语义搜索在客户端计算相似度
+type RedisStore struct {
+	client  redis.UniversalClient
+	options *Options
+}
+
+// NewRedisStore 创建一个新的 RedisStore 实例
+func NewRedisStore(client redis.UniversalClient, opts ...Option) *RedisStore {
+	return &RedisStore{
+		client:  client,
+		options: newOptions(opts...),
+	}
+}
+
+// getNamespacesKey 生成记录所有 namespace 的 set key
+func (s *RedisStore) getNamespacesKey() string {
+	return fmt.Sprintf("%s:namespaces", s.options.KeyPrefix)
+}
+
+// getKeysKey 生成 namespace 下记忆索引的 key，score 为更新时间
+func (s *RedisStore) getKeysKey(namespace string) string {
+	return fmt.Sprintf("%s:{%s}:keys", s.options.KeyPrefix, namespace)
+}
+
+// getItemKey 生成单个记忆的 key
+func (s *RedisStore) getItemKey(namespace, key string) string {
+	return fmt.Sprintf("%s:{%s}:item:%s", s.options.KeyPrefix, namespace, key)
+}
+
+// Put 写入或覆盖记忆
+func (s *RedisStore) Put(ctx context.Context, namespace []string, key string, value map[string]interface{}) error {
+	joined, err := joinNamespace(namespace)
+	if err != nil {
+		return err
+	}
+
+	vector, err := embed(ctx, s.options, value)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	record := redisRecord{
+		Namespace: namespace,
+		Key:       key,
+		Value:     value,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Vector:    vector,
+	}
+
+	// 覆盖时保留创建时间
+	existing, err := s.load(ctx, []itemRef{{namespace: joined, key: key}})
+	if err != nil {
+		return err
+	}
+	if len(existing) == 1 {
+		record.CreatedAt = existing[0].item.CreatedAt
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return xerror.Wrap(fmt.Errorf("failed to marshal item: %w", err))
+	}
+
+	// 事务中只包含 {namespace} 下的 key，保证在 Cluster 中位于同一个 slot
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, s.getItemKey(joined, key), data, 0)
+	pipe.ZAdd(ctx, s.getKeysKey(joined), redis.Z{Score: float64(now.UnixMicro()), Member: key})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return xerror.Wrap(fmt.Errorf("failed to put item: %w", err))
+	}
+
+	// namespace 集合是所有 namespace 共用的 key，和记忆不在同一个 slot，在事务之外单独写入，
+	// 失败时记忆已经保存，重新 Put 即可补上
+	if err := s.client.SAdd(ctx, s.getNamespacesKey(), joined).Err(); err != nil {
+		return xerror.Wrap(fmt.Errorf("failed to add namespace: %w", err))
+	}
+
+	return nil
+}
+
+// Get 获取记忆
+func (s *RedisStore) Get(ctx context.Context, namespace []string, key string) (*flowcontract.Item, error) {
+	joined, err := joinNamespace(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.load(ctx, []itemRef{{namespace: joined, key: key}})
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, xerror.Wrap(fmt.Errorf("item %s not found in namespace %s: %w", key, joined, flowcontract.ErrItemNotFound))
+	}
+
+	return items[0].item, nil
+}
+
+// Delete 删除记忆，记忆不存在时不报错
+func (s *RedisStore) Delete(ctx context.Context, namespace []string, key string) error {
+	joined, err := joinNamespace(namespace)
+	if err != nil {
+		return err
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, s.getItemKey(joined, key))
+	pipe.ZRem(ctx, s.getKeysKey(joined), key)
+	remaining := pipe.ZCard(ctx, s.getKeysKey(joined))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return xerror.Wrap(fmt.Errorf("failed to delete item: %w", err))
+	}
+
+	if remaining.Val() == 0 {
+		if err := s.client.SRem(ctx, s.getNamespacesKey(), joined).Err(); err != nil {
+			return xerror.Wrap(fmt.Errorf("failed to remove namespace: %w", err))
+		}
+	}
+
+	return nil
+}
+
+// List 按更新时间倒序返回 namespace 前缀下的记忆
+func (s *RedisStore) List(ctx context.Context, namespacePrefix []string, opts ...flowcontract.StoreOption) ([]*flowcontract.Item, error) {
+	storeOptions := flowcontract.NewStoreOptions(opts...)
+
+	refs, err := s.refs(ctx, namespacePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按批读取，凑够 offset + limit 条满足条件的记忆即停止
+	items := make([]*flowcontract.Item, 0)
+	for start := 0; start < len(refs) && len(items) < storeOptions.Offset+storeOptions.Limit; start += redisBatchSize {
+		loaded, err := s.load(ctx, refs[start:min(start+redisBatchSize, len(refs))])
+		if err != nil {
+			return nil, err
+		}
+		for _, candidate := range loaded {
+			if matchFilter(candidate.item.Value, storeOptions.Filter) {
+				items = append(items, candidate.item)
+			}
+		}
+	}
+
+	return paginate(items, storeOptions), nil
+}
+
+// Search 按与 query 的语义相似度倒序返回 namespace 前缀下的记忆
+func (s *RedisStore) Search(ctx context.Context, namespacePrefix []string, query string, opts ...flowcontract.StoreOption) ([]*flowcontract.Item, error) {
+	if s.options.Embedder == nil {
+		return nil, xerror.Wrap(flowcontract.ErrSearchNotSupported)
+	}
+
+	refs, err := s.refs(ctx, namespacePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]vectorItem, 0, len(refs))
+	for start := 0; start < len(refs); start += redisBatchSize {
+		loaded, err := s.load(ctx, refs[start:min(start+redisBatchSize, len(refs))])
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, loaded...)
+	}
+
+	return rank(ctx, s.options, candidates, query, flowcontract.NewStoreOptions(opts...))
+}
+
+// refs 按更新时间倒序返回 namespace 前缀下所有记忆的位置
+func (s *RedisStore) refs(ctx context.Context, namespacePrefix []string) ([]itemRef, error) {
+	namespaces, err := s.client.SMembers(ctx, s.getNamespacesKey()).Result()
+	if err != nil {
+		return nil, xerror.Wrap(fmt.Errorf("failed to list namespaces: %w", err))
+	}
+
+	pipe := s.client.Pipeline()
+	matched := make([]string, 0)
+	cmds := make([]*redis.ZSliceCmd, 0)
+	for _, namespace := range namespaces {
+		if hasNamespacePrefix(namespace, namespacePrefix) {
+			matched = append(matched, namespace)
+			cmds = append(cmds, pipe.ZRevRangeWithScores(ctx, s.getKeysKey(namespace), 0, -1))
+		}
+	}
+	if len(matched) == 0 {
+		return nil, nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, xerror.Wrap(fmt.Errorf("failed to list keys: %w", err))
+	}
+
+	type scoredRef struct {
+		ref   itemRef
+		score float64
+	}
+	scored := make([]scoredRef, 0)
+	for i, cmd := range cmds {
+		for _, member := range cmd.Val() {
+			scored = append(scored, scoredRef{ref: itemRef{namespace: matched[i], key: member.Member.(string)}, score: member.Score})
+		}
+	}
+
+	// 多个 namespace 合并后按更新时间倒序
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+
+	refs := make([]itemRef, len(scored))
+	for i, s := range scored {
+		refs[i] = s.ref
+	}
+	return refs, nil
+}
+
+// load 使用 pipeline 批量读取记忆，跳过已被删除的记忆
+func (s *RedisStore) load(ctx context.Context, refs []itemRef) ([]vectorItem, error) {
+	pipe := s.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(refs))
+	for i, ref := range refs {
+		cmds[i] = pipe.Get(ctx, s.getItemKey(ref.namespace, ref.key))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, xerror.Wrap(fmt.Errorf("failed to get items: %w", err))
+	}
+
+	items := make([]vectorItem, 0, len(refs))
+	for _, cmd := range cmds {
+		data, err := cmd.Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, xerror.Wrap(fmt.Errorf("failed to get item: %w", err))
+		}
+
+		var record redisRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, xerror.Wrap(fmt.Errorf("failed to unmarshal item: %w", err))
+		}
+
+		items = append(items, vectorItem{
+			item: &flowcontract.Item{
+				Namespace: record.Namespace,
+				Key:       record.Key,
+				Value:     record.Value,
+				CreatedAt: record.CreatedAt,
+				UpdatedAt: record.UpdatedAt,
+			},
+			vector: record.Vector,
+		})
+	}
+
+	return items, nil
+}
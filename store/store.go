@@ -0,0 +1,208 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+	"github.com/futurxlab/golanggraph/xerror"
+
+	"github.com/tmc/langchaingo/embeddings"
+)
+
+const (
+	DefaultKeyPrefix = "store"
+
+	// namespaceSeparator 拼接分层 namespace 的分隔符，namespace 的每一级都不能包含它
+	namespaceSeparator = "/"
+)
+
+type Options struct {
+	// Embedder 用于语义搜索，为空时 Search 返回 ErrSearchNotSupported
+	Embedder embeddings.Embedder
+	// IndexFields 参与向量化的 Value 字段，为空时使用整个 Value 的 JSON
+	IndexFields []string
+	// KeyPrefix Redis key 前缀，默认为 DefaultKeyPrefix，仅 RedisStore 使用
+	KeyPrefix string
+}
+
+type Option func(*Options)
+
+// WithEmbedder 开启语义搜索，fields 指定参与向量化的 Value 字段
+func WithEmbedder(embedder embeddings.Embedder, fields ...string) Option {
+	return func(o *Options) {
+		o.Embedder = embedder
+		o.IndexFields = fields
+	}
+}
+
+func WithKeyPrefix(prefix string) Option {
+	return func(o *Options) {
+		o.KeyPrefix = prefix
+	}
+}
+
+func newOptions(opts ...Option) *Options {
+	options := &Options{
+		KeyPrefix: DefaultKeyPrefix,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// joinNamespace 校验并拼接 namespace
+func joinNamespace(namespace []string) (string, error) {
+	if len(namespace) == 0 {
+		return "", xerror.New("namespace cannot be empty")
+	}
+	for _, part := range namespace {
+		if part == "" || strings.Contains(part, namespaceSeparator) {
+			return "", xerror.New(fmt.Sprintf("invalid namespace part %q", part))
+		}
+	}
+	return strings.Join(namespace, namespaceSeparator), nil
+}
+
+func splitNamespace(namespace string) []string {
+	return strings.Split(namespace, namespaceSeparator)
+}
+
+// hasNamespacePrefix 判断 namespace 是否在 prefix 之下，空 prefix 匹配所有 namespace
+func hasNamespacePrefix(namespace string, prefix []string) bool {
+	if len(prefix) == 0 {
+		return true
+	}
+	joined := strings.Join(prefix, namespaceSeparator)
+	return namespace == joined || strings.HasPrefix(namespace, joined+namespaceSeparator)
+}
+
+// matchFilter 判断 Value 是否满足过滤条件，按 JSON 表示比较，避免 int 与 float64 的差异
+func matchFilter(value map[string]interface{}, filter map[string]interface{}) bool {
+	for k, expected := range filter {
+		actual, ok := value[k]
+		if !ok {
+			return false
+		}
+		a, errA := json.Marshal(actual)
+		b, errB := json.Marshal(expected)
+		if errA != nil || errB != nil || string(a) != string(b) {
+			return false
+		}
+	}
+	return true
+}
+
+// embeddingText 生成用于向量化的文本
+func embeddingText(value map[string]interface{}, fields []string) (string, error) {
+	if len(fields) == 0 {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", xerror.Wrap(err)
+		}
+		return string(data), nil
+	}
+
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if v, ok := value[field]; ok {
+			parts = append(parts, fmt.Sprint(v))
+		}
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+// embed 对 Value 进行向量化，未配置 Embedder 时返回 nil
+func embed(ctx context.Context, options *Options, value map[string]interface{}) ([]float32, error) {
+	if options.Embedder == nil {
+		return nil, nil
+	}
+
+	text, err := embeddingText(value, options.IndexFields)
+	if err != nil {
+		return nil, err
+	}
+
+	vectors, err := options.Embedder.EmbedDocuments(ctx, []string{text})
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+	if len(vectors) != 1 {
+		return nil, xerror.New("embedder returned no vector")
+	}
+	return vectors[0], nil
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// vectorItem 带向量的记忆
+type vectorItem struct {
+	item   *flowcontract.Item
+	vector []float32
+}
+
+// rank 对候选记忆过滤、按相似度排序并分页
+func rank(ctx context.Context, options *Options, candidates []vectorItem, query string, storeOptions *flowcontract.StoreOptions) ([]*flowcontract.Item, error) {
+	if options.Embedder == nil {
+		return nil, xerror.Wrap(flowcontract.ErrSearchNotSupported)
+	}
+
+	queryVector, err := options.Embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, xerror.Wrap(err)
+	}
+
+	items := make([]*flowcontract.Item, 0, len(candidates))
+	for _, candidate := range candidates {
+		if !matchFilter(candidate.item.Value, storeOptions.Filter) {
+			continue
+		}
+		candidate.item.Score = cosineSimilarity(queryVector, candidate.vector)
+		items = append(items, candidate.item)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Score > items[j].Score
+	})
+
+	return paginate(items, storeOptions), nil
+}
+
+func paginate(items []*flowcontract.Item, storeOptions *flowcontract.StoreOptions) []*flowcontract.Item {
+	if storeOptions.Offset >= len(items) {
+		return []*flowcontract.Item{}
+	}
+	items = items[storeOptions.Offset:]
+	if len(items) > storeOptions.Limit {
+		items = items[:storeOptions.Limit]
+	}
+	return items
+}
+
+func copyValue(value map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(value))
+	for k, v := range value {
+		copied[k] = v
+	}
+	return copied
+}
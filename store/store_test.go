@@ -0,0 +1,184 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	flowcontract "github.com/futurxlab/golanggraph/contract"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// keywordEmbedder 按关键词是否出现生成向量，用于测试语义搜索
+type keywordEmbedder struct {
+	keywords []string
+}
+
+func (e *keywordEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = e.EmbedQuery(ctx, text)
+	}
+	return vectors, nil
+}
+
+func (e *keywordEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	vector := make([]float32, len(e.keywords))
+	for i, keyword := range e.keywords {
+		if strings.Contains(strings.ToLower(text), keyword) {
+			vector[i] = 1
+		}
+	}
+	return vector, nil
+}
+
+func TestStore(t *testing.T) {
+	embedder := &keywordEmbedder{keywords: []string{"coffee", "tea", "language", "english"}}
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	for name, store := range map[string]flowcontract.Store{
+		"inmemory": NewInMemoryStore(WithEmbedder(embedder, "text")),
+		"redis":    NewRedisStore(client, WithEmbedder(embedder, "text")),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			preferences := []string{"user-1", "preferences"}
+
+			if err := store.Put(ctx, preferences, "drink", map[string]interface{}{"text": "likes coffee", "kind": "food"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Put(ctx, preferences, "language", map[string]interface{}{"text": "prefers english language", "kind": "style"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Put(ctx, []string{"user-1", "facts"}, "city", map[string]interface{}{"text": "lives in Sydney", "kind": "fact"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Put(ctx, []string{"user-2", "preferences"}, "drink", map[string]interface{}{"text": "likes tea", "kind": "food"}); err != nil {
+				t.Fatal(err)
+			}
+
+			item, err := store.Get(ctx, preferences, "drink")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.Value["text"] != "likes coffee" || item.Namespace[1] != "preferences" {
+				t.Fatalf("unexpected item %+v", item)
+			}
+
+			items, err := store.List(ctx, []string{"user-1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != 3 || items[0].Key != "city" {
+				t.Fatalf("unexpected items %+v", items)
+			}
+
+			items, err = store.List(ctx, nil, flowcontract.WithFilter(map[string]interface{}{"kind": "food"}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != 2 {
+				t.Fatalf("expected 2 food items, got %d", len(items))
+			}
+
+			items, err = store.Search(ctx, []string{"user-1"}, "what language should I answer in?", flowcontract.WithStoreLimit(1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != 1 || items[0].Key != "language" || items[0].Score <= 0 {
+				t.Fatalf("unexpected search result %+v", items)
+			}
+
+			if err := store.Delete(ctx, preferences, "drink"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Get(ctx, preferences, "drink"); !errors.Is(err, flowcontract.ErrItemNotFound) {
+				t.Fatalf("expected item not found, got %v", err)
+			}
+
+			if err := store.Put(ctx, []string{"user/1"}, "key", nil); err == nil {
+				t.Fatal("expected invalid namespace error")
+			}
+		})
+	}
+
+	t.Run("search without embedder", func(t *testing.T) {
+		_, err := NewInMemoryStore().Search(context.Background(), nil, "coffee")
+		if !errors.Is(err, flowcontract.ErrSearchNotSupported) {
+			t.Fatalf("expected search not supported, got %v", err)
+		}
+	})
+}
+
+// txKeysHook 记录事务中访问的 key
+type txKeysHook struct {
+	keys []string
+}
+
+func (h *txKeysHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *txKeysHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return next
+}
+
+func (h *txKeysHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if len(cmds) == 0 || cmds[0].Name() != "multi" {
+			return next(ctx, cmds)
+		}
+		for _, cmd := range cmds {
+			if name := cmd.Name(); name == "multi" || name == "exec" {
+				continue
+			}
+			if key, ok := cmd.Args()[1].(string); ok {
+				h.keys = append(h.keys, key)
+			}
+		}
+		return next(ctx, cmds)
+	}
+}
+
+// hashTag 返回 Cluster 计算 slot 时使用的部分
+func hashTag(key string) string {
+	if start := strings.Index(key, "{"); start >= 0 {
+		if end := strings.Index(key[start+1:], "}"); end > 0 {
+			return key[start+1 : start+1+end]
+		}
+	}
+	return key
+}
+
+func TestRedisStoreTransactionSlot(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	hook := &txKeysHook{}
+	client.AddHook(hook)
+
+	ctx := context.Background()
+	store := NewRedisStore(client)
+	if err := store.Put(ctx, []string{"user-1", "preferences"}, "drink", map[string]interface{}{"text": "likes coffee"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, []string{"user-1", "preferences"}, "drink"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hook.keys) == 0 {
+		t.Fatal("expected transaction keys")
+	}
+	for _, key := range hook.keys {
+		if hashTag(key) != hashTag(hook.keys[0]) {
+			t.Fatalf("transaction keys %v are not in the same slot", hook.keys)
+		}
+	}
+}
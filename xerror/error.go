@@ -16,6 +16,10 @@ func (e xerror) Error() string {
 	return fmt.Sprintf("%s\n%s", e.err, strings.Join(e.stacktrace, "\n"))
 }
 
+func (e xerror) Unwrap() error {
+	return e.err
+}
+
 func New(message string) error {
 	_, file, line, _ := runtime.Caller(1)
 	return xerror{